@@ -2,56 +2,145 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"os/signal"
+	"time"
 
+	"github.com/stevielcb/motd-client/internal/cache"
 	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/failure"
 	"github.com/stevielcb/motd-client/internal/network"
 	"github.com/stevielcb/motd-client/internal/terminal"
 )
 
+// Version identifies the client to servers. Release builds set it with
+// -ldflags "-X github.com/stevielcb/motd-client/internal/app.Version=...".
+var Version = "dev"
+
+// ErrEmptyMessage is returned by Run when the message source provides no
+// content.
+var ErrEmptyMessage = errors.New("received empty message from server")
+
 // App represents the main application.
 type App struct {
 	cfg       *config.Config
 	client    network.ClientInterface
+	sources   []messageSource // Tried in order until one provides a message
+	cache     *cache.Cache    // Last message; nil unless verifying signatures or using HTTP
 	detector  terminal.DetectorInterface
+	palette   terminal.Theme // Colors overriding those of the theme
 	formatter *terminal.Formatter
+	out       io.Writer
+	shown     *network.Message // Message on screen in watch mode
 }
 
-// New creates a new application instance.
-func New(cfg *config.Config) *App {
-	client := network.NewClient(cfg.Host, cfg.Port, cfg.Timeout())
-	detector := terminal.NewDetector()
+// New creates a new application instance. Returned errors are classified
+// as failure.KindConfig.
+func New(cfg *config.Config) (*App, error) {
+	opts := []network.Option{network.WithProtocol(cfg.Protocol)}
+	if cfg.MaxSizeKb > 0 {
+		opts = append(opts, network.WithMaxSize(cfg.MaxSizeKb*1024))
+	}
 
-	return &App{
+	if cfg.Network != "" {
+		opts = append(opts, network.WithNetwork(cfg.Network))
+	}
+	if cfg.Resolver != "" {
+		address, err := network.ParseDNSServer(cfg.Resolver)
+		if err != nil {
+			return nil, failure.Wrap(failure.KindConfig, err)
+		}
+		opts = append(opts, network.WithDNSServer(address))
+	}
+	proxy, err := cfg.ProxyFunc()
+	if err != nil {
+		return nil, failure.Wrap(failure.KindConfig, err)
+	}
+	opts = append(opts, network.WithProxy(proxy))
+
+	secret, err := cfg.AuthKey()
+	if err != nil {
+		return nil, failure.Wrap(failure.KindConfig, err)
+	}
+	if secret != nil {
+		opts = append(opts, network.WithAuth(cfg.AuthKeyId, secret))
+	}
+
+	// Never fetch unverified messages when trusted keys are configured but
+	// cannot be used.
+	keys, err := cfg.PublicKeys()
+	if err != nil {
+		return nil, failure.Wrap(failure.KindConfig, fmt.Errorf("invalid trusted key: %w", err))
+	}
+	if len(keys) > 0 {
+		opts = append(opts, network.WithTrustedKeys(keys))
+	}
+
+	// The cache is needed to fall back on when verification fails and to
+	// revalidate messages fetched over HTTP.
+	var messageCache *cache.Cache
+	if len(keys) > 0 || cfg.URL != "" {
+		if path, err := cfg.CachePath(); err == nil {
+			messageCache = cache.New(path, cache.WithTrustedKeys(keys))
+		} else {
+			slog.Warn("Message cache disabled", "error", err)
+		}
+	}
+
+	var client network.ClientInterface = network.NewClient(cfg.Host, cfg.Port, cfg.Timeout(), opts...)
+	if cfg.URL != "" {
+		if messageCache != nil {
+			opts = append(opts, network.WithMessageStore(messageCache))
+		}
+		httpClient, err := network.NewHTTPClient(cfg.URL, cfg.Timeout(), opts...)
+		if err != nil {
+			return nil, failure.Wrap(failure.KindConfig, err)
+		}
+		client = httpClient
+	}
+	palette, err := terminal.ParsePalette(cfg.Palette)
+	if err != nil {
+		return nil, failure.Wrap(failure.KindConfig, err)
+	}
+	detector := terminal.NewDetector(terminal.WithBackgroundTimeout(cfg.BackgroundTimeout()))
+
+	a := &App{
 		cfg:      cfg,
 		client:   client,
+		cache:    messageCache,
 		detector: detector,
+		palette:  palette,
+		out:      os.Stdout,
 	}
+	sources, err := newSources(a, cfg)
+	if err != nil {
+		return nil, failure.Wrap(failure.KindConfig, err)
+	}
+	a.sources = sources
+	return a, nil
 }
 
 // Run executes the main application logic.
+// Returned errors are classified with a failure.Kind.
 func (a *App) Run() error {
 	// Detect terminal environment
 	env, err := a.detector.Detect()
 	if err != nil {
-		return fmt.Errorf("failed to detect terminal environment: %w", err)
+		return failure.Wrap(failure.KindTerminal, fmt.Errorf("failed to detect terminal environment: %w", err))
 	}
 
 	// Create formatter
-	a.formatter = terminal.NewFormatter(env)
-
-	// Connect to server
-	conn, err := a.client.Connect()
-	if err != nil {
-		return fmt.Errorf("failed to connect to server: %w", err)
-	}
-	defer conn.Close()
+	a.formatter = a.newFormatter(env)
 
-	// Fetch message
-	message, err := a.client.FetchMessage(conn)
+	// Fetch message from the first source that has one
+	message, err := a.fetch(context.Background(), hello(env), a.sources)
 	if err != nil {
-		return fmt.Errorf("failed to fetch message: %w", err)
+		return err
 	}
 
 	// Display message
@@ -60,15 +149,96 @@ func (a *App) Run() error {
 	return nil
 }
 
-// displayMessage formats and displays the MOTD message.
-func (a *App) displayMessage(message string) {
-	if message == "" {
+// fallback returns the cached message after verifyErr rejected the
+// server's message, or an error if nothing trusted has been cached.
+func (a *App) fallback(verifyErr error) (*network.Message, error) {
+	err := fmt.Errorf("failed to verify message: %w", verifyErr)
+	if a.cache == nil {
+		return nil, err
+	}
+
+	message, cacheErr := a.cache.Load()
+	if cacheErr != nil {
+		slog.Debug("No cached message to fall back to", "error", cacheErr)
+		return nil, err
+	}
+
+	slog.Warn("Message failed verification, displaying cached message", "error", verifyErr)
+	return message, nil
+}
+
+// remember caches a verified message for later fallback.
+func (a *App) remember(message *network.Message) {
+	if a.cache == nil {
+		return
+	}
+	if err := a.cache.Store(message); err != nil {
+		slog.Warn("Failed to cache message", "error", err)
+	}
+}
+
+// hello describes the client and its terminal to the server.
+func hello(env *terminal.Environment) network.Hello {
+	var background string
+	if light, known := env.LightBackground(); known {
+		background = network.BackgroundDark
+		if light {
+			background = network.BackgroundLight
+		}
+	}
+	return network.Hello{
+		ClientVersion: Version,
+		Capabilities: network.Capabilities{
+			Graphics:   env.Graphics,
+			Columns:    env.Columns,
+			Rows:       env.Rows,
+			ColorDepth: env.ColorDepth,
+			Background: background,
+		},
+	}
+}
+
+// newFormatter returns a formatter for env in the configured theme and
+// layout.
+func (a *App) newFormatter(env *terminal.Environment) *terminal.Formatter {
+	env.Theme = terminal.NewTheme(a.cfg.Theme, env, a.palette)
+	layout := terminal.Layout{Wrap: a.cfg.Wrap, Align: a.cfg.Align, Box: a.cfg.Box}
+	return terminal.NewFormatter(env, terminal.WithLayout(layout))
+}
+
+// animate plays an animation drawn with half blocks over its first frame,
+// within the configured bounds. An interrupt stops it early, so the
+// cursor it hid is shown again.
+func (a *App) animate(animation *terminal.Animation, printed string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	animation.Play(ctx, a.out, printed, a.cfg.AnimationLoops, a.cfg.AnimationMaxDuration())
+}
+
+// displayMessage expands, renders and displays the MOTD message, with the
+// facts panel if enabled. Expired messages are skipped.
+func (a *App) displayMessage(message *network.Message) {
+	if len(message.Body) == 0 {
 		slog.Warn("Received empty message from server")
 		return
 	}
+	if message.Expired(time.Now()) {
+		slog.Info("Skipping expired message", "id", message.Metadata.ID, "expires", message.Metadata.Expires)
+		return
+	}
 
-	formattedMessage := a.formatter.Format(message)
-	fmt.Println(formattedMessage)
+	message = a.expand(message)
+	formattedMessage := a.formatter.Render(message)
+	if a.cfg.Facts != "" && a.cfg.Facts != terminal.PanelOff {
+		formattedMessage = a.formatter.Panel(formattedMessage, systemFacts(), a.cfg.Facts)
+	}
+	fmt.Fprintln(a.out, formattedMessage)
+	if animation := a.formatter.Animation(message); animation != nil {
+		a.animate(animation, formattedMessage+"\n")
+	}
 
-	slog.Debug("Message displayed successfully", "message_length", len(message))
+	slog.Debug("Message displayed successfully",
+		"message_length", len(message.Body),
+		"content_type", message.ContentType,
+		"id", message.Metadata.ID)
 }
@@ -1,11 +1,30 @@
 package app
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/stevielcb/motd-client/internal/cache"
 	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/failure"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/server"
 	"github.com/stevielcb/motd-client/internal/terminal"
+	"github.com/stevielcb/motd-client/motdtest"
 )
 
 func TestNew(t *testing.T) {
@@ -16,7 +35,7 @@ func TestNew(t *testing.T) {
 		LogLevel:  "info",
 	}
 
-	app := New(cfg)
+	app := mustNew(t, cfg)
 
 	if app.cfg != cfg {
 		t.Error("Expected config to be set")
@@ -29,6 +48,49 @@ func TestNew(t *testing.T) {
 	}
 }
 
+// mustNew creates an application for cfg, failing the test on error.
+func mustNew(t *testing.T, cfg *config.Config) *App {
+	t.Helper()
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	return app
+}
+
+func TestNew_ConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.Config)
+	}{
+		// Messages must never be shown unverified because of a bad key.
+		{name: "invalid trusted key", modify: func(c *config.Config) { c.TrustedKeys = []string{"not a key"} }},
+		{name: "missing auth key", modify: func(c *config.Config) { c.AuthKeyFile = filepath.Join(t.TempDir(), "missing") }},
+		{name: "invalid resolver", modify: func(c *config.Config) { c.Resolver = ":53" }},
+		{name: "invalid proxy", modify: func(c *config.Config) { c.Proxy = "ftp://proxy:21" }},
+		{name: "invalid URL", modify: func(c *config.Config) { c.URL = "ftp://example.com/motd" }},
+		{name: "invalid palette", modify: func(c *config.Config) { c.Palette = map[string]string{"code": "chartreuse-ish"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Host: "localhost", Port: 8080, TimeoutMs: 100, LogLevel: "info"}
+			tt.modify(cfg)
+
+			app, err := New(cfg)
+			if err == nil {
+				t.Fatal("New() expected error")
+			}
+			if app != nil {
+				t.Error("New() returned an application along with the error")
+			}
+			if kind := failure.KindOf(err); kind != failure.KindConfig {
+				t.Errorf("failure.KindOf() = %v, want %v", kind, failure.KindConfig)
+			}
+		})
+	}
+}
+
 func TestApp_Run_NoServer(t *testing.T) {
 	cfg := &config.Config{
 		Host:      "localhost",
@@ -37,7 +99,7 @@ func TestApp_Run_NoServer(t *testing.T) {
 		LogLevel:  "info",
 	}
 
-	app := New(cfg)
+	app := mustNew(t, cfg)
 	err := app.Run()
 
 	if err == nil {
@@ -47,45 +109,213 @@ func TestApp_Run_NoServer(t *testing.T) {
 
 func TestApp_Run_WithServer(t *testing.T) {
 	// Create a test server
+	srv := motdtest.NewServer(motdtest.PayloadString("Test MOTD Message"))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Host:      srv.Host(),
+		Port:      srv.Port(),
+		TimeoutMs: 1000,
+		LogLevel:  "info",
+	}
+
+	app := mustNew(t, cfg)
+
+	// Mock the terminal detector to avoid TERM environment issues in CI
+	mockEnv := &terminal.Environment{
+		StartSeq: "\033]",
+		EndSeq:   "\a",
+	}
+	app.detector = &mockDetector{env: mockEnv, err: nil}
+
+	err := app.Run()
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestApp_Run_ServerFailures(t *testing.T) {
+	payload := []byte("Test MOTD Message")
+	frame := motdtest.EncodeFrame(motdtest.FrameMessage, motdtest.Response{Body: payload})
+
+	tests := []struct {
+		name     string
+		behavior motdtest.Behavior
+		want     failure.Kind
+	}{
+		{name: "hang", behavior: motdtest.Hang(), want: failure.KindTimeout},
+		{name: "partial write then hang", behavior: motdtest.PartialThenHang(payload, 4), want: failure.KindTimeout},
+		{name: "empty", behavior: motdtest.Empty(), want: failure.KindEmptyMessage},
+		{
+			name:     "truncated frame",
+			behavior: motdtest.VersionedRaw(func(motdtest.Hello) []byte { return frame[:len(frame)-4] }),
+			want:     failure.KindTruncated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			cfg := &config.Config{
+				Host:      srv.Host(),
+				Port:      srv.Port(),
+				TimeoutMs: 100,
+				LogLevel:  "info",
+			}
+
+			app := mustNew(t, cfg)
+			app.detector = &mockDetector{env: &terminal.Environment{StartSeq: "\033]", EndSeq: "\a"}}
+
+			err := app.Run()
+			if got := failure.KindOf(err); got != tt.want {
+				t.Errorf("Run() error kind = %s, want %s (error: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApp_Run_WithReferenceServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("Reference MOTD"), 0o644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	store, err := server.NewStore(dir, server.SelectRandom)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
 	listener, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
 		t.Fatalf("Failed to create test server: %v", err)
 	}
-	defer listener.Close()
+	srv := server.New(store, time.Second)
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Host:      "localhost",
+		Port:      listener.Addr().(*net.TCPAddr).Port,
+		TimeoutMs: 1000,
+		LogLevel:  "info",
+	}
+
+	app := mustNew(t, cfg)
+	app.detector = &mockDetector{env: &terminal.Environment{StartSeq: "\033]", EndSeq: "\a"}}
+
+	if err := app.Run(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestApp_Run_SignatureVerification(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	signed := &network.Message{ContentType: network.ContentTypeText, Body: []byte("Signed MOTD")}
+	if err := signed.Sign(priv); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	var frames bytes.Buffer
+	if err := network.WriteMessage(&frames, network.ProtocolVersion, signed, network.WriteOptions{Checksum: true}); err != nil {
+		t.Fatalf("WriteMessage() unexpected error: %v", err)
+	}
+	signedServer := motdtest.VersionedRaw(func(motdtest.Hello) []byte { return frames.Bytes() })
+	unsignedServer := motdtest.Versioned([]byte("Forged MOTD"))
+	unsignedCache := &network.Message{Body: []byte("Cached MOTD")}
+
+	tests := []struct {
+		name      string
+		behavior  motdtest.Behavior
+		cached    *network.Message
+		want      failure.Kind
+		wantCache bool
+	}{
+		{name: "signed message is cached", behavior: signedServer, want: failure.KindUnknown, wantCache: true},
+		{name: "unsigned message without cache", behavior: unsignedServer, want: failure.KindSignature},
+		{name: "unsigned message falls back to cache", behavior: unsignedServer, cached: signed, want: failure.KindUnknown, wantCache: true},
+		{name: "unsigned cache is not trusted", behavior: unsignedServer, cached: unsignedCache, want: failure.KindSignature, wantCache: true},
+	}
 
-	addr := listener.Addr().(*net.TCPAddr)
-	port := addr.Port
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			cachePath := filepath.Join(t.TempDir(), "message.json")
+			if tt.cached != nil {
+				if err := cache.New(cachePath).Store(tt.cached); err != nil {
+					t.Fatalf("Store() unexpected error: %v", err)
+				}
+			}
+
+			cfg := &config.Config{
+				Host:        srv.Host(),
+				Port:        srv.Port(),
+				TimeoutMs:   1000,
+				LogLevel:    "info",
+				TrustedKeys: []string{base64.StdEncoding.EncodeToString(pub)},
+				CacheFile:   cachePath,
+			}
+			app := mustNew(t, cfg)
+			app.detector = &mockDetector{env: &terminal.Environment{StartSeq: "\033]", EndSeq: "\a"}}
+
+			err := app.Run()
+			if got := failure.KindOf(err); got != tt.want {
+				t.Errorf("Run() error kind = %s, want %s (error: %v)", got, tt.want, err)
+			}
+			if _, err := os.Stat(cachePath); (err == nil) != tt.wantCache {
+				t.Errorf("Cache file exists = %v, want %v", err == nil, tt.wantCache)
+			}
+		})
+	}
+}
 
-	// Start server goroutine
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
+func TestApp_Run_HTTP(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
-		defer conn.Close()
-		conn.Write([]byte("Test MOTD Message"))
-	}()
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("HTTP MOTD"))
+	}))
+	defer srv.Close()
 
+	cachePath := filepath.Join(t.TempDir(), "message.json")
 	cfg := &config.Config{
 		Host:      "localhost",
-		Port:      port,
+		Port:      4200,
 		TimeoutMs: 1000,
 		LogLevel:  "info",
+		URL:       srv.URL,
+		CacheFile: cachePath,
 	}
 
-	app := New(cfg)
-
-	// Mock the terminal detector to avoid TERM environment issues in CI
-	mockEnv := &terminal.Environment{
-		StartSeq: "\033]",
-		EndSeq:   "\a",
+	for range 2 {
+		app := mustNew(t, cfg)
+		app.detector = &mockDetector{env: &terminal.Environment{StartSeq: "\033]", EndSeq: "\a"}}
+		if err := app.Run(); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
 	}
-	app.detector = &mockDetector{env: mockEnv, err: nil}
-
-	err = app.Run()
 
+	cached, err := cache.New(cachePath).Load()
 	if err != nil {
-		t.Errorf("Unexpected error: %v", err)
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cached.ETag != `"v1"` || string(cached.Body) != "HTTP MOTD" {
+		t.Errorf("Cached message = %+v, want HTTP MOTD with ETag", cached)
+	}
+	if requests != 2 {
+		t.Errorf("Server saw %d requests, want 2", requests)
 	}
 }
 
@@ -103,14 +333,76 @@ func TestApp_displayMessage(t *testing.T) {
 		LogLevel:  "info",
 	}
 
-	app := New(cfg)
+	app := mustNew(t, cfg)
 	app.formatter = terminal.NewFormatter(env)
 
 	// Test with non-empty message
-	app.displayMessage("Test Message")
+	app.displayMessage(&network.Message{ContentType: network.ContentTypeText, Body: []byte("Test Message")})
 
 	// Test with empty message
-	app.displayMessage("")
+	app.displayMessage(&network.Message{})
+
+	// Test with expired message
+	app.displayMessage(&network.Message{
+		ContentType: network.ContentTypeText,
+		Metadata:    network.Metadata{Expires: time.Now().Add(-time.Hour)},
+		Body:        []byte("Old news"),
+	})
+}
+
+func TestApp_newFormatter_Theme(t *testing.T) {
+	app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", Theme: "light", Palette: map[string]string{"code": "#ff8700"}})
+	app.formatter = app.newFormatter(&terminal.Environment{ColorDepth: 24})
+	var out bytes.Buffer
+	app.out = &out
+
+	app.displayMessage(&network.Message{ContentType: network.ContentTypeMarkdown, Body: []byte("Run `make`")})
+
+	if want := "Run \033[38;2;255;135;0mmake\033[0m\n"; out.String() != want {
+		t.Errorf("Output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestApp_newFormatter_Layout(t *testing.T) {
+	app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", Wrap: true, Box: "single"})
+	app.formatter = app.newFormatter(&terminal.Environment{Columns: 14})
+	var out bytes.Buffer
+	app.out = &out
+
+	app.displayMessage(&network.Message{ContentType: network.ContentTypeText, Body: []byte("Hello there, world")})
+
+	if want := "┌────────┐\n│ Hello  │\n│ there, │\n│ world  │\n└────────┘\n"; out.String() != want {
+		t.Errorf("Output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestApp_displayMessage_Animation(t *testing.T) {
+	// A 1x2 GIF whose single pixel column turns from red to blue.
+	palette := color.Palette{color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}}
+	animation := &gif.GIF{LoopCount: -1}
+	for i := range palette {
+		frame := image.NewPaletted(image.Rect(0, 0, 1, 2), palette)
+		frame.Pix = []uint8{uint8(i), uint8(i)}
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 2)
+	}
+	var data bytes.Buffer
+	if err := gif.EncodeAll(&data, animation); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+
+	app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", AnimationLoops: 3, AnimationMaxSec: 5})
+	app.formatter = terminal.NewFormatter(&terminal.Environment{ColorDepth: 24, Columns: 80, Rows: 24})
+	var out bytes.Buffer
+	app.out = &out
+
+	app.displayMessage(&network.Message{ContentType: network.ContentTypeGIF, Body: data.Bytes()})
+
+	want := "\033[38;2;255;0;0m\033[48;2;255;0;0m▀\033[0m\n" +
+		"\033[?25l\0337\033[1A\r\033[38;2;0;0;255m\033[48;2;0;0;255m▀\033[0m\0338\033[?25h"
+	if out.String() != want {
+		t.Errorf("Output = %q, want the first frame followed by the second played once", out.String())
+	}
 }
 
 // Mock implementations for testing
@@ -124,38 +416,35 @@ func (m *mockDetector) Detect() (*terminal.Environment, error) {
 }
 
 type mockClient struct {
-	conn net.Conn
-	err  error
+	conn     net.Conn
+	err      error
+	message  *network.Message
+	fetchErr error
+	hello    network.Hello
 }
 
 func (m *mockClient) Connect() (net.Conn, error) {
 	return m.conn, m.err
 }
 
-func (m *mockClient) FetchMessage(conn net.Conn) (string, error) {
-	return "Mock Message", nil
+func (m *mockClient) FetchMessage(conn net.Conn, hello network.Hello) (*network.Message, error) {
+	m.hello = hello
+	if m.fetchErr != nil {
+		return nil, m.fetchErr
+	}
+	if m.message != nil {
+		return m.message, nil
+	}
+	return &network.Message{Body: []byte("Mock Message")}, nil
 }
 
 func TestApp_Run_WithMocks(t *testing.T) {
 	// Create a test server to get a real connection
-	listener, err := net.Listen("tcp", "localhost:0")
-	if err != nil {
-		t.Fatalf("Failed to create test server: %v", err)
-	}
-	defer listener.Close()
-
-	// Start server goroutine
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
-			return
-		}
-		defer conn.Close()
-		conn.Write([]byte("Test Message"))
-	}()
+	srv := motdtest.NewServer(motdtest.PayloadString("Test Message"))
+	defer srv.Close()
 
 	// Connect to the test server to get a real connection
-	conn, err := net.Dial("tcp", listener.Addr().String())
+	conn, err := net.Dial("tcp", srv.Addr())
 	if err != nil {
 		t.Fatalf("Failed to connect to test server: %v", err)
 	}
@@ -168,7 +457,7 @@ func TestApp_Run_WithMocks(t *testing.T) {
 		LogLevel:  "info",
 	}
 
-	app := New(cfg)
+	app := mustNew(t, cfg)
 
 	// Test with successful detection
 	mockEnv := &terminal.Environment{
@@ -195,7 +484,7 @@ func TestApp_Run_DetectionError(t *testing.T) {
 		LogLevel:  "info",
 	}
 
-	app := New(cfg)
+	app := mustNew(t, cfg)
 
 	// Test with detection error
 	app.detector = &mockDetector{env: nil, err: terminal.ErrTerminalNotSet}
@@ -205,3 +494,125 @@ func TestApp_Run_DetectionError(t *testing.T) {
 		t.Error("Expected error when terminal detection fails")
 	}
 }
+
+func TestApp_Run_ErrorKinds(t *testing.T) {
+	empty := &network.Message{}
+
+	tests := []struct {
+		name   string
+		client *mockClient
+		want   failure.Kind
+	}{
+		{
+			name:   "connect refused",
+			client: &mockClient{err: errors.New("connection refused")},
+			want:   failure.KindConnect,
+		},
+		{
+			name:   "connect timeout",
+			client: &mockClient{err: &net.OpError{Op: "dial", Err: timeoutError{}}},
+			want:   failure.KindTimeout,
+		},
+		{
+			name:   "read error",
+			client: &mockClient{fetchErr: errors.New("connection reset")},
+			want:   failure.KindProtocol,
+		},
+		{
+			name:   "read timeout",
+			client: &mockClient{fetchErr: &net.OpError{Op: "read", Err: timeoutError{}}},
+			want:   failure.KindTimeout,
+		},
+		{
+			name:   "truncated frame",
+			client: &mockClient{fetchErr: fmt.Errorf("read frame: %w", network.ErrTruncated)},
+			want:   failure.KindTruncated,
+		},
+		{
+			name:   "rejected",
+			client: &mockClient{fetchErr: fmt.Errorf("read: %w", network.ErrRejected)},
+			want:   failure.KindRejected,
+		},
+		{
+			name:   "empty message",
+			client: &mockClient{message: empty},
+			want:   failure.KindEmptyMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			if tt.client.err == nil {
+				tt.client.conn = client
+			}
+
+			cfg := &config.Config{Host: "localhost", Port: 8080, TimeoutMs: 100, LogLevel: "info"}
+			app := mustNew(t, cfg)
+			app.detector = &mockDetector{env: &terminal.Environment{StartSeq: "\033]", EndSeq: "\a"}}
+			app.client = tt.client
+
+			err := app.Run()
+			if got := failure.KindOf(err); got != tt.want {
+				t.Errorf("Run() error kind = %s, want %s (error: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApp_Run_DetectionErrorKind(t *testing.T) {
+	cfg := &config.Config{Host: "localhost", Port: 8080, TimeoutMs: 100, LogLevel: "info"}
+	app := mustNew(t, cfg)
+	app.detector = &mockDetector{err: terminal.ErrTerminalNotSet}
+
+	err := app.Run()
+	if got := failure.KindOf(err); got != failure.KindTerminal {
+		t.Errorf("Run() error kind = %s, want %s", got, failure.KindTerminal)
+	}
+	if !errors.Is(err, terminal.ErrTerminalNotSet) {
+		t.Error("Expected error to wrap ErrTerminalNotSet")
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestApp_Run_SendsCapabilities(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	cfg := &config.Config{Host: "localhost", Port: 8080, TimeoutMs: 100, LogLevel: "info"}
+	app := mustNew(t, cfg)
+	app.detector = &mockDetector{env: &terminal.Environment{
+		StartSeq:   "\033]",
+		EndSeq:     "\a",
+		Graphics:   []string{terminal.GraphicsITerm2},
+		Columns:    132,
+		Rows:       43,
+		ColorDepth: 24,
+
+		Background:          terminal.RGB(253, 246, 227),
+		BackgroundLuminance: 0.91,
+	}}
+	client := &mockClient{conn: conn}
+	app.client = client
+
+	if err := app.Run(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	caps := client.hello.Capabilities
+	if client.hello.ClientVersion != Version {
+		t.Errorf("ClientVersion = %q, want %q", client.hello.ClientVersion, Version)
+	}
+	if len(caps.Graphics) != 1 || caps.Graphics[0] != terminal.GraphicsITerm2 ||
+		caps.Columns != 132 || caps.Rows != 43 || caps.ColorDepth != 24 ||
+		caps.Background != network.BackgroundLight {
+		t.Errorf("Capabilities = %+v, want environment capabilities", caps)
+	}
+}
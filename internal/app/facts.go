@@ -0,0 +1,41 @@
+package app
+
+import (
+	"log/slog"
+
+	"github.com/stevielcb/motd-client/internal/sysinfo"
+	"github.com/stevielcb/motd-client/internal/terminal"
+)
+
+// systemFacts returns the facts shown in the facts panel, like the
+// update-motd scripts of Debian and Ubuntu show them. Facts that cannot be
+// read are left out.
+func systemFacts() []terminal.Fact {
+	var facts []terminal.Fact
+	add := func(label, value string, err error) {
+		if err != nil {
+			slog.Debug("System fact unavailable", "fact", label, "error", err)
+			return
+		}
+		facts = append(facts, terminal.Fact{Label: label, Value: value})
+	}
+
+	host, err := sysinfo.Hostname()
+	add("Host", host, err)
+	release, err := sysinfo.OSRelease()
+	add("OS", release, err)
+	kernel, err := sysinfo.Kernel()
+	add("Kernel", kernel, err)
+	uptime, err := sysinfo.Uptime()
+	add("Uptime", sysinfo.FormatDuration(uptime), err)
+	load, err := sysinfo.LoadAverage()
+	add("Load", load.String(), err)
+	memory, err := sysinfo.Memory()
+	add("Memory", memory.String(), err)
+	disk, err := sysinfo.Disk("/")
+	add("Disk /", disk.String(), err)
+	if sysinfo.RebootRequired() {
+		facts = append(facts, terminal.Fact{Label: "Reboot", Value: "required", Warn: true})
+	}
+	return facts
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/terminal"
+)
+
+func TestSystemFacts(t *testing.T) {
+	facts := systemFacts()
+	if len(facts) == 0 || facts[0].Label != "Host" || facts[0].Value == "" {
+		t.Errorf("systemFacts() = %+v, want the host name first", facts)
+	}
+}
+
+func TestApp_displayMessage_Facts(t *testing.T) {
+	tests := []struct {
+		facts     string
+		wantPanel bool
+	}{
+		{facts: "", wantPanel: false},
+		{facts: terminal.PanelOff, wantPanel: false},
+		{facts: terminal.PanelBelow, wantPanel: true},
+		{facts: terminal.PanelRight, wantPanel: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.facts, func(t *testing.T) {
+			app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", Facts: tt.facts})
+			app.formatter = terminal.NewFormatter(&terminal.Environment{Columns: 200})
+			var out bytes.Buffer
+			app.out = &out
+
+			app.displayMessage(&network.Message{ContentType: network.ContentTypeText, Body: []byte("Hello")})
+
+			if !strings.HasPrefix(out.String(), "Hello") {
+				t.Errorf("Output = %q, want the message first", out.String())
+			}
+			if got := strings.Contains(out.String(), "Host"); got != tt.wantPanel {
+				t.Errorf("Output %q has panel = %v, want %v", out.String(), got, tt.wantPanel)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/failure"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/source"
+)
+
+// messageSource is a configured source and the specification it was
+// created from, used in logs and errors.
+type messageSource struct {
+	spec source.Spec
+	source.Source
+}
+
+// networkSource fetches the message from the configured server with the
+// app's client.
+type networkSource struct {
+	app *App
+}
+
+// Fetch connects to the server and fetches the message. Errors are
+// classified with a failure.Kind.
+func (s *networkSource) Fetch(_ context.Context, hello network.Hello) (*network.Message, error) {
+	return s.app.fetchNetwork(hello)
+}
+
+// newSources creates the configured sources in the order they are tried.
+func newSources(a *App, cfg *config.Config) ([]messageSource, error) {
+	specs, err := cfg.Sources()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []source.Option{}
+	if cfg.MaxSizeKb > 0 {
+		opts = append(opts, source.WithMaxSize(cfg.MaxSizeKb*1024))
+	}
+	if cfg.SourceSelection != "" {
+		opts = append(opts, source.WithSelection(cfg.SourceSelection))
+	}
+	if path, err := cfg.SourceStatePath(); err == nil {
+		opts = append(opts, source.WithStateFile(path))
+	} else {
+		slog.Warn("Source selection state disabled", "error", err)
+	}
+
+	var sources []messageSource
+	for _, spec := range specs {
+		if spec.Kind == source.KindNetwork {
+			sources = append(sources, messageSource{spec: spec, Source: &networkSource{app: a}})
+			continue
+		}
+		src, err := source.New(spec, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message source %s: %w", spec, err)
+		}
+		sources = append(sources, messageSource{spec: spec, Source: src})
+	}
+	return sources, nil
+}
+
+// fetch returns the message of the first source that provides one. When
+// every source fails, the first source's error is returned. Errors are
+// classified with a failure.Kind.
+func (a *App) fetch(ctx context.Context, hello network.Hello, sources []messageSource) (*network.Message, error) {
+	var firstErr error
+	for i, src := range sources {
+		message, err := src.Fetch(ctx, hello)
+		if err == nil && len(message.Body) == 0 {
+			err = failure.Wrap(failure.KindEmptyMessage, ErrEmptyMessage)
+		}
+		if err == nil {
+			if i > 0 {
+				slog.Info("Displaying message from fallback source", "source", src.spec)
+			}
+			return message, nil
+		}
+
+		if failure.KindOf(err) == failure.KindUnknown {
+			err = failure.Wrap(failure.KindSource, fmt.Errorf("failed to read message from %s: %w", src.spec, err))
+		}
+		if i < len(sources)-1 {
+			slog.Warn("Message source failed, trying the next one", "source", src.spec, "error", err)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// fetchNetwork connects to the server and fetches its message. A message
+// that fails verification is replaced by the cached one, if any; verified
+// messages are cached.
+func (a *App) fetchNetwork(hello network.Hello) (*network.Message, error) {
+	conn, err := a.client.Connect()
+	if err != nil {
+		return nil, failure.WrapNetwork(failure.KindConnect, fmt.Errorf("failed to connect to server: %w", err))
+	}
+	defer conn.Close()
+
+	message, err := a.client.FetchMessage(conn, hello)
+	switch {
+	case errors.Is(err, network.ErrSignature):
+		if message, err = a.fallback(err); err != nil {
+			return nil, failure.Wrap(failure.KindSignature, err)
+		}
+	case err != nil:
+		kind := failure.KindProtocol
+		switch {
+		case errors.Is(err, network.ErrTruncated):
+			kind = failure.KindTruncated
+		case errors.Is(err, network.ErrRejected):
+			kind = failure.KindRejected
+		}
+		return nil, failure.WrapNetwork(kind, fmt.Errorf("failed to fetch message: %w", err))
+	case len(message.Body) == 0:
+		return nil, failure.Wrap(failure.KindEmptyMessage, ErrEmptyMessage)
+	default:
+		a.remember(message)
+	}
+	return message, nil
+}
+
+// networkPrimary reports whether messages come from the server first.
+func (a *App) networkPrimary() bool {
+	return a.sources[0].spec.Kind == source.KindNetwork
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/failure"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/source"
+	"github.com/stevielcb/motd-client/internal/terminal"
+)
+
+// writeMOTD writes body to a file in a new directory and returns its path.
+func writeMOTD(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "motd.txt")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	return path
+}
+
+func TestNewSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		want    []string
+		wantErr bool
+	}{
+		{name: "default", want: []string{"network"}},
+		{
+			name: "local with network fallback",
+			cfg:  config.Config{MessageSource: "file:/etc/motd", FallbackSource: "network"},
+			want: []string{"file:/etc/motd", "network"},
+		},
+		{name: "invalid source", cfg: config.Config{MessageSource: "ftp:/etc/motd"}, wantErr: true},
+		{name: "invalid selection", cfg: config.Config{MessageSource: "dir:/etc/motd.d", SourceSelection: "shuffled"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+			sources, err := newSources(&App{}, &tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, src := range sources {
+				got = append(got, src.spec.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Sources = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Sources = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestApp_Run_Sources(t *testing.T) {
+	local := writeMOTD(t, "Local MOTD")
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+
+	tests := []struct {
+		name     string
+		primary  string
+		fallback string
+		client   *mockClient
+		want     string
+		wantKind failure.Kind
+	}{
+		{
+			name:    "local primary",
+			primary: "file:" + local,
+			client:  &mockClient{err: errors.New("should not connect")},
+			want:    "Local MOTD\n",
+		},
+		{
+			name:     "network failure falls back to a local file",
+			primary:  "network",
+			fallback: "file:" + local,
+			client:   &mockClient{err: errors.New("connection refused")},
+			want:     "Local MOTD\n",
+		},
+		{
+			name:     "local failure falls back to the network",
+			primary:  "file:" + missing,
+			fallback: "network",
+			want:     "Mock Message\n",
+		},
+		{
+			name:     "network error is reported when every source fails",
+			primary:  "network",
+			fallback: "file:" + missing,
+			client:   &mockClient{err: errors.New("connection refused")},
+			wantKind: failure.KindConnect,
+		},
+		{
+			name:     "local error",
+			primary:  "file:" + missing,
+			wantKind: failure.KindSource,
+		},
+		{
+			name:     "empty local message",
+			primary:  "file:" + writeMOTD(t, ""),
+			wantKind: failure.KindEmptyMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, peer := net.Pipe()
+			defer peer.Close()
+			client := tt.client
+			if client == nil {
+				client = &mockClient{}
+			}
+			if client.err == nil {
+				client.conn = conn
+			}
+
+			cfg := &config.Config{
+				Host:           "localhost",
+				Port:           4200,
+				TimeoutMs:      100,
+				LogLevel:       "info",
+				CacheFile:      filepath.Join(t.TempDir(), "cache.json"),
+				MessageSource:  tt.primary,
+				FallbackSource: tt.fallback,
+			}
+			app := mustNew(t, cfg)
+			app.client = client
+			app.detector = &mockDetector{env: &terminal.Environment{}}
+			var out bytes.Buffer
+			app.out = &out
+
+			err := app.Run()
+			if got := failure.KindOf(err); got != tt.wantKind {
+				t.Fatalf("Run() error kind = %s, want %s (error: %v)", got, tt.wantKind, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Run_LocalMessageIsNotCached(t *testing.T) {
+	cfg := &config.Config{
+		Host:          "localhost",
+		Port:          4200,
+		TimeoutMs:     100,
+		LogLevel:      "info",
+		CacheFile:     filepath.Join(t.TempDir(), "cache.json"),
+		MessageSource: "file:" + writeMOTD(t, "Local MOTD"),
+	}
+	app := mustNew(t, cfg)
+	app.detector = &mockDetector{env: &terminal.Environment{}}
+	app.out = &bytes.Buffer{}
+
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(cfg.CacheFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Cache file exists after showing a local message (stat error: %v)", err)
+	}
+}
+
+func TestApp_Watch_LocalSource(t *testing.T) {
+	cfg := &config.Config{
+		Host:             "localhost",
+		Port:             4200,
+		TimeoutMs:        100,
+		LogLevel:         "info",
+		WatchIntervalSec: 60,
+		CacheFile:        filepath.Join(t.TempDir(), "cache.json"),
+		MessageSource:    "file:" + writeMOTD(t, "Local MOTD"),
+	}
+	app := mustNew(t, cfg)
+	app.client = &mockClient{err: errors.New("should not connect")}
+	app.detector = &mockDetector{env: &terminal.Environment{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &cancelWriter{until: "Local MOTD", cancel: cancel}
+	app.out = out
+
+	if err := app.Watch(ctx); err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	if out.String() != terminal.ClearScreen+"Local MOTD\n" {
+		t.Errorf("Output = %q, want the local message", out.String())
+	}
+}
+
+func TestApp_Watch_FallbackWhileDisconnected(t *testing.T) {
+	cfg := &config.Config{
+		Host:             "localhost",
+		Port:             4200,
+		TimeoutMs:        100,
+		LogLevel:         "info",
+		WatchIntervalSec: 60,
+		CacheFile:        filepath.Join(t.TempDir(), "cache.json"),
+		FallbackSource:   "file:" + writeMOTD(t, "Offline MOTD"),
+	}
+	app := mustNew(t, cfg)
+	app.client = &mockClient{err: errors.New("connection refused")}
+	app.detector = &mockDetector{env: &terminal.Environment{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &cancelWriter{until: "Offline MOTD", cancel: cancel}
+	app.out = out
+
+	if err := app.Watch(ctx); err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	if out.String() != terminal.ClearScreen+"Offline MOTD\n" {
+		t.Errorf("Output = %q, want the fallback message", out.String())
+	}
+}
+
+func TestApp_fetch_SourceErrorKind(t *testing.T) {
+	app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info"})
+	src, err := source.New(source.Spec{Kind: source.KindDir, Arg: t.TempDir()})
+	if err != nil {
+		t.Fatalf("source.New() unexpected error: %v", err)
+	}
+
+	_, err = app.fetch(context.Background(), network.Hello{}, []messageSource{{spec: source.Spec{Kind: source.KindDir}, Source: src}})
+	if got := failure.KindOf(err); got != failure.KindSource {
+		t.Errorf("fetch() error kind = %s, want %s", got, failure.KindSource)
+	}
+	if !errors.Is(err, source.ErrNoMessages) {
+		t.Errorf("fetch() error = %v, want it to wrap ErrNoMessages", err)
+	}
+}
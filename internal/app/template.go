@@ -0,0 +1,203 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode"
+
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/sysinfo"
+)
+
+// templateContentTypes lists the content types whose body may refer to
+// template variables.
+var templateContentTypes = []string{
+	network.ContentTypeText, network.ContentTypeANSI, network.ContentTypeMarkdown,
+}
+
+// errTemplateAction is returned for template actions other than variables
+// and conditionals.
+var errTemplateAction = errors.New("only variables such as {{.User}} and {{if .Branch}} conditionals are allowed")
+
+// expand returns message with the template variables in its body replaced
+// by their local values. Messages that use no variables are returned as is,
+// as are messages whose templates are invalid, since they were most likely
+// never meant as templates.
+func (a *App) expand(message *network.Message) *network.Message {
+	if !a.cfg.Templates || !slices.Contains(templateContentTypes, message.ContentType) ||
+		!bytes.Contains(message.Body, []byte("{{")) {
+		return message
+	}
+
+	body, err := expandTemplate(message.Body, templateVars{now: time.Now()})
+	if err != nil {
+		slog.Warn("Displaying message without expanding its template", "id", message.Metadata.ID, "error", err)
+		return message
+	}
+
+	expanded := *message
+	expanded.Body = body
+	return &expanded
+}
+
+// expandTemplate executes body as a text/template with vars as its data.
+// Templates are limited to variables and conditionals, so a message
+// cannot call functions or loop.
+func expandTemplate(body []byte, vars any) ([]byte, error) {
+	tmpl, err := template.New("message").Parse(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if err := checkTemplate(tmpl.Tree.Root); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("failed to parse template: %w", errTemplateAction)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// checkTemplate rejects template nodes other than text, variables and
+// conditionals on variables.
+func checkTemplate(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplate(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.TextNode:
+		return nil
+	case *parse.ActionNode:
+		return checkPipe(n.Pipe)
+	case *parse.IfNode:
+		if err := checkPipe(n.Pipe); err != nil {
+			return err
+		}
+		if err := checkTemplate(n.List); err != nil {
+			return err
+		}
+		return checkTemplate(n.ElseList)
+	default:
+		return fmt.Errorf("%w, got %s", errTemplateAction, node)
+	}
+}
+
+// checkPipe rejects pipelines other than a single variable.
+func checkPipe(pipe *parse.PipeNode) error {
+	if len(pipe.Decl) == 0 && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+		if field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode); ok && len(field.Ident) == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w, got {{%s}}", errTemplateAction, pipe)
+}
+
+// templateVars are the variables available to message templates. Each is
+// a method, so only the facts a message uses are read. Facts that cannot
+// be read are empty.
+type templateVars struct {
+	now time.Time
+}
+
+// Host is the host name.
+func (templateVars) Host() string {
+	return fact(sysinfo.Hostname())
+}
+
+// User is the login name of the current user.
+func (templateVars) User() string {
+	return fact(sysinfo.Username())
+}
+
+// Uptime is how long the system has been running, e.g. "3 days, 4 hours".
+func (templateVars) Uptime() string {
+	uptime, err := sysinfo.Uptime()
+	if err != nil {
+		slog.Debug("Template variable unavailable", "name", "Uptime", "error", err)
+		return ""
+	}
+	return sysinfo.FormatDuration(uptime)
+}
+
+// Load is the load average, e.g. "0.52 0.58 0.59".
+func (templateVars) Load() string {
+	load, err := sysinfo.LoadAverage()
+	if err != nil {
+		slog.Debug("Template variable unavailable", "name", "Load", "error", err)
+		return ""
+	}
+	return load.String()
+}
+
+// Date is today's date, e.g. "Monday, January 2, 2006".
+func (v templateVars) Date() string {
+	return v.now.Format("Monday, January 2, 2006")
+}
+
+// Time is the time of day, e.g. "15:04".
+func (v templateVars) Time() string {
+	return v.now.Format("15:04")
+}
+
+// Branch is the git branch of the working directory, if any.
+func (templateVars) Branch() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return fact(sysinfo.GitBranch(dir))
+}
+
+// LastLogin is the user's previous login, e.g. "Mon Jan 2 15:04 from
+// 192.0.2.1".
+func (templateVars) LastLogin() string {
+	username, err := sysinfo.Username()
+	if err != nil {
+		return ""
+	}
+	login, err := sysinfo.LastLogin(username)
+	if err != nil {
+		slog.Debug("Template variable unavailable", "name", "LastLogin", "error", err)
+		return ""
+	}
+	return sanitizeFact(login.String())
+}
+
+// fact returns value, or "" if reading it failed. Control characters are
+// removed so values cannot inject escape sequences.
+func fact(value string, err error) string {
+	if err != nil {
+		slog.Debug("Template variable unavailable", "error", err)
+		return ""
+	}
+	return sanitizeFact(value)
+}
+
+// sanitizeFact removes control characters from value.
+func sanitizeFact(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/terminal"
+)
+
+// stubVars are fixed template variables for tests.
+type stubVars struct {
+	User, Host, Branch string
+}
+
+func TestExpandTemplate(t *testing.T) {
+	vars := stubVars{User: "alice", Host: "build-01"}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{name: "variables", body: "Good morning {{.User}}, {{.Host}} is up", want: "Good morning alice, build-01 is up"},
+		{name: "trim markers", body: "Hi {{- .User -}} !", want: "Hialice!"},
+		{name: "conditional", body: "{{if .Branch}}on {{.Branch}}{{else}}no repo{{end}}", want: "no repo"},
+		{name: "comment", body: "Hi{{/* greeting */}}", want: "Hi"},
+		{name: "range", body: "{{range 1000000000}}x{{end}}", wantErr: errTemplateAction},
+		{name: "function", body: `{{printf "%0999999d" 1}}`, wantErr: errTemplateAction},
+		{name: "pipeline", body: "{{.User | len}}", wantErr: errTemplateAction},
+		{name: "variable declaration", body: "{{$u := .User}}{{$u}}", wantErr: errTemplateAction},
+		{name: "define", body: `{{define "x"}}x{{end}}{{.User}}`, wantErr: errTemplateAction},
+		{name: "with", body: "{{with .User}}{{.}}{{end}}", wantErr: errTemplateAction},
+		{name: "nested field", body: "{{.User.Name}}", wantErr: errTemplateAction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandTemplate([]byte(tt.body), vars)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expandTemplate() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expandTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, body := range []string{"{{.Unknown}}", "{{.User", "{{end}}"} {
+		if _, err := expandTemplate([]byte(body), vars); err == nil {
+			t.Errorf("expandTemplate(%q) expected error, got nil", body)
+		}
+	}
+}
+
+func TestApp_expand(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates bool
+		message   network.Message
+		want      string
+	}{
+		{
+			name:      "text",
+			templates: true,
+			message:   network.Message{ContentType: network.ContentTypeText, Body: []byte("Today is {{.Date}}")},
+			want:      "Today is " + time.Now().Format("Monday, January 2, 2006"),
+		},
+		{
+			name:      "disabled",
+			templates: false,
+			message:   network.Message{ContentType: network.ContentTypeText, Body: []byte("Today is {{.Date}}")},
+			want:      "Today is {{.Date}}",
+		},
+		{
+			name:      "images are not templates",
+			templates: true,
+			message:   network.Message{ContentType: network.ContentTypePNG, Body: []byte("{{.Date}}")},
+			want:      "{{.Date}}",
+		},
+		{
+			name:      "invalid template is shown as is",
+			templates: true,
+			message:   network.Message{ContentType: network.ContentTypeMarkdown, Body: []byte("Use {{range}} loops")},
+			want:      "Use {{range}} loops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", Templates: tt.templates})
+			original := bytes.Clone(tt.message.Body)
+
+			got := app.expand(&tt.message)
+			if string(got.Body) != tt.want {
+				t.Errorf("expand() body = %q, want %q", got.Body, tt.want)
+			}
+			if !bytes.Equal(tt.message.Body, original) {
+				t.Error("expand() modified the original message")
+			}
+		})
+	}
+}
+
+func TestApp_displayMessage_Template(t *testing.T) {
+	app := mustNew(t, &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", Templates: true})
+	app.formatter = terminal.NewFormatter(&terminal.Environment{})
+	var out bytes.Buffer
+	app.out = &out
+
+	app.displayMessage(&network.Message{ContentType: network.ContentTypeText, Body: []byte("It is {{.Time}}.")})
+
+	want := "It is " + time.Now().Format("15:04") + ".\n"
+	if out.String() != want {
+		t.Errorf("Output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	vars := templateVars{now: time.Date(2026, 10, 19, 9, 5, 0, 0, time.UTC)}
+	if got := vars.Date(); got != "Monday, October 19, 2026" {
+		t.Errorf("Date() = %q", got)
+	}
+	if got := vars.Time(); got != "09:05" {
+		t.Errorf("Time() = %q", got)
+	}
+	if vars.Host() == "" {
+		t.Error("Host() returned an empty name")
+	}
+}
+
+func TestSanitizeFact(t *testing.T) {
+	if got := sanitizeFact("evil\033]52;c;payload\a-branch"); got != "evil]52;c;payload-branch" {
+		t.Errorf("sanitizeFact() = %q", got)
+	}
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/failure"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/terminal"
+)
+
+// Reconnection backoff bounds for watch mode. The delay doubles after every
+// failed attempt and is reset once a message arrives.
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// defaultWatchInterval is used when the configuration sets no interval.
+const defaultWatchInterval = 5 * time.Minute
+
+// Watch displays the MOTD and redraws it whenever the server publishes a new
+// one, until ctx is canceled. Servers that support watching keep the
+// connection open and push new messages; others, including HTTP servers,
+// are polled every watch interval, as are local sources. Lost connections
+// are retried with exponential backoff, showing the fallback source's
+// message meanwhile. Only terminal detection errors end the watch.
+func (a *App) Watch(ctx context.Context) error {
+	env, err := a.detector.Detect()
+	if err != nil {
+		return failure.Wrap(failure.KindTerminal, fmt.Errorf("failed to detect terminal environment: %w", err))
+	}
+	a.formatter = a.newFormatter(env)
+
+	interval := a.cfg.WatchInterval()
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	backoff := minBackoff
+	for {
+		received, err := a.watchOnce(ctx, env)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if received {
+			backoff = minBackoff
+		}
+		wait := interval
+		if err != nil {
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+			slog.Warn("Watch interrupted, reconnecting", "error", err, "retry_in", wait)
+			if !received && a.networkPrimary() && len(a.sources) > 1 {
+				if message, err := a.fetch(ctx, hello(env), a.sources[1:]); err == nil {
+					a.redraw(message)
+				}
+			}
+		} else {
+			slog.Debug("Server closed the connection, polling again later", "poll_in", wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// watchOnce connects and shows messages until the connection ends, or
+// shows the message of the sources when they do not start with the
+// network. A message that fails verification is replaced by the cached
+// one, as in Run. It reports whether any message arrived.
+func (a *App) watchOnce(ctx context.Context, env *terminal.Environment) (bool, error) {
+	if !a.networkPrimary() {
+		message, err := a.fetch(ctx, hello(env), a.sources)
+		if err != nil {
+			return false, err
+		}
+		a.redraw(message)
+		return true, nil
+	}
+
+	conn, err := a.client.Connect()
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer conn.Close()
+
+	received := false
+	show := func(message *network.Message) error {
+		received = true
+		if len(message.Body) > 0 {
+			a.remember(message)
+		}
+		a.redraw(message)
+		return nil
+	}
+
+	if sub, ok := a.client.(network.Subscriber); ok {
+		err = sub.Watch(ctx, conn, hello(env), show)
+	} else {
+		var message *network.Message
+		if message, err = a.client.FetchMessage(conn, hello(env)); err == nil {
+			show(message)
+		}
+	}
+	if errors.Is(err, network.ErrSignature) {
+		message, err := a.fallback(err)
+		if err != nil {
+			return received, err
+		}
+		a.redraw(message)
+		return true, nil
+	}
+	if err != nil {
+		return received, fmt.Errorf("failed to fetch message: %w", err)
+	}
+	return received, nil
+}
+
+// redraw clears the screen and displays message, unless it is the message
+// already shown.
+func (a *App) redraw(message *network.Message) {
+	if len(message.Body) == 0 {
+		slog.Warn("Received empty message from server")
+		return
+	}
+	if shown := a.shown; shown != nil && shown.ContentType == message.ContentType &&
+		shown.Metadata == message.Metadata && bytes.Equal(shown.Body, message.Body) {
+		slog.Debug("Message unchanged", "id", message.Metadata.ID)
+		return
+	}
+
+	a.shown = message
+	fmt.Fprint(a.out, terminal.ClearScreen)
+	a.displayMessage(message)
+}
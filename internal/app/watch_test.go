@@ -0,0 +1,210 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/cache"
+	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/failure"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/terminal"
+	"github.com/stevielcb/motd-client/motdtest"
+)
+
+// cancelWriter records output and calls cancel once it contains until.
+type cancelWriter struct {
+	bytes.Buffer
+	until  string
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	if strings.Contains(w.String(), w.until) {
+		w.cancel()
+	}
+	return n, err
+}
+
+// watchApp returns an app for srv that writes to a cancelWriter.
+func watchApp(t *testing.T, srv *motdtest.Server, out *cancelWriter) *App {
+	t.Helper()
+	cfg := &config.Config{
+		Host:             srv.Host(),
+		Port:             srv.Port(),
+		TimeoutMs:        1000,
+		LogLevel:         "info",
+		WatchIntervalSec: 60,
+	}
+	app := mustNew(t, cfg)
+	app.detector = &mockDetector{env: &terminal.Environment{StartSeq: "\033]", EndSeq: "\a"}}
+	app.out = out
+	return app
+}
+
+func TestApp_Watch(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior motdtest.Behavior
+		want     string
+	}{
+		{
+			name:     "stream",
+			behavior: motdtest.Stream(10*time.Millisecond, []byte("One"), []byte("Two")),
+			want:     terminal.ClearScreen + "One\n" + terminal.ClearScreen + "Two\n",
+		},
+		{
+			name: "reconnects after a failure",
+			behavior: motdtest.Sequence(
+				motdtest.ResetAfter(nil, 0),
+				motdtest.Stream(10*time.Millisecond, []byte("One"), []byte("Two")),
+			),
+			want: terminal.ClearScreen + "One\n" + terminal.ClearScreen + "Two\n",
+		},
+		{
+			name:     "unchanged message is not redrawn",
+			behavior: motdtest.Stream(10*time.Millisecond, []byte("One"), []byte("One"), []byte("Two")),
+			want:     terminal.ClearScreen + "One\n" + terminal.ClearScreen + "Two\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			out := &cancelWriter{until: "Two", cancel: cancel}
+
+			if err := watchApp(t, srv, out).Watch(ctx); err != nil {
+				t.Fatalf("Watch() unexpected error: %v", err)
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				t.Fatalf("Watch() did not show every message, output %q", out.String())
+			}
+			if out.String() != tt.want {
+				t.Errorf("Output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Watch_Polls(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	client := &mockClient{conn: conn, message: &network.Message{ContentType: network.ContentTypeText, Body: []byte("Polled")}}
+	cfg := &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info", WatchIntervalSec: 60}
+	app := mustNew(t, cfg)
+	app.client = client
+	app.detector = &mockDetector{env: &terminal.Environment{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &cancelWriter{until: "Polled", cancel: cancel}
+	app.out = out
+
+	if err := app.Watch(ctx); err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	if out.String() != terminal.ClearScreen+"Polled\n" {
+		t.Errorf("Output = %q, want the polled message", out.String())
+	}
+}
+
+func TestApp_Watch_HTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("HTTP MOTD"))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		URL:              srv.URL,
+		CacheFile:        filepath.Join(t.TempDir(), "message.json"),
+		TimeoutMs:        1000,
+		LogLevel:         "info",
+		WatchIntervalSec: 60,
+	}
+	app := mustNew(t, cfg)
+	if _, ok := app.client.(network.Subscriber); ok {
+		t.Fatal("HTTP client is a Subscriber, want it polled")
+	}
+	app.detector = &mockDetector{env: &terminal.Environment{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &cancelWriter{until: "HTTP MOTD", cancel: cancel}
+	app.out = out
+
+	if err := app.Watch(ctx); err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	if out.String() != terminal.ClearScreen+"HTTP MOTD\n" {
+		t.Errorf("Output = %q, want the message fetched over HTTP", out.String())
+	}
+}
+
+func TestApp_Watch_SignatureFallback(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	cached := &network.Message{ContentType: network.ContentTypeText, Body: []byte("Cached MOTD")}
+	if err := cached.Sign(priv); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "message.json")
+	if err := cache.New(cachePath).Store(cached); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	cfg := &config.Config{
+		Host:             "localhost",
+		Port:             4200,
+		TimeoutMs:        100,
+		LogLevel:         "info",
+		TrustedKeys:      []string{base64.StdEncoding.EncodeToString(pub)},
+		CacheFile:        cachePath,
+		WatchIntervalSec: 60,
+	}
+	app := mustNew(t, cfg)
+	app.client = &mockClient{conn: conn, fetchErr: network.ErrUnsigned}
+	app.detector = &mockDetector{env: &terminal.Environment{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &cancelWriter{until: "Cached MOTD", cancel: cancel}
+	app.out = out
+
+	if err := app.Watch(ctx); err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	if out.String() != terminal.ClearScreen+"Cached MOTD\n" {
+		t.Errorf("Output = %q, want the cached message", out.String())
+	}
+}
+
+func TestApp_Watch_DetectionError(t *testing.T) {
+	cfg := &config.Config{Host: "localhost", Port: 4200, TimeoutMs: 100, LogLevel: "info"}
+	app := mustNew(t, cfg)
+	app.detector = &mockDetector{err: terminal.ErrTerminalNotSet}
+
+	err := app.Watch(context.Background())
+	if got := failure.KindOf(err); got != failure.KindTerminal {
+		t.Errorf("Watch() error kind = %s, want %s", got, failure.KindTerminal)
+	}
+}
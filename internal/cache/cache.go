@@ -0,0 +1,132 @@
+// Package cache persists the last trusted message so it can be displayed
+// when a fresh message fails verification, or reused when an HTTP server
+// reports it unchanged.
+package cache
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// DefaultFile is the cache file name inside the user's cache directory.
+const DefaultFile = "motd-client/message.json"
+
+// Cache stores a single message in a file.
+type Cache struct {
+	path string
+	keys []ed25519.PublicKey
+}
+
+// Option configures optional Cache behavior.
+type Option func(*Cache)
+
+// WithTrustedKeys makes Load verify the cached message's signature against
+// keys, so a message stored before keys were configured, or signed with a
+// key no longer trusted, is never returned.
+func WithTrustedKeys(keys []ed25519.PublicKey) Option {
+	return func(c *Cache) {
+		c.keys = keys
+	}
+}
+
+// entry is the on-disk representation of a cached message. The signature
+// covers the declared content type and raw metadata, not the parsed ones.
+type entry struct {
+	ContentType  string           `json:"content_type"`
+	Metadata     network.Metadata `json:"metadata"`
+	Body         []byte           `json:"body"`
+	ETag         string           `json:"etag,omitempty"`
+	Signature    []byte           `json:"signature,omitempty"`
+	DeclaredType string           `json:"declared_type,omitempty"`
+	RawMetadata  []byte           `json:"raw_metadata,omitempty"`
+}
+
+// New creates a cache backed by the file at path.
+func New(path string, opts ...Option) *Cache {
+	c := &Cache{path: path}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// DefaultPath returns the cache file in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(dir, DefaultFile), nil
+}
+
+// Path returns the cache file path.
+func (c *Cache) Path() string {
+	return c.path
+}
+
+// Store replaces the cached message with msg. The file is written
+// atomically and readable only by the current user.
+func (c *Cache) Store(msg *network.Message) error {
+	declaredType, rawMetadata := msg.SignedFields()
+	data, err := json.Marshal(entry{
+		ContentType:  msg.ContentType,
+		Metadata:     msg.Metadata,
+		Body:         msg.Body,
+		ETag:         msg.ETag,
+		Signature:    msg.Signature,
+		DeclaredType: declaredType,
+		RawMetadata:  rawMetadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode cached message: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".message-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to replace cache file: %w", err)
+	}
+	return nil
+}
+
+// Load returns the cached message. The error matches os.ErrNotExist if
+// nothing has been cached yet, and network.ErrSignature if trusted keys
+// are set and the message fails verification.
+func (c *Cache) Load() (*network.Message, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode cached message: %w", err)
+	}
+	msg := &network.Message{ContentType: e.ContentType, Metadata: e.Metadata, Body: e.Body, ETag: e.ETag, Signature: e.Signature}
+	msg.SetSignedFields(e.DeclaredType, e.RawMetadata)
+	if len(c.keys) > 0 {
+		if err := msg.Verify(c.keys); err != nil {
+			return nil, fmt.Errorf("cached message cannot be trusted: %w", err)
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestCache_StoreLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "message.json")
+	c := New(path)
+
+	msg := &network.Message{
+		ContentType: network.ContentTypeANSI,
+		Metadata:    network.Metadata{ID: "42", Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Body:        []byte("\033[1mHello\033[0m"),
+		ETag:        `"abc"`,
+	}
+	if err := c.Store(msg); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat cache file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Errorf("Cache file permissions = %v, want no group or other access", perm)
+	}
+
+	got, err := c.Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if got.ContentType != msg.ContentType || got.ETag != msg.ETag || got.Metadata != msg.Metadata || !bytes.Equal(got.Body, msg.Body) {
+		t.Errorf("Load() = %+v, want %+v", got, msg)
+	}
+}
+
+func TestCache_StoreReplaces(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "message.json"))
+
+	for _, body := range []string{"First", "Second"} {
+		if err := c.Store(&network.Message{Body: []byte(body)}); err != nil {
+			t.Fatalf("Store() unexpected error: %v", err)
+		}
+	}
+
+	got, err := c.Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if string(got.Body) != "Second" {
+		t.Errorf("Load() body = %q, want %q", got.Body, "Second")
+	}
+}
+
+func TestCache_TrustedKeys(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	signed := &network.Message{
+		ContentType: network.ContentTypeText,
+		Metadata:    network.Metadata{ID: "42"},
+		Body:        []byte("Signed MOTD"),
+	}
+	if err := signed.Sign(priv); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		msg     *network.Message
+		keys    []ed25519.PublicKey
+		wantErr error
+	}{
+		{name: "signed", msg: signed, keys: []ed25519.PublicKey{pub}},
+		{name: "unsigned", msg: &network.Message{Body: []byte("Unsigned MOTD")}, keys: []ed25519.PublicKey{pub}, wantErr: network.ErrUnsigned},
+		{name: "untrusted key", msg: signed, keys: []ed25519.PublicKey{other}, wantErr: network.ErrBadSignature},
+		{name: "no keys", msg: &network.Message{Body: []byte("Unsigned MOTD")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "message.json")
+			if err := New(path).Store(tt.msg); err != nil {
+				t.Fatalf("Store() unexpected error: %v", err)
+			}
+
+			got, err := New(path, WithTrustedKeys(tt.keys)).Load()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got.Body, tt.msg.Body) {
+				t.Errorf("Load() body = %q, want %q", got.Body, tt.msg.Body)
+			}
+		})
+	}
+}
+
+func TestCache_LoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := New(filepath.Join(dir, "missing.json")).Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() of a missing file error = %v, want os.ErrNotExist", err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("{"), 0o600)
+	if _, err := New(corrupt).Load(); err == nil {
+		t.Error("Expected an error loading a corrupt cache file")
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/cache")
+	t.Setenv("HOME", "/home/test")
+
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath() unexpected error: %v", err)
+	}
+	if filepath.Base(path) != "message.json" {
+		t.Errorf("DefaultPath() = %q, want a message.json file", path)
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+)
+
+// ErrCheckFailed is returned by Check when the configuration is invalid.
+var ErrCheckFailed = errors.New("configuration check failed")
+
+// Check loads the configuration from the environment and writes a report of
+// every setting, where it came from, unknown MOTD_* variables and all
+// validation problems to w. It returns ErrCheckFailed if the configuration
+// would be rejected by Load.
+func Check(w io.Writer) error {
+	cfg, err := load()
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return ErrCheckFailed
+	}
+
+	keys, err := knownKeys()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tVARIABLE\tVALUE\tSOURCE")
+	value := reflect.ValueOf(cfg).Elem()
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", k.field, k.name, value.FieldByName(k.field), cfg.Source(k.field))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	warnings, err := UnknownVariables()
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			return err
+		}
+		for _, fe := range verr.Errors {
+			fmt.Fprintf(w, "error: %s\n", fe)
+		}
+		return ErrCheckFailed
+	}
+
+	fmt.Fprintln(w, "configuration OK")
+	return nil
+}
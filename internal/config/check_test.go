@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		wantErr  bool
+		contains []string
+	}{
+		{
+			name:    "defaults",
+			envVars: map[string]string{},
+			wantErr: false,
+			contains: []string{
+				"Port MOTD_PORT 4200 default",
+				"configuration OK",
+			},
+		},
+		{
+			name: "multiple problems",
+			envVars: map[string]string{
+				"MOTD_PORT":     "0",
+				"MOTD_LOGLEVEL": "dbug",
+				"MOTD_TIMEOUT":  "500",
+			},
+			wantErr: true,
+			contains: []string{
+				"warning: unknown environment variable MOTD_TIMEOUT (did you mean MOTD_TIMEOUT_MS?)",
+				`error: Port="0" (from MOTD_PORT)`,
+				`error: LogLevel="dbug" (from MOTD_LOGLEVEL)`,
+			},
+		},
+		{
+			name: "unparsable value",
+			envVars: map[string]string{
+				"MOTD_TIMEOUT_MS": "soon",
+			},
+			wantErr:  true,
+			contains: []string{`TimeoutMs="soon" (from MOTD_TIMEOUT_MS)`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			var buf bytes.Buffer
+			err := Check(&buf)
+
+			if tt.wantErr != errors.Is(err, ErrCheckFailed) {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Collapse the table's column padding before matching.
+			output := strings.Join(strings.Fields(buf.String()), " ")
+			for _, want := range tt.contains {
+				if !strings.Contains(output, want) {
+					t.Errorf("Check() output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -2,12 +2,48 @@
 package config
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/stevielcb/motd-client/internal/cache"
+	"github.com/stevielcb/motd-client/internal/failure"
+	"github.com/stevielcb/motd-client/internal/logger"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/server"
+	"github.com/stevielcb/motd-client/internal/source"
+	"github.com/stevielcb/motd-client/internal/terminal"
 )
 
+// envPrefix is the prefix shared by all environment variables read by the client.
+const envPrefix = "motd"
+
+// SourceDefault marks a value that was not set explicitly and kept its default.
+const SourceDefault = "default"
+
+// ProxyDirect disables proxies, including those set in the environment.
+const ProxyDirect = "direct"
+
+// LogLevels lists the accepted values for Config.LogLevel.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// LogFormats lists the accepted values for Config.LogFormat.
+var LogFormats = []string{logger.FormatText, logger.FormatLogfmt, logger.FormatJSON}
+
+// LogOutputs lists the accepted values for Config.LogOutput.
+var LogOutputs = []string{
+	logger.OutputAuto, logger.OutputStderr, logger.OutputFile, logger.OutputSyslog, logger.OutputNone,
+}
+
 // Config holds the application configuration settings.
 // It can be configured through environment variables with the "MOTD_" prefix.
 type Config struct {
@@ -15,39 +51,520 @@ type Config struct {
 	Port      int    `default:"4200"`                   // Server port
 	TimeoutMs int    `default:"100" split_words:"true"` // Connection timeout in milliseconds
 	LogLevel  string `default:"info"`                   // Log level (debug, info, warn, error)
+
+	URL      string // Fetch messages over HTTP(S) from this URL instead of Host and Port
+	Proxy    string // SOCKS5 or HTTP CONNECT proxy URL, or "direct"; defaults to ALL_PROXY/HTTPS_PROXY
+	Network  string `default:"tcp"` // Address family (tcp, tcp4, tcp6)
+	Resolver string // DNS server used to resolve host names, instead of the system's
+
+	WatchIntervalSec int `default:"300" split_words:"true"` // Watch mode poll interval for servers that close the connection
+
+	Templates bool   `default:"true"` // Expand template variables such as {{.User}} in text messages
+	Facts     string `default:"off"`  // System facts panel position (off, right, below)
+
+	Wrap  bool   `default:"true"` // Wrap text messages to the terminal width
+	Align string `default:"left"` // Text message alignment (left, center)
+	Box   string `default:"none"` // Box drawn around text messages (none, single, rounded, double, heavy)
+
+	Theme   string            `default:"auto"` // Color theme (auto, dark, light)
+	Palette map[string]string // Colors replacing the theme's, e.g. code:#ff8700,warn:red
+
+	BackgroundTimeoutMs int `default:"100" split_words:"true"` // How long to wait for the terminal to report its background color, 0 to not ask
+
+	AnimationLoops  int `default:"3" split_words:"true"` // Times animated GIFs drawn with half blocks are played, 0 to show the first frame
+	AnimationMaxSec int `default:"5" split_words:"true"` // Longest time animations may hold up the shell
+
+	MessageSource   string `default:"network" split_words:"true"` // Where messages come from: network, file:PATH, dir:PATH, fortune:PATH or command:CMD
+	FallbackSource  string `split_words:"true"`                   // Source used when MessageSource fails, same syntax
+	SourceSelection string `default:"random" split_words:"true"`  // How dir and fortune sources pick a message (random, sequential)
+
+	LogFormat     string `default:"text" split_words:"true"` // Log format (text, logfmt, json)
+	LogOutput     string `default:"auto" split_words:"true"` // Log destination (auto, stderr, file, syslog, none)
+	LogFile       string `split_words:"true"`                // Log file path, used by the file and auto outputs
+	LogMaxSizeKb  int    `default:"1024" split_words:"true"` // Rotate the log file past this size
+	LogMaxBackups int    `default:"3" split_words:"true"`    // Number of rotated log files to keep
+
+	OnError   string `default:"fail" split_words:"true"`  // Error mode (silent, warn, fail)
+	Protocol  string `default:"auto"`                     // Protocol mode (auto, v1, legacy)
+	MaxSizeKb int    `default:"16384" split_words:"true"` // Largest message accepted from the server
+
+	TrustedKeys []string `split_words:"true"` // Base64 Ed25519 public keys; messages must be signed by one
+	CacheFile   string   `split_words:"true"` // Last trusted message, shown when verification fails
+
+	AuthKeyId   string `default:"default" split_words:"true"` // Key ID sent with authenticated requests
+	AuthKeyFile string `split_words:"true"`                   // Shared secret used to authenticate requests
+
+	ServeDir        string            `default:"." split_words:"true"`      // Message directory for the serve command
+	ServeSelection  string            `default:"random" split_words:"true"` // Message selection (random, sequential, date)
+	ServeProtocol   string            `default:"v1" split_words:"true"`     // Protocol spoken by the serve command (v1, legacy)
+	ServeSigningKey string            `split_words:"true"`                  // PEM Ed25519 private key used to sign served messages
+	ServeAuthKeys   map[string]string `split_words:"true"`                  // Key IDs and secret files clients must authenticate with
+
+	ServeWatchIntervalSec int `default:"60" split_words:"true"` // How often watching clients are sent a new message
+
+	// sources maps field names to the environment variable they were read
+	// from. It is populated by Load and used to annotate validation errors.
+	sources map[string]string
+}
+
+// FieldError describes a single invalid configuration field.
+type FieldError struct {
+	Field  string // Go field name, e.g. "Port"
+	Value  string // Offending value as text
+	Reason string // Human readable explanation
+	Source string // Environment variable the value came from, or SourceDefault
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s=%q (from %s): %s", e.Field, e.Value, e.Source, e.Reason)
+}
+
+// ValidationError collects every FieldError found while validating a Config.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap exposes the individual field errors to errors.Is and errors.As.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
 }
 
 // Validate checks if the configuration is valid.
+// All invalid fields are reported together in a *ValidationError.
+// Files named by the configuration, such as auth keys, are not read here
+// but where they are used.
 func (c *Config) Validate() error {
+	v := &ValidationError{}
+	add := func(field string, value any, format string, args ...any) {
+		v.Errors = append(v.Errors, &FieldError{
+			Field:  field,
+			Value:  fmt.Sprint(value),
+			Reason: fmt.Sprintf(format, args...),
+			Source: c.Source(field),
+		})
+	}
+
 	if c.Host == "" {
-		return fmt.Errorf("host cannot be empty")
+		add("Host", c.Host, "host cannot be empty")
 	}
 	if c.Port <= 0 || c.Port > 65535 {
-		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+		add("Port", c.Port, "port must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.URL != "" {
+		if _, err := network.ParseURL(c.URL); err != nil {
+			add("URL", c.URL, "%v", err)
+		}
+	}
+	if c.Proxy != "" && c.Proxy != ProxyDirect {
+		if _, err := network.ParseProxy(c.Proxy); err != nil {
+			add("Proxy", c.Proxy, "%v", err)
+		}
+	}
+	if c.Network != "" && !slices.Contains(network.Networks, c.Network) {
+		add("Network", c.Network, "network must be one of %s", strings.Join(network.Networks, ", "))
+	}
+	if c.Resolver != "" {
+		if _, err := network.ParseDNSServer(c.Resolver); err != nil {
+			add("Resolver", c.Resolver, "%v", err)
+		}
+	}
+	if c.MessageSource != "" {
+		if _, err := source.Parse(c.MessageSource); err != nil {
+			add("MessageSource", c.MessageSource, "%v", err)
+		}
+	}
+	if c.FallbackSource != "" {
+		if _, err := source.Parse(c.FallbackSource); err != nil {
+			add("FallbackSource", c.FallbackSource, "%v", err)
+		}
+	}
+	if c.SourceSelection != "" && !slices.Contains(source.Selections, c.SourceSelection) {
+		add("SourceSelection", c.SourceSelection, "source selection must be one of %s", strings.Join(source.Selections, ", "))
+	}
+	if c.Facts != "" && !slices.Contains(terminal.PanelPositions, c.Facts) {
+		add("Facts", c.Facts, "facts panel must be one of %s", strings.Join(terminal.PanelPositions, ", "))
+	}
+	if c.Align != "" && !slices.Contains(terminal.Alignments, c.Align) {
+		add("Align", c.Align, "alignment must be one of %s", strings.Join(terminal.Alignments, ", "))
+	}
+	if c.Box != "" && !slices.Contains(terminal.BoxStyles, c.Box) {
+		add("Box", c.Box, "box style must be one of %s", strings.Join(terminal.BoxStyles, ", "))
+	}
+	if c.Theme != "" && !slices.Contains(terminal.ThemeNames, c.Theme) {
+		add("Theme", c.Theme, "theme must be one of %s", strings.Join(terminal.ThemeNames, ", "))
+	}
+	for _, role := range slices.Sorted(maps.Keys(c.Palette)) {
+		if _, err := terminal.ParsePalette(map[string]string{role: c.Palette[role]}); err != nil {
+			add("Palette", role+":"+c.Palette[role], "%v", err)
+		}
+	}
+	if c.BackgroundTimeoutMs < 0 {
+		add("BackgroundTimeoutMs", c.BackgroundTimeoutMs, "background timeout cannot be negative, got %d", c.BackgroundTimeoutMs)
+	}
+	if c.AnimationLoops < 0 {
+		add("AnimationLoops", c.AnimationLoops, "animation loops cannot be negative, got %d", c.AnimationLoops)
+	}
+	if c.AnimationMaxSec < 0 {
+		add("AnimationMaxSec", c.AnimationMaxSec, "animation duration cannot be negative, got %d", c.AnimationMaxSec)
+	}
+	if c.WatchIntervalSec < 0 {
+		add("WatchIntervalSec", c.WatchIntervalSec, "watch interval cannot be negative, got %d", c.WatchIntervalSec)
+	}
+	if c.ServeWatchIntervalSec < 0 {
+		add("ServeWatchIntervalSec", c.ServeWatchIntervalSec, "serve watch interval cannot be negative, got %d", c.ServeWatchIntervalSec)
 	}
 	if c.TimeoutMs <= 0 {
-		return fmt.Errorf("timeout must be positive, got %d", c.TimeoutMs)
+		add("TimeoutMs", c.TimeoutMs, "timeout must be positive, got %d", c.TimeoutMs)
+	}
+	if !slices.Contains(LogLevels, c.LogLevel) {
+		add("LogLevel", c.LogLevel, "log level must be one of %s", strings.Join(LogLevels, ", "))
+	}
+	if c.LogFormat != "" && !slices.Contains(LogFormats, c.LogFormat) {
+		add("LogFormat", c.LogFormat, "log format must be one of %s", strings.Join(LogFormats, ", "))
+	}
+	if c.LogOutput != "" && !slices.Contains(LogOutputs, c.LogOutput) {
+		add("LogOutput", c.LogOutput, "log output must be one of %s", strings.Join(LogOutputs, ", "))
+	}
+	if c.LogOutput == logger.OutputFile && c.LogFile == "" {
+		add("LogFile", c.LogFile, "log file must be set when log output is %s", logger.OutputFile)
+	}
+	if c.LogMaxSizeKb < 0 {
+		add("LogMaxSizeKb", c.LogMaxSizeKb, "log max size cannot be negative, got %d", c.LogMaxSizeKb)
+	}
+	if c.LogMaxBackups < 0 {
+		add("LogMaxBackups", c.LogMaxBackups, "log max backups cannot be negative, got %d", c.LogMaxBackups)
+	}
+	if c.OnError != "" && !slices.Contains(failure.Modes, c.OnError) {
+		add("OnError", c.OnError, "error mode must be one of %s", strings.Join(failure.Modes, ", "))
+	}
+	if c.Protocol != "" && !slices.Contains(network.Protocols, c.Protocol) {
+		add("Protocol", c.Protocol, "protocol must be one of %s", strings.Join(network.Protocols, ", "))
+	}
+	if c.MaxSizeKb < 0 {
+		add("MaxSizeKb", c.MaxSizeKb, "max size cannot be negative, got %d", c.MaxSizeKb)
+	}
+	for _, key := range c.TrustedKeys {
+		if _, err := network.ParsePublicKey(key); err != nil {
+			add("TrustedKeys", key, "%v", err)
+		}
+	}
+	if len(c.ServeAuthKeys) > 0 && c.ServeProtocol == server.ProtocolLegacy {
+		add("ServeAuthKeys", c.ServeAuthKeys, "authentication requires serve protocol %s", server.ProtocolV1)
+	}
+	if c.ServeProtocol != "" && !slices.Contains(server.Protocols, c.ServeProtocol) {
+		add("ServeProtocol", c.ServeProtocol, "serve protocol must be one of %s", strings.Join(server.Protocols, ", "))
+	}
+	if c.ServeSelection != "" && !slices.Contains(server.Selections, c.ServeSelection) {
+		add("ServeSelection", c.ServeSelection, "selection must be one of %s", strings.Join(server.Selections, ", "))
+	}
+
+	if len(v.Errors) > 0 {
+		return v
 	}
 	return nil
 }
 
+// Source reports where the value of the named field came from: the
+// environment variable that set it, or SourceDefault.
+func (c *Config) Source(field string) string {
+	if key, ok := c.sources[field]; ok {
+		return key
+	}
+	return SourceDefault
+}
+
+// LogOptions returns the logger settings described by the configuration.
+// In silent error mode the auto output never falls back to stderr.
+func (c *Config) LogOptions() logger.Options {
+	output := c.LogOutput
+	auto := output == "" || output == logger.OutputAuto
+	if auto && c.LogFile == "" && failure.ParseMode(c.OnError) == failure.ModeSilent {
+		output = logger.OutputNone
+	}
+
+	return logger.Options{
+		Level:      c.LogLevel,
+		Format:     c.LogFormat,
+		Output:     output,
+		File:       c.LogFile,
+		MaxSizeKB:  c.LogMaxSizeKb,
+		MaxBackups: c.LogMaxBackups,
+	}
+}
+
+// PublicKeys returns the decoded trusted keys.
+func (c *Config) PublicKeys() ([]ed25519.PublicKey, error) {
+	keys := make([]ed25519.PublicKey, 0, len(c.TrustedKeys))
+	for _, s := range c.TrustedKeys {
+		key, err := network.ParsePublicKey(s)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ProxyFunc returns how to choose the proxy for connections: the explicit
+// proxy, none for ProxyDirect, or the environment's when unset.
+func (c *Config) ProxyFunc() (network.ProxyFunc, error) {
+	switch c.Proxy {
+	case "":
+		return network.ProxyFromEnvironment, nil
+	case ProxyDirect:
+		return nil, nil
+	}
+	u, err := network.ParseProxy(c.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return network.ProxyURL(u), nil
+}
+
+// AuthKey returns the shared secret used to authenticate requests, or nil
+// if authentication is not configured.
+func (c *Config) AuthKey() ([]byte, error) {
+	if c.AuthKeyFile == "" {
+		return nil, nil
+	}
+	return network.LoadAuthKey(c.AuthKeyFile)
+}
+
+// ServeAuthSecrets loads the shared secrets of the serve command, indexed by
+// key ID.
+func (c *Config) ServeAuthSecrets() (map[string][]byte, error) {
+	secrets := make(map[string][]byte, len(c.ServeAuthKeys))
+	for id, path := range c.ServeAuthKeys {
+		secret, err := network.LoadAuthKey(path)
+		if err != nil {
+			return nil, err
+		}
+		secrets[id] = secret
+	}
+	return secrets, nil
+}
+
+// CachePath returns the message cache file, defaulting to one in the
+// user's cache directory.
+func (c *Config) CachePath() (string, error) {
+	if c.CacheFile != "" {
+		return c.CacheFile, nil
+	}
+	return cache.DefaultPath()
+}
+
+// Sources returns where messages come from, in the order they are tried:
+// MessageSource, defaulting to the network, then FallbackSource if set.
+func (c *Config) Sources() ([]source.Spec, error) {
+	primary := c.MessageSource
+	if primary == "" {
+		primary = source.KindNetwork
+	}
+
+	var specs []source.Spec
+	for _, s := range []string{primary, c.FallbackSource} {
+		if s == "" {
+			continue
+		}
+		spec, err := source.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// SourceStatePath returns the file recording the position of sequential
+// source selection, next to the message cache.
+func (c *Config) SourceStatePath() (string, error) {
+	path, err := c.CachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), source.DefaultStateFile), nil
+}
+
 // Timeout returns the timeout as a time.Duration.
 func (c *Config) Timeout() time.Duration {
 	return time.Duration(c.TimeoutMs) * time.Millisecond
 }
 
+// WatchInterval returns how long watch mode waits before polling servers
+// that do not keep the connection open.
+func (c *Config) WatchInterval() time.Duration {
+	return time.Duration(c.WatchIntervalSec) * time.Second
+}
+
+// BackgroundTimeout returns how long to wait for the terminal to report
+// its background color.
+func (c *Config) BackgroundTimeout() time.Duration {
+	return time.Duration(c.BackgroundTimeoutMs) * time.Millisecond
+}
+
+// AnimationMaxDuration returns the longest time an animation is played.
+func (c *Config) AnimationMaxDuration() time.Duration {
+	return time.Duration(c.AnimationMaxSec) * time.Second
+}
+
+// ServeWatchInterval returns how often the serve command checks for a new
+// message for watching clients. Zero disables watching.
+func (c *Config) ServeWatchInterval() time.Duration {
+	return time.Duration(c.ServeWatchIntervalSec) * time.Second
+}
+
 // Load loads configuration from environment variables.
 func Load() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// load processes the environment without validating the result.
+func load() (*Config, error) {
 	var cfg Config
 
-	err := envconfig.Process("motd", &cfg)
+	err := envconfig.Process(envPrefix, &cfg)
 	if err != nil {
+		var perr *envconfig.ParseError
+		if errors.As(err, &perr) {
+			err = &ValidationError{Errors: []*FieldError{{
+				Field:  perr.FieldName,
+				Value:  perr.Value,
+				Reason: fmt.Sprintf("cannot parse as %s", perr.TypeName),
+				Source: perr.KeyName,
+			}}}
+		}
 		return nil, fmt.Errorf("failed to process environment configuration: %w", err)
 	}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	keys, err := knownKeys()
+	if err != nil {
+		return nil, err
+	}
+	cfg.sources = make(map[string]string)
+	for _, k := range keys {
+		if _, ok := os.LookupEnv(k.name); ok {
+			cfg.sources[k.field] = k.name
+		}
 	}
 
 	return &cfg, nil
 }
+
+// envKey pairs a Config field with the environment variable that sets it.
+type envKey struct {
+	field string
+	name  string
+}
+
+// knownKeys returns the environment variable for every Config field, in
+// declaration order, exactly as envconfig resolves them.
+func knownKeys() ([]envKey, error) {
+	var buf bytes.Buffer
+	err := envconfig.Usagef(envPrefix, &Config{}, &buf, "{{range .}}{{.Name}} {{.Key}}\n{{end}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configuration keys: %w", err)
+	}
+
+	var keys []envKey
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		field, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok {
+			keys = append(keys, envKey{field: field, name: name})
+		}
+	}
+	return keys, nil
+}
+
+// UnknownVariables returns a warning for every MOTD_* environment variable
+// that does not correspond to a configuration field, suggesting the closest
+// known name when the variable looks like a typo.
+func UnknownVariables() ([]string, error) {
+	keys, err := knownKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make([]string, 0, len(keys))
+	for _, k := range keys {
+		known = append(known, k.name)
+	}
+
+	prefix := strings.ToUpper(envPrefix) + "_"
+	var warnings []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix) || slices.Contains(known, name) {
+			continue
+		}
+
+		warning := fmt.Sprintf("unknown environment variable %s", name)
+		if suggestion := closest(name, known); suggestion != "" {
+			warning += fmt.Sprintf(" (did you mean %s?)", suggestion)
+		}
+		warnings = append(warnings, warning)
+	}
+	slices.Sort(warnings)
+
+	return warnings, nil
+}
+
+// closest returns the candidate nearest to name, or "" if none is close
+// enough to plausibly be what the user meant.
+func closest(name string, candidates []string) string {
+	best, bestDist := "", 4
+	for _, c := range candidates {
+		d := distance(name, c)
+		if strings.HasPrefix(c, name) || strings.HasPrefix(name, c) {
+			d = min(d, 1)
+		}
+		if d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// distance computes the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,189 @@
+// Package failure classifies application errors into kinds with distinct
+// exit codes and reports them according to the configured error mode.
+package failure
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// Kind identifies the category of a failure.
+type Kind int
+
+// Failure kinds, each mapped to its own exit code.
+const (
+	KindUnknown Kind = iota
+	KindUsage
+	KindConfig
+	KindTerminal
+	KindConnect
+	KindTimeout
+	KindProtocol
+	KindEmptyMessage
+	KindTruncated
+	KindSignature
+	KindRejected
+	KindSource
+)
+
+// String returns the name of the kind as used in logs.
+func (k Kind) String() string {
+	switch k {
+	case KindUsage:
+		return "usage"
+	case KindConfig:
+		return "config"
+	case KindTerminal:
+		return "terminal"
+	case KindConnect:
+		return "connect"
+	case KindTimeout:
+		return "timeout"
+	case KindProtocol:
+		return "protocol"
+	case KindEmptyMessage:
+		return "empty_message"
+	case KindTruncated:
+		return "truncated"
+	case KindSignature:
+		return "signature"
+	case KindRejected:
+		return "rejected"
+	case KindSource:
+		return "source"
+	default:
+		return "unknown"
+	}
+}
+
+// ExitCode returns the process exit status for the kind.
+func (k Kind) ExitCode() int {
+	switch k {
+	case KindUsage:
+		return 2
+	case KindConfig:
+		return 3
+	case KindTerminal:
+		return 4
+	case KindConnect:
+		return 5
+	case KindTimeout:
+		return 6
+	case KindProtocol:
+		return 7
+	case KindEmptyMessage:
+		return 8
+	case KindTruncated:
+		return 9
+	case KindSignature:
+		return 10
+	case KindRejected:
+		return 11
+	case KindSource:
+		return 12
+	default:
+		return 1
+	}
+}
+
+// Error attaches a Kind to an underlying error.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap annotates err with kind. It returns nil if err is nil.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// WrapNetwork annotates a network error with kind, or with KindTimeout if
+// the error was caused by a deadline or dial timeout.
+func WrapNetwork(kind Kind, err error) error {
+	if IsTimeout(err) {
+		kind = KindTimeout
+	}
+	return Wrap(kind, err)
+}
+
+// IsTimeout reports whether err was caused by a timeout.
+func IsTimeout(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain, or
+// KindUnknown if there is none.
+func KindOf(err error) Kind {
+	var ferr *Error
+	if errors.As(err, &ferr) {
+		return ferr.Kind
+	}
+	return KindUnknown
+}
+
+// Mode controls how a failure is surfaced to the user.
+type Mode string
+
+// Supported error modes.
+const (
+	// ModeSilent prints nothing and exits successfully, so shell startup
+	// is never disrupted.
+	ModeSilent Mode = "silent"
+	// ModeWarn prints a one-line warning and exits successfully.
+	ModeWarn Mode = "warn"
+	// ModeFail prints the error and exits with the kind's exit code.
+	ModeFail Mode = "fail"
+)
+
+// Modes lists the accepted error mode names.
+var Modes = []string{string(ModeSilent), string(ModeWarn), string(ModeFail)}
+
+// ParseMode converts a mode name to a Mode, defaulting to ModeFail for
+// empty or unknown names.
+func ParseMode(name string) Mode {
+	switch Mode(name) {
+	case ModeSilent, ModeWarn:
+		return Mode(name)
+	default:
+		return ModeFail
+	}
+}
+
+// Report writes err to w as required by mode and returns the exit code
+// the process should terminate with.
+func Report(w io.Writer, mode Mode, err error) int {
+	if err == nil {
+		return 0
+	}
+
+	switch mode {
+	case ModeSilent:
+		return 0
+	case ModeWarn:
+		fmt.Fprintf(w, "motd-client: %v\n", err)
+		return 0
+	default:
+		fmt.Fprintf(w, "motd-client: %s error: %v\n", KindOf(err), err)
+		return KindOf(err).ExitCode()
+	}
+}
@@ -0,0 +1,129 @@
+package failure
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestKind_ExitCode(t *testing.T) {
+	seen := make(map[int]Kind)
+	kinds := []Kind{
+		KindUnknown, KindUsage, KindConfig, KindTerminal,
+		KindConnect, KindTimeout, KindProtocol, KindEmptyMessage, KindTruncated,
+		KindSignature, KindRejected, KindSource,
+	}
+
+	for _, kind := range kinds {
+		code := kind.ExitCode()
+		if code == 0 {
+			t.Errorf("%s exit code must not be 0", kind)
+		}
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s share exit code %d", kind, other, code)
+		}
+		seen[code] = kind
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	base := errors.New("boom")
+
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{name: "plain error", err: base, want: KindUnknown},
+		{name: "wrapped", err: Wrap(KindConfig, base), want: KindConfig},
+		{name: "wrapped twice", err: fmt.Errorf("context: %w", Wrap(KindProtocol, base)), want: KindProtocol},
+		{name: "deadline", err: WrapNetwork(KindProtocol, fmt.Errorf("read: %w", os.ErrDeadlineExceeded)), want: KindTimeout},
+		{name: "network non-timeout", err: WrapNetwork(KindConnect, base), want: KindConnect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap_Nil(t *testing.T) {
+	if Wrap(KindConfig, nil) != nil {
+		t.Error("Expected Wrap(nil) to return nil")
+	}
+}
+
+func TestIsTimeout_DialTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect to test server: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+
+	if !IsTimeout(err) {
+		t.Errorf("Expected read deadline error to be a timeout, got %v", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	err := Wrap(KindConnect, errors.New("connection refused"))
+
+	tests := []struct {
+		name     string
+		mode     Mode
+		err      error
+		wantCode int
+		wantOut  string
+	}{
+		{name: "success", mode: ModeFail, err: nil, wantCode: 0, wantOut: ""},
+		{name: "silent", mode: ModeSilent, err: err, wantCode: 0, wantOut: ""},
+		{name: "warn", mode: ModeWarn, err: err, wantCode: 0, wantOut: "motd-client: connection refused\n"},
+		{name: "fail", mode: ModeFail, err: err, wantCode: 5, wantOut: "motd-client: connect error: connection refused\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			code := Report(&buf, tt.mode, tt.err)
+
+			if code != tt.wantCode {
+				t.Errorf("Report() = %d, want %d", code, tt.wantCode)
+			}
+			if buf.String() != tt.wantOut {
+				t.Errorf("Report() wrote %q, want %q", buf.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]Mode{
+		"silent":  ModeSilent,
+		"warn":    ModeWarn,
+		"fail":    ModeFail,
+		"":        ModeFail,
+		"unknown": ModeFail,
+	}
+
+	for name, want := range tests {
+		if got := ParseMode(name); got != want {
+			t.Errorf("ParseMode(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -2,31 +2,157 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 )
 
+// Supported log formats.
+const (
+	FormatText   = "text"
+	FormatLogfmt = "logfmt" // Alias for FormatText, which emits logfmt
+	FormatJSON   = "json"
+)
+
+// Supported log outputs.
+const (
+	// OutputAuto logs to File when set, to stderr when stdout is not a
+	// terminal, and nowhere otherwise so interactive shells stay clean.
+	OutputAuto   = "auto"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+	OutputNone   = "none"
+)
+
+// Default rotation settings for OutputFile.
+const (
+	DefaultMaxSizeKB  = 1024
+	DefaultMaxBackups = 3
+)
+
+// ErrSyslogUnsupported is returned when OutputSyslog is requested on a
+// platform without a local syslog socket.
+var ErrSyslogUnsupported = errors.New("syslog output is not supported on this platform")
+
+// Options controls where and how log records are written.
+type Options struct {
+	Level      string // debug, info, warn or error
+	Format     string // FormatText, FormatLogfmt or FormatJSON; empty means text
+	Output     string // One of the Output* constants; empty means OutputAuto
+	File       string // Log file path, used by OutputFile and OutputAuto
+	MaxSizeKB  int    // Rotate the log file once it exceeds this size
+	MaxBackups int    // Number of rotated log files to keep
+}
+
 // Setup configures structured logging based on the log level.
 func Setup(logLevel string) {
-	var level slog.Level
+	// Stderr text output cannot fail, so the error is safe to ignore.
+	_, _ = Configure(Options{Level: logLevel, Format: FormatText, Output: OutputStderr})
+}
+
+// Configure installs the default slog logger described by opts. The returned
+// Closer releases any file or socket opened for logging.
+func Configure(opts Options) (io.Closer, error) {
+	handlerOpts := &slog.HandlerOptions{
+		Level:     parseLevel(opts.Level),
+		AddSource: false, // Disable source to reduce noise
+	}
+
+	output := opts.Output
+	if output == "" || output == OutputAuto {
+		output = resolveAuto(opts.File)
+	}
+
+	var (
+		w      io.Writer
+		closer io.Closer = nopCloser{}
+	)
+	switch output {
+	case OutputStderr:
+		w = os.Stderr
+	case OutputNone:
+		w = io.Discard
+	case OutputFile:
+		if opts.File == "" {
+			return nil, fmt.Errorf("log output %q requires a log file path", output)
+		}
+		file, err := newRotatingFile(opts.File, opts.MaxSizeKB, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		w, closer = file, file
+	case OutputSyslog:
+		handler, c, err := newSyslogHandler(opts.Format, handlerOpts)
+		if err != nil {
+			return nil, err
+		}
+		slog.SetDefault(slog.New(handler))
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown log output %q", opts.Output)
+	}
+
+	handler, err := newHandler(w, opts.Format, handlerOpts)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	slog.SetDefault(slog.New(handler))
+	return closer, nil
+}
+
+// parseLevel maps a level name to a slog.Level, defaulting to info.
+func parseLevel(logLevel string) slog.Level {
 	switch logLevel {
 	case "debug":
-		level = slog.LevelDebug
+		return slog.LevelDebug
 	case "info":
-		level = slog.LevelInfo
+		return slog.LevelInfo
 	case "warn":
-		level = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		level = slog.LevelError
+		return slog.LevelError
 	default:
-		level = slog.LevelInfo
+		return slog.LevelInfo
 	}
+}
 
-	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: false, // Disable source to reduce noise
+// newHandler creates a handler writing records in the given format to w.
+func newHandler(w io.Writer, format string, opts *slog.HandlerOptions) (slog.Handler, error) {
+	switch format {
+	case "", FormatText, FormatLogfmt:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
 	}
+}
 
-	handler := slog.NewTextHandler(os.Stderr, opts)
-	slog.SetDefault(slog.New(handler))
+// resolveAuto picks the concrete output used by OutputAuto.
+func resolveAuto(file string) string {
+	if file != "" {
+		return OutputFile
+	}
+	if isTerminal(os.Stdout) {
+		return OutputNone
+	}
+	return OutputStderr
+}
+
+// isTerminal reports whether f refers to a character device such as a tty.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
+
+// nopCloser is returned for outputs that own no resources.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
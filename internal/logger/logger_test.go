@@ -1,7 +1,11 @@
 package logger
 
 import (
+	"encoding/json"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -63,3 +67,113 @@ func TestSetup_LogLevels(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigure(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+		check   func(t *testing.T, contents string)
+	}{
+		{
+			name: "json to file",
+			opts: Options{Level: "info", Format: FormatJSON, Output: OutputFile},
+			check: func(t *testing.T, contents string) {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(contents), &record); err != nil {
+					t.Fatalf("Expected a JSON record, got %q: %v", contents, err)
+				}
+				if record["msg"] != "hello" || record["answer"] != float64(42) {
+					t.Errorf("Unexpected record: %v", record)
+				}
+			},
+		},
+		{
+			name: "logfmt to file",
+			opts: Options{Level: "info", Format: FormatLogfmt, Output: OutputFile},
+			check: func(t *testing.T, contents string) {
+				if !strings.Contains(contents, "msg=hello answer=42") {
+					t.Errorf("Expected logfmt record, got %q", contents)
+				}
+			},
+		},
+		{
+			name: "auto with file",
+			opts: Options{Level: "info", Output: OutputAuto},
+			check: func(t *testing.T, contents string) {
+				if !strings.Contains(contents, "level=INFO msg=hello") {
+					t.Errorf("Expected text record, got %q", contents)
+				}
+			},
+		},
+		{
+			name: "level filters records",
+			opts: Options{Level: "error", Output: OutputFile},
+			check: func(t *testing.T, contents string) {
+				if contents != "" {
+					t.Errorf("Expected no output below error level, got %q", contents)
+				}
+			},
+		},
+		{
+			name:    "unknown format",
+			opts:    Options{Level: "info", Format: "xml", Output: OutputFile},
+			wantErr: true,
+		},
+		{
+			name:    "unknown output",
+			opts:    Options{Level: "info", Output: "printer"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "motd.log")
+			tt.opts.File = path
+
+			closer, err := Configure(tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Configure() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Configure() unexpected error: %v", err)
+			}
+
+			slog.Info("hello", "answer", 42)
+			if err := closer.Close(); err != nil {
+				t.Fatalf("Close() unexpected error: %v", err)
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read log file: %v", err)
+			}
+			tt.check(t, string(contents))
+		})
+	}
+}
+
+func TestConfigure_FileOutputRequiresPath(t *testing.T) {
+	_, err := Configure(Options{Level: "info", Output: OutputFile})
+	if err == nil {
+		t.Error("Expected error when file output has no path")
+	}
+}
+
+func TestResolveAuto(t *testing.T) {
+	if got := resolveAuto("/tmp/motd.log"); got != OutputFile {
+		t.Errorf("resolveAuto() with file = %q, want %q", got, OutputFile)
+	}
+
+	// Under go test stdout is not a terminal, so logs go to stderr.
+	if got := resolveAuto(""); got != OutputStderr {
+		t.Errorf("resolveAuto() without file = %q, want %q", got, OutputStderr)
+	}
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile is an io.WriteCloser that appends to a log file and rotates
+// it once it grows past a size limit, keeping a fixed number of backups
+// named path.1 (newest) through path.N (oldest).
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File // nil if reopening it after a failed rotation failed
+	size       int64
+}
+
+// newRotatingFile opens path for appending. Non-positive limits fall back to
+// DefaultMaxSizeKB; a negative backup count falls back to DefaultMaxBackups.
+func newRotatingFile(path string, maxSizeKB, maxBackups int) (*rotatingFile, error) {
+	if maxSizeKB <= 0 {
+		maxSizeKB = DefaultMaxSizeKB
+	}
+	if maxBackups < 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeKB) * 1024,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write appends p to the file, rotating first if p would exceed the limit.
+// When rotation fails, p is still appended to the current file and the
+// rotation error returned; it is retried on the next write.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rotateErr error
+	if r.file != nil && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		rotateErr = r.rotate()
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, errors.Join(rotateErr, err)
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// Close closes the underlying file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// open opens the current log file and records its size.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups along, moves the current file to path.1 and
+// reopens an empty file in its place. If that fails, the file at path is
+// reopened as it is, leaving r.file nil only if that fails too.
+func (r *rotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	if err := r.shift(); err != nil {
+		return errors.Join(err, r.open())
+	}
+	return r.open()
+}
+
+// shift moves the current file and its backups along, or removes it when
+// no backups are kept.
+func (r *rotatingFile) shift() error {
+	if r.maxBackups == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove log file: %w", err)
+		}
+		return nil
+	}
+
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", r.path, i)
+		to := fmt.Sprintf("%s.%d", r.path, i+1)
+		if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motd.log")
+
+	r, err := newRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile() unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("x", 599) + "\n"
+	for i := 0; i < 4; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+		if info.Size() != int64(len(line)) {
+			t.Errorf("%s size = %d, want %d", name, info.Size(), len(line))
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups, found %s.3", path)
+	}
+}
+
+func TestRotatingFile_NoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motd.log")
+
+	r, err := newRotatingFile(path, 1, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile() unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("y", 800)
+	r.Write([]byte(line))
+	r.Write([]byte(line))
+
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Error("Expected no backup files when maxBackups is 0")
+	}
+	contents, _ := os.ReadFile(path)
+	if string(contents) != line {
+		t.Errorf("Expected file to contain only the latest write, got %d bytes", len(contents))
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motd.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0o600); err != nil {
+		t.Fatalf("Failed to seed log file: %v", err)
+	}
+
+	r, err := newRotatingFile(path, 0, -1)
+	if err != nil {
+		t.Fatalf("newRotatingFile() unexpected error: %v", err)
+	}
+	r.Write([]byte("appended\n"))
+	r.Close()
+
+	if r.maxSize != DefaultMaxSizeKB*1024 || r.maxBackups != DefaultMaxBackups {
+		t.Errorf("Expected defaults, got maxSize=%d maxBackups=%d", r.maxSize, r.maxBackups)
+	}
+	contents, _ := os.ReadFile(path)
+	if string(contents) != "existing\nappended\n" {
+		t.Errorf("Unexpected contents %q", contents)
+	}
+}
+
+func TestRotatingFile_RotationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motd.log")
+
+	r, err := newRotatingFile(path, 1, 1)
+	if err != nil {
+		t.Fatalf("newRotatingFile() unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	// A non-empty directory where the backup goes makes the rename fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o700); err != nil {
+		t.Fatalf("Failed to create blocker: %v", err)
+	}
+
+	line := strings.Repeat("z", 799) + "\n"
+	r.Write([]byte(line))
+	if _, err := r.Write([]byte(line)); err == nil {
+		t.Error("Write() expected the rotation error")
+	}
+	contents, _ := os.ReadFile(path)
+	if string(contents) != line+line {
+		t.Errorf("Expected both writes in the log file after the failed rotation, got %d bytes", len(contents))
+	}
+
+	// Once the backup can be written, rotation resumes.
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatalf("Failed to remove blocker: %v", err)
+	}
+	if _, err := r.Write([]byte(line)); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	if contents, _ := os.ReadFile(path); string(contents) != line {
+		t.Errorf("Expected the log file to hold the latest write, got %d bytes", len(contents))
+	}
+	if contents, _ := os.ReadFile(path + ".1"); string(contents) != line+line {
+		t.Errorf("Expected the backup to hold the earlier writes, got %d bytes", len(contents))
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"io"
+	"log/slog"
+)
+
+// newSyslogHandler reports that syslog is unavailable on this platform.
+func newSyslogHandler(format string, opts *slog.HandlerOptions) (slog.Handler, io.Closer, error) {
+	return nil, nil, ErrSyslogUnsupported
+}
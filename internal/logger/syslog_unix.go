@@ -0,0 +1,106 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"sync"
+)
+
+// syslogTag identifies the client's records in the system log.
+const syslogTag = "motd-client"
+
+// newSyslogHandler connects to the local syslog socket, which journald also
+// serves, and returns a handler that forwards records at matching priorities.
+func newSyslogHandler(format string, opts *slog.HandlerOptions) (slog.Handler, io.Closer, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, syslogTag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	h, err := newSyslogWriterHandler(w, format, opts)
+	if err != nil {
+		w.Close()
+		return nil, nil, err
+	}
+	return h, w, nil
+}
+
+// priorityWriter is the subset of *syslog.Writer used by syslogHandler.
+type priorityWriter interface {
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+}
+
+// syslogHandler formats records with a text or JSON handler and sends each
+// one to syslog at the priority matching its level.
+type syslogHandler struct {
+	inner slog.Handler
+	buf   *bytes.Buffer
+	mu    *sync.Mutex
+	w     priorityWriter
+}
+
+// newSyslogWriterHandler builds a syslogHandler around w.
+func newSyslogWriterHandler(w priorityWriter, format string, opts *slog.HandlerOptions) (*syslogHandler, error) {
+	// syslog stamps every message itself, so drop slog's timestamp.
+	withoutTime := *opts
+	withoutTime.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+
+	buf := &bytes.Buffer{}
+	inner, err := newHandler(buf, format, &withoutTime)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogHandler{inner: inner, buf: buf, mu: &sync.Mutex{}, w: w}, nil
+}
+
+// Enabled implements slog.Handler.
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.inner.Handle(ctx, r); err != nil {
+		return err
+	}
+	msg := string(bytes.TrimRight(h.buf.Bytes(), "\n"))
+
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.w.Err(msg)
+	case r.Level >= slog.LevelWarn:
+		return h.w.Warning(msg)
+	case r.Level >= slog.LevelInfo:
+		return h.w.Info(msg)
+	default:
+		return h.w.Debug(msg)
+	}
+}
+
+// WithAttrs implements slog.Handler.
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{inner: h.inner.WithAttrs(attrs), buf: h.buf, mu: h.mu, w: h.w}
+}
+
+// WithGroup implements slog.Handler.
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{inner: h.inner.WithGroup(name), buf: h.buf, mu: h.mu, w: h.w}
+}
@@ -0,0 +1,54 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type fakeSyslog struct {
+	messages []string
+}
+
+func (f *fakeSyslog) record(priority, m string) error {
+	f.messages = append(f.messages, priority+" "+m)
+	return nil
+}
+
+func (f *fakeSyslog) Debug(m string) error   { return f.record("debug", m) }
+func (f *fakeSyslog) Info(m string) error    { return f.record("info", m) }
+func (f *fakeSyslog) Warning(m string) error { return f.record("warning", m) }
+func (f *fakeSyslog) Err(m string) error     { return f.record("err", m) }
+
+func TestSyslogHandler_Priorities(t *testing.T) {
+	w := &fakeSyslog{}
+	h, err := newSyslogWriterHandler(w, FormatText, &slog.HandlerOptions{Level: slog.LevelDebug})
+	if err != nil {
+		t.Fatalf("newSyslogWriterHandler() unexpected error: %v", err)
+	}
+
+	logger := slog.New(h).With("component", "test")
+	logger.Debug("d")
+	logger.Info("i")
+	logger.Warn("w")
+	logger.Error("e")
+
+	expected := []string{
+		"debug level=DEBUG msg=d component=test",
+		"info level=INFO msg=i component=test",
+		"warning level=WARN msg=w component=test",
+		"err level=ERROR msg=e component=test",
+	}
+	if strings.Join(w.messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("messages = %q, want %q", w.messages, expected)
+	}
+}
+
+func TestSyslogHandler_UnknownFormat(t *testing.T) {
+	_, err := newSyslogWriterHandler(&fakeSyslog{}, "xml", &slog.HandlerOptions{})
+	if err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
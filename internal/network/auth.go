@@ -0,0 +1,112 @@
+package network
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// AuthMaxSkew is how far a token's timestamp may differ from the server's
+// clock. Servers must remember nonces for at least twice this long.
+const AuthMaxSkew = 5 * time.Minute
+
+// authContext prefixes the authenticated data so that MACs cannot be
+// reused for anything but MOTD requests.
+const authContext = "motd auth v1\n"
+
+// nonceSize is the number of random bytes in a token nonce.
+const nonceSize = 16
+
+// Errors reported by authentication. ErrUnauthorized is returned by
+// AuthToken.Verify; ErrRejected is returned by the client when the server
+// refuses to send a message, for example because authentication failed.
+var (
+	ErrUnauthorized = errors.New("authentication failed")
+	ErrRejected     = errors.New("request rejected by server")
+)
+
+// AuthToken proves that the client holds a shared secret. It is sent in
+// the hello and is valid for a single request.
+type AuthToken struct {
+	KeyID     string `json:"key_id"`
+	Timestamp int64  `json:"timestamp"` // Unix seconds
+	Nonce     string `json:"nonce"`     // Base64, unique per request
+	MAC       string `json:"mac"`       // Base64 HMAC-SHA256
+}
+
+// NewAuthToken creates a token for keyID signed with secret.
+func NewAuthToken(keyID string, secret []byte, now time.Time) (*AuthToken, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	t := &AuthToken{
+		KeyID:     keyID,
+		Timestamp: now.Unix(),
+		Nonce:     base64.StdEncoding.EncodeToString(nonce),
+	}
+	t.MAC = base64.StdEncoding.EncodeToString(t.mac(secret))
+	return t, nil
+}
+
+// Verify checks the token's MAC against secret and that its timestamp is
+// within AuthMaxSkew of now. Replayed nonces must be rejected by the caller.
+func (t *AuthToken) Verify(secret []byte, now time.Time) error {
+	mac, err := base64.StdEncoding.DecodeString(t.MAC)
+	if err != nil || !hmac.Equal(mac, t.mac(secret)) {
+		return fmt.Errorf("%w: invalid MAC for key %q", ErrUnauthorized, t.KeyID)
+	}
+
+	skew := now.Sub(time.Unix(t.Timestamp, 0))
+	if skew > AuthMaxSkew || skew < -AuthMaxSkew {
+		return fmt.Errorf("%w: timestamp off by %s", ErrUnauthorized, skew.Round(time.Second))
+	}
+	return nil
+}
+
+// mac computes the HMAC of the token's fields.
+func (t *AuthToken) mac(secret []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(authContext))
+	h.Write([]byte(t.KeyID + "\n" + strconv.FormatInt(t.Timestamp, 10) + "\n" + t.Nonce))
+	return h.Sum(nil)
+}
+
+// LoadAuthKey reads a shared secret from path. Files that other users can
+// access are refused, as are empty ones. Surrounding whitespace is ignored.
+func LoadAuthKey(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth key: %w", err)
+	}
+	defer f.Close()
+
+	// The permissions are checked on the open file, so they are those of
+	// the file read.
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth key: %w", err)
+	}
+	if err := checkKeyPermissions(path, info); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth key: %w", err)
+	}
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("auth key %s is empty", path)
+	}
+	return secret, nil
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuthToken_Verify(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		now    time.Time
+		tamper func(*AuthToken)
+		want   error
+	}{
+		{name: "valid", secret: secret, now: now, want: nil},
+		{name: "within skew", secret: secret, now: now.Add(AuthMaxSkew - time.Second), want: nil},
+		{name: "wrong secret", secret: []byte("other"), now: now, want: ErrUnauthorized},
+		{name: "expired", secret: secret, now: now.Add(AuthMaxSkew + time.Second), want: ErrUnauthorized},
+		{name: "from the future", secret: secret, now: now.Add(-AuthMaxSkew - time.Second), want: ErrUnauthorized},
+		{name: "tampered key id", secret: secret, now: now, tamper: func(a *AuthToken) { a.KeyID = "admin" }, want: ErrUnauthorized},
+		{name: "tampered timestamp", secret: secret, now: now, tamper: func(a *AuthToken) { a.Timestamp++ }, want: ErrUnauthorized},
+		{name: "tampered nonce", secret: secret, now: now, tamper: func(a *AuthToken) { a.Nonce = "AAAA" }, want: ErrUnauthorized},
+		{name: "malformed mac", secret: secret, now: now, tamper: func(a *AuthToken) { a.MAC = "!" }, want: ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := NewAuthToken("ops", secret, now)
+			if err != nil {
+				t.Fatalf("NewAuthToken() unexpected error: %v", err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(token)
+			}
+
+			if err := token.Verify(tt.secret, tt.now); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuthToken_UniqueNonces(t *testing.T) {
+	now := time.Now()
+	a, _ := NewAuthToken("ops", []byte("s3cret"), now)
+	b, _ := NewAuthToken("ops", []byte("s3cret"), now)
+	if a.Nonce == b.Nonce || a.MAC == b.MAC {
+		t.Error("Expected tokens created at the same time to differ")
+	}
+}
+
+func TestLoadAuthKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "trims whitespace", path: write("key", "  s3cret\n"), want: "s3cret"},
+		{name: "empty", path: write("empty", "\n"), wantErr: true},
+		{name: "missing", path: filepath.Join(dir, "missing"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := LoadAuthKey(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadAuthKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(secret) != tt.want {
+				t.Errorf("LoadAuthKey() = %q, want %q", secret, tt.want)
+			}
+		})
+	}
+}
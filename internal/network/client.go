@@ -2,7 +2,11 @@
 package network
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log/slog"
@@ -13,23 +17,84 @@ import (
 // ClientInterface defines the interface for network communication
 type ClientInterface interface {
 	Connect() (net.Conn, error)
-	FetchMessage(conn net.Conn) (string, error)
+	FetchMessage(conn net.Conn, hello Hello) (*Message, error)
 }
 
 // Client handles communication with the MOTD server.
 type Client struct {
-	host    string
-	port    int
-	timeout time.Duration
+	host     string
+	port     int
+	timeout  time.Duration
+	protocol string
+	maxSize  int
+	keys     []ed25519.PublicKey
+	keyID    string
+	secret   []byte
+
+	dialer   Dialer
+	network  string
+	resolver Resolver
+	proxy    ProxyFunc
+
+	// Used by HTTPClient only.
+	tlsConfig *tls.Config
+	store     MessageStore
+}
+
+// Option configures optional Client behavior.
+type Option func(*Client)
+
+// WithProtocol selects the protocol mode: ProtocolAuto (the default),
+// ProtocolV1 or ProtocolLegacy.
+func WithProtocol(protocol string) Option {
+	return func(c *Client) {
+		c.protocol = protocol
+	}
+}
+
+// WithMaxSize limits messages to maxSize bytes. Larger messages fail with
+// ErrTooLarge. The default is MaxFrameSize.
+func WithMaxSize(maxSize int) Option {
+	return func(c *Client) {
+		c.maxSize = maxSize
+	}
+}
+
+// WithTrustedKeys requires every message to carry a signature made by one
+// of keys. Messages that fail verification are rejected with an error
+// matching ErrSignature.
+func WithTrustedKeys(keys []ed25519.PublicKey) Option {
+	return func(c *Client) {
+		c.keys = keys
+	}
+}
+
+// WithAuth authenticates every request to versioned servers with an HMAC
+// token made from the shared secret identified by keyID.
+func WithAuth(keyID string, secret []byte) Option {
+	return func(c *Client) {
+		c.keyID = keyID
+		c.secret = secret
+	}
 }
 
 // NewClient creates a new network client.
-func NewClient(host string, port int, timeout time.Duration) *Client {
-	return &Client{
-		host:    host,
-		port:    port,
-		timeout: timeout,
+func NewClient(host string, port int, timeout time.Duration, opts ...Option) *Client {
+	c := &Client{
+		host:     host,
+		port:     port,
+		timeout:  timeout,
+		protocol: ProtocolAuto,
+		maxSize:  MaxFrameSize,
+		dialer:   &net.Dialer{},
+		network:  NetworkAny,
+		resolver: net.DefaultResolver,
+		proxy:    ProxyFromEnvironment,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // Connect establishes a connection to the MOTD server.
@@ -38,7 +103,9 @@ func (c *Client) Connect() (net.Conn, error) {
 
 	slog.Debug("Connecting to server", "address", address, "timeout", c.timeout)
 
-	conn, err := net.DialTimeout("tcp", address, c.timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	conn, err := c.dial(ctx, c.host, c.port)
 	if err != nil {
 		return nil, fmt.Errorf("dial failed: %w", err)
 	}
@@ -47,22 +114,130 @@ func (c *Client) Connect() (net.Conn, error) {
 	return conn, nil
 }
 
-// FetchMessage reads the message from the server connection.
-func (c *Client) FetchMessage(conn net.Conn) (string, error) {
-	var buf bytes.Buffer
+// FetchMessage reads the message from the server connection. If the server
+// greets the client, hello is sent so the server can tailor the message;
+// otherwise the message is read until the server closes the connection.
+func (c *Client) FetchMessage(conn net.Conn, hello Hello) (*Message, error) {
+	// Set a deadline for the whole exchange
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
 
-	// Set a deadline for reading
-	if err := conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
-		return "", fmt.Errorf("failed to set read deadline: %w", err)
+	if c.protocol == ProtocolLegacy {
+		return c.readLegacy(conn)
 	}
 
-	_, err := io.Copy(&buf, conn)
+	r, greeted, err := c.detect(conn)
 	if err != nil {
-		return "", fmt.Errorf("failed to read from connection: %w", err)
+		return nil, err
+	}
+	if !greeted {
+		return c.readLegacy(r)
+	}
+
+	if err := c.handshake(r, conn, hello); err != nil {
+		return nil, err
 	}
+	return c.receive(r)
+}
 
-	message := buf.String()
-	slog.Debug("Message received", "length", len(message))
+// detect peeks at the start of the stream to tell versioned servers, which
+// greet the client, from legacy ones.
+func (c *Client) detect(conn net.Conn) (*bufio.Reader, bool, error) {
+	r := bufio.NewReader(conn)
+	head, err := r.Peek(len(Magic))
+	if err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("failed to read from connection: %w", err)
+	}
+	if !bytes.Equal(head, Magic) {
+		if c.protocol == ProtocolV1 {
+			return nil, false, fmt.Errorf("%w: server did not send a greeting", ErrProtocol)
+		}
+		slog.Debug("Server did not send a greeting, using legacy protocol")
+		return r, false, nil
+	}
+	return r, true, nil
+}
 
-	return message, nil
+// handshake reads the greeting and answers with hello.
+func (c *Client) handshake(r io.Reader, w io.Writer, hello Hello) error {
+	greeting, err := expectFrame(r, FrameGreeting, c.maxSize)
+	if err != nil {
+		return err
+	}
+	if greeting.Version < 1 {
+		return fmt.Errorf("%w: unsupported protocol version %d", ErrProtocol, greeting.Version)
+	}
+	version := min(greeting.Version, ProtocolVersion)
+
+	slog.Debug("Server greeted client", "server_version", greeting.Version, "version", version)
+
+	if hello.Encodings == nil {
+		hello.Encodings = Encodings()
+	}
+	if c.secret != nil {
+		token, err := NewAuthToken(c.keyID, c.secret, time.Now())
+		if err != nil {
+			return err
+		}
+		hello.Auth = token
+		slog.Debug("Authenticating request", "key_id", c.keyID)
+	}
+
+	return writeHello(w, version, hello)
+}
+
+// receive reads and verifies one message after the handshake.
+func (c *Client) receive(r io.Reader) (*Message, error) {
+	msg, f, err := readMessage(r, c.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verify(msg); err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Message received",
+		"length", len(msg.Body),
+		"content_type", msg.ContentType,
+		"id", msg.Metadata.ID,
+		"checksum", f.Checksum,
+		"protocol_version", f.Version)
+
+	return msg, nil
+}
+
+// readLegacy reads the message until the server closes the connection.
+func (c *Client) readLegacy(r io.Reader) (*Message, error) {
+	var buf bytes.Buffer
+
+	// Read one byte past the limit to detect oversized messages.
+	_, err := io.Copy(&buf, io.LimitReader(r, int64(c.maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from connection: %w", err)
+	}
+	if buf.Len() > c.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, c.maxSize)
+	}
+
+	msg := &Message{ContentType: Sniff(buf.Bytes()), Body: buf.Bytes()}
+
+	slog.Debug("Message received", "length", buf.Len(), "content_type", msg.ContentType)
+
+	if err := c.verify(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// verify checks the message signature when trusted keys are configured.
+func (c *Client) verify(msg *Message) error {
+	if len(c.keys) == 0 {
+		return nil
+	}
+	if err := msg.Verify(c.keys); err != nil {
+		return err
+	}
+	slog.Debug("Message signature verified")
+	return nil
 }
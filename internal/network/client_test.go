@@ -1,9 +1,14 @@
 package network
 
 import (
-	"net"
+	"crypto/ed25519"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/stevielcb/motd-client/motdtest"
 )
 
 func TestNewClient(t *testing.T) {
@@ -34,118 +39,427 @@ func TestClient_Connect(t *testing.T) {
 	}
 }
 
-func TestClient_FetchMessage(t *testing.T) {
-	// Create a test server
-	listener, err := net.Listen("tcp", "localhost:0")
-	if err != nil {
-		t.Fatalf("Failed to create test server: %v", err)
-	}
-	defer listener.Close()
-
-	// Get the port the server is listening on
-	addr := listener.Addr().(*net.TCPAddr)
-	port := addr.Port
-
-	// Start server goroutine
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
-			return
-		}
-		defer conn.Close()
+// fetch connects client to srv and fetches one message.
+func fetch(t *testing.T, srv *motdtest.Server, timeout time.Duration, opts ...Option) (*Message, error) {
+	t.Helper()
 
-		// Send test message
-		conn.Write([]byte("Test MOTD Message"))
-	}()
-
-	// Create client and connect
-	client := NewClient("localhost", port, 1*time.Second)
+	client := NewClient(srv.Host(), srv.Port(), timeout, opts...)
 	conn, err := client.Connect()
 	if err != nil {
 		t.Fatalf("Failed to connect to test server: %v", err)
 	}
 	defer conn.Close()
 
-	// Fetch message
-	message, err := client.FetchMessage(conn)
+	return client.FetchMessage(conn, Hello{})
+}
+
+func TestClient_FetchMessage(t *testing.T) {
+	srv := motdtest.NewServer(motdtest.PayloadString("Test MOTD Message"))
+	defer srv.Close()
+
+	message, err := fetch(t, srv, 1*time.Second)
 	if err != nil {
 		t.Fatalf("Failed to fetch message: %v", err)
 	}
 
 	expected := "Test MOTD Message"
-	if message != expected {
-		t.Errorf("Expected message %q, got %q", expected, message)
+	if string(message.Body) != expected {
+		t.Errorf("Expected message %q, got %q", expected, message.Body)
 	}
 }
 
 func TestClient_FetchMessage_Empty(t *testing.T) {
 	// Create a test server that sends empty message
-	listener, err := net.Listen("tcp", "localhost:0")
+	srv := motdtest.NewServer(motdtest.Empty())
+	defer srv.Close()
+
+	message, err := fetch(t, srv, 1*time.Second)
 	if err != nil {
-		t.Fatalf("Failed to create test server: %v", err)
+		t.Fatalf("Failed to fetch message: %v", err)
+	}
+
+	if len(message.Body) != 0 {
+		t.Errorf("Expected empty message, got %q", message.Body)
 	}
-	defer listener.Close()
+}
 
-	addr := listener.Addr().(*net.TCPAddr)
-	port := addr.Port
+func TestClient_FetchMessage_Timeout(t *testing.T) {
+	// Create a test server that doesn't send anything
+	srv := motdtest.NewServer(motdtest.Hang())
+	defer srv.Close()
+
+	_, err := fetch(t, srv, 100*time.Millisecond)
+	if err == nil {
+		t.Error("Expected timeout error, got nil")
+	}
+}
 
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
-			return
-		}
-		defer conn.Close()
-		// Send empty message
-		conn.Write([]byte(""))
-	}()
+func TestClient_FetchMessage_FailurePaths(t *testing.T) {
+	payload := []byte("Test MOTD Message")
 
-	client := NewClient("localhost", port, 1*time.Second)
+	tests := []struct {
+		name     string
+		behavior motdtest.Behavior
+		wantErr  bool
+	}{
+		{
+			name:     "slow drip within timeout",
+			behavior: motdtest.SlowDrip(payload, 4, 5*time.Millisecond),
+			wantErr:  false,
+		},
+		{
+			name:     "slow drip past timeout",
+			behavior: motdtest.SlowDrip(payload, 1, 20*time.Millisecond),
+			wantErr:  true,
+		},
+		{
+			name:     "partial write then hang",
+			behavior: motdtest.PartialThenHang(payload, 4),
+			wantErr:  true,
+		},
+		{
+			name:     "reset mid-stream",
+			behavior: motdtest.ResetAfter(payload, 4),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			client := NewClient(srv.Host(), srv.Port(), 200*time.Millisecond)
+			conn, err := client.Connect()
+			if err != nil {
+				// A reset can race the handshake and surface from Connect.
+				if tt.wantErr {
+					return
+				}
+				t.Fatalf("Failed to connect to test server: %v", err)
+			}
+			defer conn.Close()
+
+			message, err := client.FetchMessage(conn, Hello{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(message.Body) != string(payload) {
+				t.Errorf("Expected message %q, got %q", payload, message.Body)
+			}
+		})
+	}
+}
+
+func TestClient_FetchMessage_Versioned(t *testing.T) {
+	var got motdtest.Hello
+	srv := motdtest.NewServer(motdtest.VersionedFunc(func(hello motdtest.Hello) motdtest.Response {
+		got = hello
+		return motdtest.Response{ContentType: ContentTypeText, Body: []byte("Versioned MOTD"), Checksum: true}
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.Host(), srv.Port(), time.Second)
 	conn, err := client.Connect()
 	if err != nil {
 		t.Fatalf("Failed to connect to test server: %v", err)
 	}
 	defer conn.Close()
 
-	message, err := client.FetchMessage(conn)
+	hello := Hello{
+		ClientVersion: "test",
+		Capabilities:  Capabilities{Graphics: []string{"kitty"}, Columns: 100, Rows: 30, ColorDepth: 8},
+	}
+	message, err := client.FetchMessage(conn, hello)
 	if err != nil {
-		t.Fatalf("Failed to fetch message: %v", err)
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+
+	if string(message.Body) != "Versioned MOTD" || message.ContentType != ContentTypeText {
+		t.Errorf("Expected text message %q, got %+v", "Versioned MOTD", message)
+	}
+	if got.ClientVersion != "test" || !slices.Equal(got.Capabilities.Graphics, []string{"kitty"}) ||
+		got.Capabilities.Columns != 100 || got.Capabilities.Rows != 30 || got.Capabilities.ColorDepth != 8 {
+		t.Errorf("Server received hello %+v, want %+v", got, hello)
 	}
+}
 
-	if message != "" {
-		t.Errorf("Expected empty message, got %q", message)
+func TestClient_FetchMessage_ProtocolModes(t *testing.T) {
+	tests := []struct {
+		name         string
+		behavior     motdtest.Behavior
+		protocol     string
+		want         string
+		wantProtocol bool
+	}{
+		{name: "auto with legacy server", behavior: motdtest.PayloadString("Legacy"), protocol: ProtocolAuto, want: "Legacy"},
+		{name: "auto with short legacy payload", behavior: motdtest.PayloadString("Hi"), protocol: ProtocolAuto, want: "Hi"},
+		{name: "auto with versioned server", behavior: motdtest.Versioned([]byte("V1")), protocol: ProtocolAuto, want: "V1"},
+		{name: "v1 with versioned server", behavior: motdtest.Versioned([]byte("V1")), protocol: ProtocolV1, want: "V1"},
+		{name: "v1 with legacy server", behavior: motdtest.PayloadString("Legacy"), protocol: ProtocolV1, wantProtocol: true},
+		{name: "legacy with legacy server", behavior: motdtest.PayloadString("Legacy"), protocol: ProtocolLegacy, want: "Legacy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			message, err := fetch(t, srv, time.Second, WithProtocol(tt.protocol))
+
+			if tt.wantProtocol {
+				if !errors.Is(err, ErrProtocol) {
+					t.Errorf("FetchMessage() error = %v, want ErrProtocol", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchMessage() unexpected error: %v", err)
+			}
+			if string(message.Body) != tt.want {
+				t.Errorf("Expected message %q, got %q", tt.want, message.Body)
+			}
+		})
 	}
 }
 
-func TestClient_FetchMessage_Timeout(t *testing.T) {
-	// Create a test server that doesn't send anything
-	listener, err := net.Listen("tcp", "localhost:0")
+func TestClient_FetchMessage_FrameErrors(t *testing.T) {
+	frame := motdtest.EncodeFrame(motdtest.FrameMessage, motdtest.Response{
+		ContentType: ContentTypeText,
+		Body:        []byte("Complete MOTD"),
+		Checksum:    true,
+	})
+	corrupt := append([]byte{}, frame...)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	tests := []struct {
+		name     string
+		behavior motdtest.Behavior
+		want     error
+	}{
+		{
+			name:     "truncated payload",
+			behavior: motdtest.VersionedRaw(func(motdtest.Hello) []byte { return frame[:len(frame)-8] }),
+			want:     ErrTruncated,
+		},
+		{
+			name:     "closed before message",
+			behavior: motdtest.VersionedRaw(func(motdtest.Hello) []byte { return nil }),
+			want:     ErrTruncated,
+		},
+		{
+			name:     "corrupted checksum",
+			behavior: motdtest.VersionedRaw(func(motdtest.Hello) []byte { return corrupt }),
+			want:     ErrChecksum,
+		},
+		{
+			name:     "oversized frame",
+			behavior: motdtest.Versioned(make([]byte, 2048)),
+			want:     ErrTooLarge,
+		},
+		{
+			name:     "oversized legacy payload",
+			behavior: motdtest.Oversized(2048),
+			want:     ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			_, err := fetch(t, srv, time.Second, WithMaxSize(1024))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FetchMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_FetchMessage_SniffsLegacyContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{name: "plain text", payload: "Legacy", want: ContentTypeText},
+		{name: "osc image", payload: "1337;File=inline=1:AAAA", want: ContentTypeOSC},
+		{name: "ansi text", payload: "\033[31mRed\033[0m", want: ContentTypeANSI},
+		{name: "png", payload: "\x89PNG\r\n\x1a\nIHDR", want: ContentTypePNG},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(motdtest.PayloadString(tt.payload))
+			defer srv.Close()
+
+			message, err := fetch(t, srv, time.Second)
+			if err != nil {
+				t.Fatalf("FetchMessage() unexpected error: %v", err)
+			}
+			if message.ContentType != tt.want {
+				t.Errorf("ContentType = %q, want %q", message.ContentType, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_FetchMessage_Metadata(t *testing.T) {
+	meta := motdtest.EncodeFrame(motdtest.FrameMetadata, motdtest.Response{
+		ContentType: ContentTypeJSON,
+		Body:        []byte(`{"id":"42","title":"Maintenance","author":"ops","expires":"2030-01-02T03:04:05Z","url":"https://example.com"}`),
+	})
+	body := motdtest.EncodeFrame(motdtest.FrameMessage, motdtest.Response{
+		ContentType: ContentTypeMarkdown,
+		Body:        []byte("# Maintenance tonight"),
+	})
+	srv := motdtest.NewServer(motdtest.VersionedRaw(func(motdtest.Hello) []byte {
+		return append(meta, body...)
+	}))
+	defer srv.Close()
+
+	message, err := fetch(t, srv, time.Second)
 	if err != nil {
-		t.Fatalf("Failed to create test server: %v", err)
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
 	}
-	defer listener.Close()
 
-	addr := listener.Addr().(*net.TCPAddr)
-	port := addr.Port
+	want := Metadata{
+		ID:      "42",
+		Title:   "Maintenance",
+		Author:  "ops",
+		Expires: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		URL:     "https://example.com",
+	}
+	if message.Metadata != want {
+		t.Errorf("Metadata = %+v, want %+v", message.Metadata, want)
+	}
+	if message.ContentType != ContentTypeMarkdown {
+		t.Errorf("ContentType = %q, want %q", message.ContentType, ContentTypeMarkdown)
+	}
+}
 
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
-			return
-		}
-		defer conn.Close()
-		// Don't send anything, just keep connection open
-		time.Sleep(2 * time.Second)
-	}()
+func TestClient_FetchMessage_TrustedKeys(t *testing.T) {
+	pub, priv := newKey(t)
+	other, _ := newKey(t)
+	signed := signedMessage(t, priv, &Message{ContentType: ContentTypeText, Body: []byte("Signed MOTD")})
 
-	client := NewClient("localhost", port, 100*time.Millisecond)
-	conn, err := client.Connect()
+	tests := []struct {
+		name     string
+		behavior motdtest.Behavior
+		keys     []ed25519.PublicKey
+		want     error
+	}{
+		{
+			name:     "signed by trusted key",
+			behavior: motdtest.VersionedRaw(func(motdtest.Hello) []byte { return signed }),
+			keys:     []ed25519.PublicKey{pub},
+		},
+		{
+			name:     "signed by untrusted key",
+			behavior: motdtest.VersionedRaw(func(motdtest.Hello) []byte { return signed }),
+			keys:     []ed25519.PublicKey{other},
+			want:     ErrBadSignature,
+		},
+		{
+			name:     "unsigned",
+			behavior: motdtest.Versioned([]byte("Unsigned MOTD")),
+			keys:     []ed25519.PublicKey{pub},
+			want:     ErrUnsigned,
+		},
+		{
+			name:     "legacy server",
+			behavior: motdtest.PayloadString("Legacy MOTD"),
+			keys:     []ed25519.PublicKey{pub},
+			want:     ErrUnsigned,
+		},
+		{
+			name:     "verification disabled",
+			behavior: motdtest.Versioned([]byte("Unsigned MOTD")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			_, err := fetch(t, srv, time.Second, WithTrustedKeys(tt.keys))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FetchMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_FetchMessage_Auth(t *testing.T) {
+	secret := []byte("s3cret")
+
+	var got motdtest.Hello
+	srv := motdtest.NewServer(motdtest.VersionedFunc(func(hello motdtest.Hello) motdtest.Response {
+		got = hello
+		return motdtest.Response{ContentType: ContentTypeText, Body: []byte("Internal MOTD")}
+	}))
+	defer srv.Close()
+
+	if _, err := fetch(t, srv, time.Second, WithAuth("ops", secret)); err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+
+	if got.Auth == nil {
+		t.Fatal("Expected the hello to carry an auth token")
+	}
+	token := AuthToken{KeyID: got.Auth.KeyID, Timestamp: got.Auth.Timestamp, Nonce: got.Auth.Nonce, MAC: got.Auth.MAC}
+	if token.KeyID != "ops" {
+		t.Errorf("Token key ID = %q, want %q", token.KeyID, "ops")
+	}
+	if err := token.Verify(secret, time.Now()); err != nil {
+		t.Errorf("Token failed verification: %v", err)
+	}
+}
+
+func TestClient_FetchMessage_Rejected(t *testing.T) {
+	srv := motdtest.NewServer(motdtest.VersionedRaw(func(motdtest.Hello) []byte {
+		return motdtest.EncodeFrame(motdtest.FrameError, motdtest.Response{
+			ContentType: ContentTypeText,
+			Body:        []byte("authentication required"),
+		})
+	}))
+	defer srv.Close()
+
+	_, err := fetch(t, srv, time.Second)
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("FetchMessage() error = %v, want ErrRejected", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "authentication required") {
+		t.Errorf("FetchMessage() error = %v, want the server's reason", err)
+	}
+}
+
+func TestClient_FetchMessage_Compressed(t *testing.T) {
+	text := []byte(strings.Repeat("Compressed MOTD ", 200))
+
+	var got motdtest.Hello
+	srv := motdtest.NewServer(motdtest.VersionedFunc(func(hello motdtest.Hello) motdtest.Response {
+		got = hello
+		return motdtest.Response{ContentType: ContentTypeText, Encoding: EncodingGzip, Body: motdtest.Gzip(text), Checksum: true}
+	}))
+	defer srv.Close()
+
+	message, err := fetch(t, srv, time.Second)
 	if err != nil {
-		t.Fatalf("Failed to connect to test server: %v", err)
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(message.Body) != string(text) {
+		t.Errorf("FetchMessage() returned %d bytes, want %d", len(message.Body), len(text))
+	}
+	if !slices.Equal(got.Encodings, Encodings()) {
+		t.Errorf("Advertised encodings = %v, want %v", got.Encodings, Encodings())
 	}
-	defer conn.Close()
 
-	_, err = client.FetchMessage(conn)
-	if err == nil {
-		t.Error("Expected timeout error, got nil")
+	// A small compressed payload must not expand past the size limit.
+	_, err = fetch(t, srv, time.Second, WithMaxSize(1024))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("FetchMessage() error = %v, want ErrTooLarge", err)
 	}
 }
@@ -0,0 +1,206 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// Address families for WithNetwork.
+const (
+	NetworkAny  = "tcp"  // IPv6 and IPv4, raced
+	NetworkIPv4 = "tcp4" // IPv4 only
+	NetworkIPv6 = "tcp6" // IPv6 only
+)
+
+// Networks lists the accepted values for WithNetwork.
+var Networks = []string{NetworkAny, NetworkIPv4, NetworkIPv6}
+
+// maxAttemptDelay is how long a connection attempt runs before the next
+// address is tried in parallel (RFC 8305 recommends 250ms). Short timeouts
+// shorten it so that every family gets a chance.
+const maxAttemptDelay = 250 * time.Millisecond
+
+// Resolver looks up the addresses of a host. *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// WithNetwork restricts connections to an address family: NetworkAny (the
+// default), NetworkIPv4 or NetworkIPv6.
+func WithNetwork(network string) Option {
+	return func(c *Client) {
+		c.network = network
+	}
+}
+
+// WithResolver replaces the resolver used to look up host names.
+func WithResolver(r Resolver) Option {
+	return func(c *Client) {
+		c.resolver = r
+	}
+}
+
+// WithDNSServer resolves host names by querying the DNS server at address
+// ("host:port", see ParseDNSServer) instead of the system's.
+func WithDNSServer(address string) Option {
+	return func(c *Client) {
+		c.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, address)
+			},
+		}
+	}
+}
+
+// ParseDNSServer checks a DNS server address, adding port 53 if it has
+// none.
+func ParseDNSServer(address string) (string, error) {
+	if addr, err := netip.ParseAddr(address); err == nil {
+		return netip.AddrPortFrom(addr, 53).String(), nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "53"
+	}
+	if host == "" {
+		return "", fmt.Errorf("DNS server %q has no host", address)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return "", fmt.Errorf("DNS server %q has an invalid port", address)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// dialTCP connects to address ("host:port"). Host names are resolved and,
+// following Happy Eyeballs (RFC 8305), their addresses are tried
+// alternating between IPv6 and IPv4, starting a new attempt whenever the
+// previous one fails or has not connected after the attempt delay. The
+// first connection wins.
+func (c *Client) dialTCP(ctx context.Context, address string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", portStr)
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 1 {
+		return c.dialer.DialContext(ctx, c.network, netip.AddrPortFrom(addrs[0], uint16(port)).String())
+	}
+
+	slog.Debug("Racing connection attempts", "host", host, "addresses", addrs)
+
+	type result struct {
+		conn net.Conn
+		addr netip.Addr
+		err  error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result)
+	delay := min(maxAttemptDelay, c.timeout/4)
+
+	start := func(addr netip.Addr) {
+		go func() {
+			conn, err := c.dialer.DialContext(ctx, c.network, netip.AddrPortFrom(addr, uint16(port)).String())
+			results <- result{conn: conn, addr: addr, err: err}
+		}()
+	}
+
+	var errs []error
+	next, running := 0, 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if next < len(addrs) {
+				start(addrs[next])
+				next++
+				running++
+				timer.Reset(delay)
+			}
+		case r := <-results:
+			running--
+			if r.err == nil {
+				slog.Debug("Connection attempt won", "address", r.addr, "attempts", next)
+				cancel()
+				// Close connections from attempts that finish later.
+				go func() {
+					for range running {
+						if late := <-results; late.err == nil {
+							late.conn.Close()
+						}
+					}
+				}()
+				return r.conn, nil
+			}
+			slog.Debug("Connection attempt failed", "address", r.addr, "error", r.err)
+			errs = append(errs, r.err)
+			if next < len(addrs) {
+				// Start the next attempt right away.
+				timer.Reset(0)
+			} else if running == 0 {
+				return nil, errors.Join(errs...)
+			}
+		}
+	}
+}
+
+// lookup returns the addresses of host in the configured family, ordered
+// for Happy Eyeballs: IPv6 and IPv4 interleaved, IPv6 first.
+func (c *Client) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+
+	family := "ip"
+	switch c.network {
+	case NetworkIPv4:
+		family = "ip4"
+	case NetworkIPv6:
+		family = "ip6"
+	}
+	found, err := c.resolver.LookupNetIP(ctx, family, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("failed to resolve %s: no addresses", host)
+	}
+
+	var v6, v4 []netip.Addr
+	for _, addr := range found {
+		if addr = addr.Unmap(); addr.Is4() {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+	addrs := make([]netip.Addr, 0, len(found))
+	for i := range max(len(v6), len(v4)) {
+		if i < len(v6) {
+			addrs = append(addrs, v6[i])
+		}
+		if i < len(v4) {
+			addrs = append(addrs, v4[i])
+		}
+	}
+
+	slog.Debug("Resolved host", "host", host, "network", c.network, "addresses", addrs)
+	return addrs, nil
+}
@@ -0,0 +1,251 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver returns fixed addresses and records the families asked for.
+type fakeResolver struct {
+	addrs    []netip.Addr
+	err      error
+	families []string
+}
+
+func (r *fakeResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	r.families = append(r.families, network)
+	var addrs []netip.Addr
+	for _, addr := range r.addrs {
+		if network == "ip" || (network == "ip4") == addr.Is4() {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, r.err
+}
+
+// scriptedDialer fails, hangs or connects depending on the address.
+type scriptedDialer struct {
+	fail map[string]bool // Fail immediately
+	hang map[string]bool // Block until the attempt is canceled
+
+	mu       sync.Mutex
+	attempts []string
+}
+
+func (d *scriptedDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.attempts = append(d.attempts, address)
+	d.mu.Unlock()
+
+	switch {
+	case d.fail[address]:
+		return nil, errors.New("connection refused")
+	case d.hang[address]:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return &addrConn{Conn: client, remote: address}, nil
+}
+
+// addrConn reports the address it was dialed with.
+type addrConn struct {
+	net.Conn
+	remote string
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP(netip.MustParseAddrPort(c.remote).Addr().String())}
+}
+
+func TestClient_Connect_HappyEyeballs(t *testing.T) {
+	resolver := &fakeResolver{addrs: []netip.Addr{
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("2001:db8::2"),
+		netip.MustParseAddr("192.0.2.1"),
+	}}
+
+	tests := []struct {
+		name   string
+		fail   []string
+		hang   []string
+		want   string
+		wantOK bool
+	}{
+		{name: "first address", want: "[2001:db8::1]:4200", wantOK: true},
+		{name: "broken IPv6 hangs", hang: []string{"[2001:db8::1]:4200", "[2001:db8::2]:4200"}, want: "192.0.2.1:4200", wantOK: true},
+		{name: "broken IPv6 fails", fail: []string{"[2001:db8::1]:4200"}, want: "192.0.2.1:4200", wantOK: true},
+		{name: "all fail", fail: []string{"[2001:db8::1]:4200", "[2001:db8::2]:4200", "192.0.2.1:4200"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := &scriptedDialer{fail: map[string]bool{}, hang: map[string]bool{}}
+			for _, a := range tt.fail {
+				dialer.fail[a] = true
+			}
+			for _, a := range tt.hang {
+				dialer.hang[a] = true
+			}
+
+			client := NewClient("motd.example.com", 4200, 400*time.Millisecond,
+				WithDialer(dialer), WithResolver(resolver), WithProxy(nil))
+			start := time.Now()
+			conn, err := client.Connect()
+			if !tt.wantOK {
+				if err == nil {
+					conn.Close()
+					t.Fatal("Connect() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Connect() unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			if got := conn.(*addrConn).remote; got != tt.want {
+				t.Errorf("Connected to %s, want %s", got, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed >= 400*time.Millisecond {
+				t.Errorf("Connect() took %v, want less than the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestClient_Connect_Network(t *testing.T) {
+	tests := []struct {
+		network    string
+		wantFamily string
+		want       string
+	}{
+		{network: NetworkAny, wantFamily: "ip", want: "[2001:db8::1]:4200"},
+		{network: NetworkIPv4, wantFamily: "ip4", want: "192.0.2.1:4200"},
+		{network: NetworkIPv6, wantFamily: "ip6", want: "[2001:db8::1]:4200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			resolver := &fakeResolver{addrs: []netip.Addr{
+				netip.MustParseAddr("192.0.2.1"),
+				netip.MustParseAddr("2001:db8::1"),
+			}}
+			dialer := &scriptedDialer{}
+			client := NewClient("motd.example.com", 4200, time.Second,
+				WithDialer(dialer), WithResolver(resolver), WithNetwork(tt.network), WithProxy(nil))
+
+			conn, err := client.Connect()
+			if err != nil {
+				t.Fatalf("Connect() unexpected error: %v", err)
+			}
+			conn.Close()
+
+			if !slices.Equal(resolver.families, []string{tt.wantFamily}) {
+				t.Errorf("Resolved families %v, want %s", resolver.families, tt.wantFamily)
+			}
+			if got := conn.(*addrConn).remote; got != tt.want {
+				t.Errorf("Connected to %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Connect_ResolveError(t *testing.T) {
+	resolver := &fakeResolver{err: errors.New("no such host")}
+	client := NewClient("motd.example.com", 4200, time.Second,
+		WithDialer(&scriptedDialer{}), WithResolver(resolver), WithProxy(nil))
+
+	if _, err := client.Connect(); err == nil {
+		t.Error("Connect() expected error, got nil")
+	}
+}
+
+func TestClient_lookup_Order(t *testing.T) {
+	resolver := &fakeResolver{addrs: []netip.Addr{
+		netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("192.0.2.2"),
+		netip.MustParseAddr("192.0.2.3"),
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("::ffff:192.0.2.4"),
+	}}
+	client := NewClient("motd.example.com", 4200, time.Second, WithResolver(resolver))
+
+	got, err := client.lookup(context.Background(), "motd.example.com")
+	if err != nil {
+		t.Fatalf("lookup() unexpected error: %v", err)
+	}
+	want := []netip.Addr{
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("192.0.2.2"),
+		netip.MustParseAddr("192.0.2.3"),
+		netip.MustParseAddr("192.0.2.4"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("lookup() = %v, want %v", got, want)
+	}
+}
+
+func TestWithDNSServer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer pc.Close()
+
+	queried := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 512)
+		if _, _, err := pc.ReadFrom(buf); err == nil {
+			queried <- struct{}{}
+		}
+	}()
+
+	client := NewClient("motd.example.com", 4200, 200*time.Millisecond,
+		WithDNSServer(pc.LocalAddr().String()), WithProxy(nil))
+	if _, err := client.Connect(); err == nil {
+		t.Error("Connect() expected error without DNS answers, got nil")
+	}
+
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Error("DNS server was not queried")
+	}
+}
+
+func TestParseDNSServer(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		wantErr bool
+	}{
+		{address: "1.1.1.1", want: "1.1.1.1:53"},
+		{address: "1.1.1.1:5353", want: "1.1.1.1:5353"},
+		{address: "2606:4700::1111", want: "[2606:4700::1111]:53"},
+		{address: "[2606:4700::1111]:53", want: "[2606:4700::1111]:53"},
+		{address: "dns.example.com", want: "dns.example.com:53"},
+		{address: ":53", wantErr: true},
+		{address: "1.1.1.1:dns", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := ParseDNSServer(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDNSServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDNSServer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package network
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Payload encodings supported out of the box. EncodingDeflate is the zlib
+// format, as in HTTP.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// Decoder returns a reader that decompresses r.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// ErrUnsupportedEncoding is returned for payloads in an encoding without a
+// registered Decoder. It also matches ErrProtocol.
+var ErrUnsupportedEncoding = fmt.Errorf("%w: unsupported encoding", ErrProtocol)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		EncodingGzip: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		EncodingDeflate: zlib.NewReader,
+	}
+)
+
+// RegisterDecoder makes an encoding available to DecodePayload and
+// advertises it to servers. Registering an existing name replaces its
+// decoder.
+func RegisterDecoder(name string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[name] = d
+}
+
+// Encodings returns the names of all registered encodings, sorted.
+func Encodings() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	return slices.Sorted(maps.Keys(decoders))
+}
+
+// DecodePayload returns the frame's payload decompressed, or unchanged if
+// it is not encoded. Output longer than maxSize bytes fails with
+// ErrTooLarge, so compressed payloads cannot exhaust memory.
+func DecodePayload(f Frame, maxSize int) ([]byte, error) {
+	if f.Encoding == "" {
+		return f.Payload, nil
+	}
+
+	decodersMu.RLock()
+	decode, ok := decoders[f.Encoding]
+	decodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, f.Encoding)
+	}
+
+	r, err := decode(bytes.NewReader(f.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s payload: %v", ErrProtocol, f.Encoding, err)
+	}
+	defer r.Close()
+
+	// Read one byte past the limit to detect oversized payloads.
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(r, int64(maxSize)+1)); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %s payload ended early", ErrTruncated, f.Encoding)
+		}
+		return nil, fmt.Errorf("%w: invalid %s payload: %v", ErrProtocol, f.Encoding, err)
+	}
+	if buf.Len() > maxSize {
+		return nil, fmt.Errorf("%w: decoded payload exceeds limit of %d", ErrTooLarge, maxSize)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodePayload compresses data with one of the built-in encodings, or
+// returns it unchanged if encoding is empty.
+func encodePayload(encoding string, data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingDeflate:
+		w = zlib.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
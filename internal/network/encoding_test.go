@@ -0,0 +1,102 @@
+package network
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodePayload(t *testing.T) {
+	text := []byte(strings.Repeat("Message of the day. ", 100))
+	gzipped, _ := encodePayload(EncodingGzip, text)
+	deflated, _ := encodePayload(EncodingDeflate, text)
+
+	tests := []struct {
+		name    string
+		frame   Frame
+		maxSize int
+		want    []byte
+		wantErr error
+	}{
+		{name: "not encoded", frame: Frame{Payload: text}, maxSize: MaxFrameSize, want: text},
+		{name: "gzip", frame: Frame{Encoding: EncodingGzip, Payload: gzipped}, maxSize: MaxFrameSize, want: text},
+		{name: "deflate", frame: Frame{Encoding: EncodingDeflate, Payload: deflated}, maxSize: MaxFrameSize, want: text},
+		{name: "bomb", frame: Frame{Encoding: EncodingGzip, Payload: gzipped}, maxSize: len(text) - 1, wantErr: ErrTooLarge},
+		{name: "exactly max size", frame: Frame{Encoding: EncodingGzip, Payload: gzipped}, maxSize: len(text), want: text},
+		{name: "unknown encoding", frame: Frame{Encoding: "br", Payload: text}, maxSize: MaxFrameSize, wantErr: ErrUnsupportedEncoding},
+		{name: "corrupt", frame: Frame{Encoding: EncodingGzip, Payload: []byte("not gzip")}, maxSize: MaxFrameSize, wantErr: ErrProtocol},
+		{name: "truncated", frame: Frame{Encoding: EncodingGzip, Payload: gzipped[:len(gzipped)/2]}, maxSize: MaxFrameSize, wantErr: ErrTruncated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePayload(tt.frame, tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DecodePayload() error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DecodePayload() returned %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	RegisterDecoder("identity", func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	})
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		delete(decoders, "identity")
+		decodersMu.Unlock()
+	})
+
+	if !slices.Contains(Encodings(), "identity") {
+		t.Errorf("Encodings() = %v, want it to include the registered decoder", Encodings())
+	}
+
+	got, err := DecodePayload(Frame{Encoding: "identity", Payload: []byte("plain")}, MaxFrameSize)
+	if err != nil || string(got) != "plain" {
+		t.Errorf("DecodePayload() = %q, %v, want %q", got, err, "plain")
+	}
+}
+
+func TestEncodings_Default(t *testing.T) {
+	if got := Encodings(); !slices.Equal(got, []string{EncodingDeflate, EncodingGzip}) {
+		t.Errorf("Encodings() = %v, want [deflate gzip]", got)
+	}
+}
+
+func TestEncodePayload_Unsupported(t *testing.T) {
+	if _, err := encodePayload("br", []byte("data")); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("encodePayload() error = %v, want ErrUnsupportedEncoding", err)
+	}
+}
+
+func TestFrame_EncodingRoundTrip(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte("Hello"))
+	w.Close()
+
+	var buf bytes.Buffer
+	in := Frame{Version: ProtocolVersion, Type: FrameMessage, ContentType: ContentTypeText, Encoding: EncodingGzip, Payload: compressed.Bytes()}
+	if err := WriteFrame(&buf, in); err != nil {
+		t.Fatalf("WriteFrame() unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("MOTD\x01\x03\x02\x0atext/plain\x04gzip")) {
+		t.Errorf("Encoded frame = %q, want encoding after content type", buf.Bytes())
+	}
+
+	out, err := ReadFrame(&buf, MaxFrameSize)
+	if err != nil {
+		t.Fatalf("ReadFrame() unexpected error: %v", err)
+	}
+	if out.Encoding != EncodingGzip {
+		t.Errorf("Encoding = %q, want %q", out.Encoding, EncodingGzip)
+	}
+}
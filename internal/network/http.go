@@ -0,0 +1,305 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP headers carrying the hello and message metadata. Metadata headers
+// are ignored when signatures are verified, as the signature does not
+// cover them.
+const (
+	HeaderClientVersion = "X-Motd-Client-Version"
+	HeaderCapabilities  = "X-Motd-Capabilities" // JSON encoded Capabilities
+	HeaderAuth          = "X-Motd-Auth"         // JSON encoded AuthToken
+	HeaderID            = "X-Motd-Id"
+	HeaderTitle         = "X-Motd-Title"
+	HeaderAuthor        = "X-Motd-Author"
+	HeaderURL           = "X-Motd-Url"
+	HeaderCaption       = "X-Motd-Caption"
+	HeaderSignature     = "X-Motd-Signature" // Base64 Ed25519 signature
+)
+
+// MessageStore persists the last message so that an unchanged response
+// can be served from it. cache.Cache implements it.
+type MessageStore interface {
+	Load() (*Message, error)
+	Store(msg *Message) error
+}
+
+// HTTPClient fetches messages with an HTTP GET instead of the TCP
+// protocol. It satisfies ClientInterface: Connect opens the connection,
+// including the TLS handshake for https URLs, and FetchMessage sends the
+// request over it. It does not implement Subscriber, so watch mode polls
+// it.
+type HTTPClient struct {
+	client Client // Dials the server and holds the options; not embedded, see Subscriber
+	url    *url.URL
+}
+
+// WithTLSConfig sets the TLS configuration used for https URLs, for
+// example to trust a private certificate authority.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithMessageStore lets the HTTP client revalidate the stored message with
+// If-None-Match and reuse it when the server answers 304 Not Modified.
+func WithMessageStore(store MessageStore) Option {
+	return func(c *Client) {
+		c.store = store
+	}
+}
+
+// NewHTTPClient creates a client for an http or https URL.
+func NewHTTPClient(rawURL string, timeout time.Duration, opts ...Option) (*HTTPClient, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	port, _ := strconv.Atoi(u.Port())
+	c := &HTTPClient{client: *NewClient(u.Hostname(), port, timeout, opts...), url: u}
+	return c, nil
+}
+
+// ParseURL checks that rawURL is an absolute http or https URL and fills
+// in the scheme's default port.
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("URL scheme must be http or https, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("URL %q has no host", rawURL)
+	}
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
+
+// Connect opens a connection to the URL's host, completing the TLS
+// handshake for https URLs.
+func (c *HTTPClient) Connect() (net.Conn, error) {
+	conn, err := c.client.Connect()
+	if err != nil || c.url.Scheme != "https" {
+		return conn, err
+	}
+
+	cfg := &tls.Config{}
+	if c.client.tlsConfig != nil {
+		cfg = c.client.tlsConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = c.url.Hostname()
+	}
+	cfg.NextProtos = []string{"http/1.1"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.client.timeout)
+	defer cancel()
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// FetchMessage sends a GET request over conn and converts the response
+// into a Message.
+func (c *HTTPClient) FetchMessage(conn net.Conn, hello Hello) (*Message, error) {
+	if err := conn.SetDeadline(time.Now().Add(c.client.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	req, err := c.newRequest(hello)
+	if err != nil {
+		return nil, err
+	}
+	cached := c.cached()
+	if cached != nil {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	slog.Debug("Sending HTTP request", "url", c.url.Redacted(), "etag", req.Header.Get("If-None-Match"))
+
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: incomplete HTTP response", ErrTruncated)
+		}
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	slog.Debug("HTTP response received", "status", resp.Status, "content_type", resp.Header.Get("Content-Type"))
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		slog.Debug("Message not modified, using stored message", "etag", cached.ETag)
+		return cached, nil
+	case resp.StatusCode == http.StatusNoContent:
+		return &Message{ContentType: ContentTypeText}, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: HTTP %s", ErrRejected, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: unexpected HTTP status %s", ErrProtocol, resp.Status)
+	}
+
+	msg, err := c.readResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.client.verify(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// newRequest builds the GET request describing the client in its headers.
+func (c *HTTPClient) newRequest(hello Hello) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	caps, err := json.Marshal(hello.Capabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode capabilities: %w", err)
+	}
+	req.Header.Set("User-Agent", "motd-client/"+hello.ClientVersion)
+	req.Header.Set("Accept", strings.Join(ContentTypes, ", "))
+	req.Header.Set("Accept-Encoding", strings.Join(Encodings(), ", "))
+	req.Header.Set(HeaderClientVersion, hello.ClientVersion)
+	req.Header.Set(HeaderCapabilities, string(caps))
+
+	if c.client.secret != nil {
+		token, err := NewAuthToken(c.client.keyID, c.client.secret, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		auth, err := json.Marshal(token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode auth token: %w", err)
+		}
+		req.Header.Set(HeaderAuth, string(auth))
+	}
+	return req, nil
+}
+
+// cached returns the stored message if it can be revalidated. With trusted
+// keys, a stored message that fails verification is not offered, so the
+// server sends the current one in full.
+func (c *HTTPClient) cached() *Message {
+	if c.client.store == nil {
+		return nil
+	}
+	msg, err := c.client.store.Load()
+	if err != nil || msg.ETag == "" {
+		return nil
+	}
+	if err := c.client.verify(msg); err != nil {
+		slog.Debug("Not revalidating stored message", "error", err)
+		return nil
+	}
+	return msg
+}
+
+// readResponse reads a 200 response into a Message.
+func (c *HTTPClient) readResponse(resp *http.Response) (*Message, error) {
+	// Read one byte past the limit to detect oversized messages.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(c.client.maxSize)+1))
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: response body ended early", ErrTruncated)
+		}
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > c.client.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, c.client.maxSize)
+	}
+
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if encoding == "identity" {
+		encoding = ""
+	}
+	if body, err = DecodePayload(Frame{Encoding: encoding, Payload: body}, c.client.maxSize); err != nil {
+		return nil, err
+	}
+
+	declared := resp.Header.Get("Content-Type")
+	msg := &Message{
+		ContentType:  contentTypeOf(declared, body),
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		declaredType: declared,
+	}
+
+	if sig := resp.Header.Get(HeaderSignature); sig != "" {
+		if msg.Signature, err = base64.StdEncoding.DecodeString(sig); err != nil {
+			return nil, fmt.Errorf("%w: invalid signature header: %v", ErrProtocol, err)
+		}
+	}
+	if len(c.client.keys) == 0 {
+		msg.Metadata = metadataOf(resp.Header)
+	}
+
+	slog.Debug("Message received", "length", len(body), "content_type", msg.ContentType, "etag", msg.ETag)
+	return msg, nil
+}
+
+// contentTypeOf maps an HTTP Content-Type to a message content type. Types
+// that say nothing about the content are sniffed.
+func contentTypeOf(header string, body []byte) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || mediaType == "application/octet-stream" {
+		return Sniff(body)
+	}
+	if mediaType == "text/plain" && slices.Contains(body, 0x1b) {
+		return ContentTypeANSI
+	}
+	return mediaType
+}
+
+// metadataOf reads message metadata from response headers.
+func metadataOf(h http.Header) Metadata {
+	meta := Metadata{
+		ID:      h.Get(HeaderID),
+		Title:   h.Get(HeaderTitle),
+		Author:  h.Get(HeaderAuthor),
+		URL:     h.Get(HeaderURL),
+		Caption: h.Get(HeaderCaption),
+	}
+	if expires, err := http.ParseTime(h.Get("Expires")); err == nil {
+		meta.Expires = expires
+	}
+	return meta
+}
@@ -0,0 +1,404 @@
+package network
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// memoryStore is a MessageStore kept in memory.
+type memoryStore struct {
+	msg *Message
+}
+
+func (s *memoryStore) Load() (*Message, error) {
+	if s.msg == nil {
+		return nil, errors.New("nothing stored")
+	}
+	return s.msg, nil
+}
+
+func (s *memoryStore) Store(msg *Message) error {
+	s.msg = msg
+	return nil
+}
+
+// fetchHTTP fetches one message from srv with a client for its URL.
+func fetchHTTP(t *testing.T, srv *httptest.Server, opts ...Option) (*Message, error) {
+	t.Helper()
+
+	if srv.TLS != nil {
+		opts = append(opts, WithTLSConfig(srv.Client().Transport.(*http.Transport).TLSClientConfig))
+	}
+	client, err := NewHTTPClient(srv.URL+"/motd", time.Second, opts...)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() unexpected error: %v", err)
+	}
+	conn, err := client.Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect to test server: %v", err)
+	}
+	defer conn.Close()
+
+	return client.FetchMessage(conn, Hello{ClientVersion: "1.2.3", Capabilities: Capabilities{Columns: 80}})
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		wantHost string
+		wantErr  bool
+	}{
+		{url: "http://motd.example.com/", wantHost: "motd.example.com:80"},
+		{url: "https://motd.example.com/", wantHost: "motd.example.com:443"},
+		{url: "https://motd.example.com:8443/motd", wantHost: "motd.example.com:8443"},
+		{url: "https://[::1]/motd", wantHost: "[::1]:443"},
+		{url: "ftp://motd.example.com/", wantErr: true},
+		{url: "motd.example.com", wantErr: true},
+		{url: "https:///motd", wantErr: true},
+		{url: "http://%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && u.Host != tt.wantHost {
+				t.Errorf("ParseURL() host = %q, want %q", u.Host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_FetchMessage(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set(HeaderID, "42")
+		w.Header().Set(HeaderTitle, "Welcome")
+		w.Header().Set(HeaderCaption, "Our new logo")
+		w.Header().Set("Expires", "Wed, 01 Jan 2031 00:00:00 GMT")
+		w.Write([]byte("# Hello"))
+	}))
+	defer srv.Close()
+
+	msg, err := fetchHTTP(t, srv)
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+
+	if string(msg.Body) != "# Hello" {
+		t.Errorf("Body = %q, want %q", msg.Body, "# Hello")
+	}
+	if msg.ContentType != ContentTypeMarkdown {
+		t.Errorf("ContentType = %q, want %q", msg.ContentType, ContentTypeMarkdown)
+	}
+	wantMeta := Metadata{ID: "42", Title: "Welcome", Caption: "Our new logo", Expires: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if msg.Metadata != wantMeta {
+		t.Errorf("Metadata = %+v, want %+v", msg.Metadata, wantMeta)
+	}
+
+	if got.Method != http.MethodGet || got.URL.Path != "/motd" {
+		t.Errorf("Request = %s %s, want GET /motd", got.Method, got.URL.Path)
+	}
+	if v := got.Header.Get(HeaderClientVersion); v != "1.2.3" {
+		t.Errorf("%s = %q, want %q", HeaderClientVersion, v, "1.2.3")
+	}
+	var caps Capabilities
+	if err := json.Unmarshal([]byte(got.Header.Get(HeaderCapabilities)), &caps); err != nil || caps.Columns != 80 {
+		t.Errorf("%s = %q, want columns 80", HeaderCapabilities, got.Header.Get(HeaderCapabilities))
+	}
+	if got.Header.Get("Accept") == "" || got.Header.Get("Accept-Encoding") == "" {
+		t.Errorf("Accept and Accept-Encoding must be set, got %v", got.Header)
+	}
+}
+
+func TestHTTPClient_FetchMessage_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeText)
+		w.Write([]byte("Secure MOTD"))
+	}))
+	defer srv.Close()
+
+	msg, err := fetchHTTP(t, srv)
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(msg.Body) != "Secure MOTD" {
+		t.Errorf("Body = %q, want %q", msg.Body, "Secure MOTD")
+	}
+}
+
+func TestHTTPClient_Connect_UntrustedCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(srv.URL, time.Second, WithTLSConfig(&tls.Config{}))
+	if err != nil {
+		t.Fatalf("NewHTTPClient() unexpected error: %v", err)
+	}
+	if conn, err := client.Connect(); err == nil {
+		conn.Close()
+		t.Error("Connect() expected error for untrusted certificate, got nil")
+	}
+}
+
+func TestHTTPClient_FetchMessage_ContentTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "declared", contentType: "text/x-ansi", body: "\033[1mHi\033[0m", want: ContentTypeANSI},
+		{name: "parameters stripped", contentType: "text/plain; charset=utf-8", body: "Hi", want: ContentTypeText},
+		{name: "plain text with escapes", contentType: "text/plain", body: "\033[1mHi\033[0m", want: ContentTypeANSI},
+		{name: "octet stream sniffed", contentType: "application/octet-stream", body: "\x89PNG\r\n\x1a\n", want: ContentTypePNG},
+		{name: "missing sniffed", body: "1337;File=inline=1:AAAA", want: ContentTypeOSC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Suppress net/http's own sniffing when no type is given.
+				w.Header()["Content-Type"] = nil
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			msg, err := fetchHTTP(t, srv)
+			if err != nil {
+				t.Fatalf("FetchMessage() unexpected error: %v", err)
+			}
+			if msg.ContentType != tt.want {
+				t.Errorf("ContentType = %q, want %q", msg.ContentType, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_FetchMessage_Status(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{name: "no content", status: http.StatusNoContent},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: ErrRejected},
+		{name: "forbidden", status: http.StatusForbidden, wantErr: ErrRejected},
+		{name: "not found", status: http.StatusNotFound, wantErr: ErrProtocol},
+		{name: "not modified without stored message", status: http.StatusNotModified, wantErr: ErrProtocol},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: ErrProtocol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			msg, err := fetchHTTP(t, srv)
+			if tt.wantErr == nil {
+				if err != nil || len(msg.Body) != 0 {
+					t.Errorf("FetchMessage() = %v, %v, want empty message", msg, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("FetchMessage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_FetchMessage_ETag(t *testing.T) {
+	const etag = `"v1"`
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", ContentTypeText)
+		w.Write([]byte("Cached MOTD"))
+	}))
+	defer srv.Close()
+
+	store := &memoryStore{}
+	first, err := fetchHTTP(t, srv, WithMessageStore(store))
+	if err != nil {
+		t.Fatalf("First FetchMessage() unexpected error: %v", err)
+	}
+	if first.ETag != etag {
+		t.Fatalf("ETag = %q, want %q", first.ETag, etag)
+	}
+	store.Store(first)
+
+	second, err := fetchHTTP(t, srv, WithMessageStore(store))
+	if err != nil {
+		t.Fatalf("Second FetchMessage() unexpected error: %v", err)
+	}
+	if string(second.Body) != "Cached MOTD" {
+		t.Errorf("Body = %q, want stored message", second.Body)
+	}
+	if requests != 2 {
+		t.Errorf("Server saw %d requests, want 2", requests)
+	}
+}
+
+func TestHTTPClient_FetchMessage_ETagUnverified(t *testing.T) {
+	pub, priv := newKey(t)
+	signed := &Message{ContentType: ContentTypeText, Body: []byte("Signed MOTD")}
+	if err := signed.Sign(priv); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+
+	const etag = `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", ContentTypeText)
+		w.Header().Set(HeaderSignature, base64.StdEncoding.EncodeToString(signed.Signature))
+		w.Write(signed.Body)
+	}))
+	defer srv.Close()
+
+	store := &memoryStore{msg: &Message{Body: []byte("Forged MOTD"), ETag: etag}}
+	msg, err := fetchHTTP(t, srv, WithMessageStore(store), WithTrustedKeys([]ed25519.PublicKey{pub}))
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(msg.Body) != "Signed MOTD" {
+		t.Errorf("Body = %q, want the server's message instead of the unverified stored one", msg.Body)
+	}
+}
+
+func TestHTTPClient_FetchMessage_Compressed(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("Compressed MOTD"))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeText)
+		w.Header().Set("Content-Encoding", EncodingGzip)
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	msg, err := fetchHTTP(t, srv)
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(msg.Body) != "Compressed MOTD" {
+		t.Errorf("Body = %q, want %q", msg.Body, "Compressed MOTD")
+	}
+}
+
+func TestHTTPClient_FetchMessage_TooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), 100))
+	}))
+	defer srv.Close()
+
+	if _, err := fetchHTTP(t, srv, WithMaxSize(10)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("FetchMessage() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestHTTPClient_FetchMessage_Truncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("short"))
+	}))
+	defer srv.Close()
+
+	if _, err := fetchHTTP(t, srv); !errors.Is(err, ErrTruncated) {
+		t.Errorf("FetchMessage() error = %v, want ErrTruncated", err)
+	}
+}
+
+func TestHTTPClient_FetchMessage_TrustedKeys(t *testing.T) {
+	pub, priv := newKey(t)
+	signed := &Message{ContentType: ContentTypeText, Body: []byte("Signed MOTD")}
+	if err := signed.Sign(priv); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		sig     []byte
+		wantErr error
+	}{
+		{name: "valid", body: "Signed MOTD", sig: signed.Signature},
+		{name: "unsigned", body: "Signed MOTD", wantErr: ErrUnsigned},
+		{name: "tampered", body: "Forged MOTD", sig: signed.Signature, wantErr: ErrBadSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", ContentTypeText)
+				w.Header().Set(HeaderTitle, "Unsigned title")
+				if tt.sig != nil {
+					w.Header().Set(HeaderSignature, base64.StdEncoding.EncodeToString(tt.sig))
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			msg, err := fetchHTTP(t, srv, WithTrustedKeys([]ed25519.PublicKey{pub}))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FetchMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !msg.Metadata.IsZero() {
+				t.Errorf("Metadata = %+v, want none when verifying signatures", msg.Metadata)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_FetchMessage_Auth(t *testing.T) {
+	secret := []byte("shared secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token AuthToken
+		if err := json.Unmarshal([]byte(r.Header.Get(HeaderAuth)), &token); err != nil || token.Verify(secret, time.Now()) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("Authenticated MOTD"))
+	}))
+	defer srv.Close()
+
+	if _, err := fetchHTTP(t, srv); !errors.Is(err, ErrRejected) {
+		t.Errorf("FetchMessage() without auth error = %v, want ErrRejected", err)
+	}
+	msg, err := fetchHTTP(t, srv, WithAuth("default", secret))
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(msg.Body) != "Authenticated MOTD" {
+		t.Errorf("Body = %q, want %q", msg.Body, "Authenticated MOTD")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package network
+
+import "os"
+
+// checkKeyPermissions is a no-op on Windows, where file modes do not
+// reflect access control lists.
+func checkKeyPermissions(path string, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package network
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkKeyPermissions refuses key files that users other than the owner
+// can access.
+func checkKeyPermissions(path string, info os.FileInfo) error {
+	if perm := info.Mode().Perm(); perm&0o007 != 0 {
+		return fmt.Errorf("auth key %s is accessible by other users (mode %04o); run chmod o-rwx on it", path, perm)
+	}
+	return nil
+}
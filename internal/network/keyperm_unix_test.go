@@ -0,0 +1,39 @@
+//go:build !windows
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAuthKey_Permissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    os.FileMode
+		wantErr bool
+	}{
+		{name: "owner only", mode: 0o600, wantErr: false},
+		{name: "group readable", mode: 0o640, wantErr: false},
+		{name: "world readable", mode: 0o644, wantErr: true},
+		{name: "world writable", mode: 0o602, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key")
+			if err := os.WriteFile(path, []byte("s3cret"), 0o600); err != nil {
+				t.Fatalf("Failed to write key: %v", err)
+			}
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatalf("Failed to chmod key: %v", err)
+			}
+
+			_, err := LoadAuthKey(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadAuthKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// Content types of messages.
+const (
+	// ContentTypeText is plain text. Control characters are not trusted and
+	// are removed before display.
+	ContentTypeText = "text/plain"
+	// ContentTypeANSI is text containing ANSI escape sequences for colors
+	// and styles.
+	ContentTypeANSI = "text/x-ansi"
+	// ContentTypeMarkdown is Markdown text.
+	ContentTypeMarkdown = "text/markdown"
+	// ContentTypePNG is a PNG image, displayed with the terminal's inline
+	// image protocol.
+	ContentTypePNG = "image/png"
+	// ContentTypeGIF is a GIF image, which may be animated.
+	ContentTypeGIF = "image/gif"
+	// ContentTypeOSC is the body of an operating system command, such as an
+	// iTerm2 inline image, to be wrapped in the terminal's OSC sequence.
+	ContentTypeOSC = "application/vnd.motd.osc"
+)
+
+// ContentTypes lists the content types clients know how to display.
+var ContentTypes = []string{
+	ContentTypeText, ContentTypeANSI, ContentTypeMarkdown, ContentTypePNG, ContentTypeGIF, ContentTypeOSC,
+}
+
+// Message is a MOTD received from the server.
+type Message struct {
+	// ContentType is the MIME type declared by the server or, for servers
+	// that do not declare one, sniffed from Body.
+	ContentType string
+	// Metadata describes the message. It is empty for legacy servers.
+	Metadata Metadata
+	// Body holds the message content exactly as received.
+	Body []byte
+	// Signature is the detached Ed25519 signature sent by the server, if
+	// any. See Sign and Verify.
+	Signature []byte
+	// ETag identifies this version of the message for HTTP servers, which
+	// answer 304 Not Modified when it is still current.
+	ETag string
+
+	// declaredType and rawMetadata hold the content type and metadata as
+	// sent on the wire, which the signature covers.
+	declaredType string
+	rawMetadata  []byte
+}
+
+// Metadata carries optional information about a message. Versioned servers
+// send it as a JSON FrameMetadata before the message frame.
+type Metadata struct {
+	ID      string    `json:"id,omitempty"`      // Server assigned identifier
+	Title   string    `json:"title,omitempty"`   // Short title, used as alternative text
+	Author  string    `json:"author,omitempty"`  // Who wrote the message
+	Expires time.Time `json:"expires,omitzero"`  // Do not display after this time
+	URL     string    `json:"url,omitempty"`     // Link to more information
+	Caption string    `json:"caption,omitempty"` // Markdown shown beside an image
+}
+
+// IsZero reports whether no metadata is set.
+func (m Metadata) IsZero() bool {
+	return m.ID == "" && m.Title == "" && m.Author == "" && m.Expires.IsZero() && m.URL == "" &&
+		m.Caption == ""
+}
+
+// Expired reports whether the message has an expiry before now.
+func (m *Message) Expired(now time.Time) bool {
+	return !m.Metadata.Expires.IsZero() && now.After(m.Metadata.Expires)
+}
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	// gifSignatures are the headers of the two GIF versions.
+	gifSignatures = [][]byte{[]byte("GIF87a"), []byte("GIF89a")}
+	// oscCommand matches the start of an OSC body such as "1337;File=".
+	oscCommand = regexp.MustCompile(`^[0-9]+;`)
+	// markdownBlock matches lines that are unlikely outside Markdown:
+	// headings, fenced code and links.
+	markdownBlock = regexp.MustCompile("(?m)^(#{1,6} |```)|\\[[^\\]\n]+\\]\\([^)\n]+\\)")
+)
+
+// Sniff guesses the content type of body for servers that do not declare
+// one. Legacy servers sent OSC bodies, so anything that starts like an
+// operating system command is treated as one.
+func Sniff(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, pngSignature):
+		return ContentTypePNG
+	case bytes.HasPrefix(body, gifSignatures[0]) || bytes.HasPrefix(body, gifSignatures[1]):
+		return ContentTypeGIF
+	case oscCommand.Match(body):
+		return ContentTypeOSC
+	case bytes.IndexByte(body, 0x1b) >= 0:
+		return ContentTypeANSI
+	case markdownBlock.Match(body):
+		return ContentTypeMarkdown
+	default:
+		return ContentTypeText
+	}
+}
+
+// WriteOptions controls how WriteMessage encodes frames.
+type WriteOptions struct {
+	Checksum bool   // Append a CRC-32 to every frame
+	Encoding string // Compress the message body, e.g. EncodingGzip
+}
+
+// WriteMessage encodes msg to w as optional metadata and signature frames
+// followed by a message frame of the given protocol version. Servers use it
+// to reply to a client's hello.
+func WriteMessage(w io.Writer, version byte, msg *Message, opts WriteOptions) error {
+	payload := msg.rawMetadata
+	if payload == nil && !msg.Metadata.IsZero() {
+		var err error
+		if payload, err = json.Marshal(msg.Metadata); err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+	}
+	if payload != nil {
+		meta := Frame{Version: version, Type: FrameMetadata, ContentType: ContentTypeJSON, Checksum: opts.Checksum, Payload: payload}
+		if err := WriteFrame(w, meta); err != nil {
+			return err
+		}
+	}
+
+	if len(msg.Signature) > 0 {
+		sig := Frame{Version: version, Type: FrameSignature, Checksum: opts.Checksum, Payload: msg.Signature}
+		if err := WriteFrame(w, sig); err != nil {
+			return err
+		}
+	}
+
+	body, err := encodePayload(opts.Encoding, msg.Body)
+	if err != nil {
+		return err
+	}
+	return WriteFrame(w, Frame{
+		Version:     version,
+		Type:        FrameMessage,
+		ContentType: msg.ContentType,
+		Encoding:    opts.Encoding,
+		Checksum:    opts.Checksum,
+		Payload:     body,
+	})
+}
+
+// readMessage reads the optional metadata and signature frames, in that
+// order, and the message frame.
+func readMessage(r io.Reader, maxSize int) (*Message, Frame, error) {
+	msg := &Message{}
+	expect := FrameMetadata
+	for {
+		f, err := ReadFrame(r, maxSize)
+		if err != nil {
+			return nil, Frame{}, err
+		}
+		if f.Payload, err = DecodePayload(f, maxSize); err != nil {
+			return nil, Frame{}, err
+		}
+
+		switch {
+		case f.Type == FrameMetadata && expect == FrameMetadata:
+			if err := json.Unmarshal(f.Payload, &msg.Metadata); err != nil {
+				return nil, Frame{}, fmt.Errorf("%w: invalid metadata: %v", ErrProtocol, err)
+			}
+			msg.rawMetadata = f.Payload
+			expect = FrameSignature
+		case f.Type == FrameSignature && expect != FrameMessage:
+			msg.Signature = f.Payload
+			expect = FrameMessage
+		case f.Type == FrameError:
+			return nil, Frame{}, fmt.Errorf("%w: %q", ErrRejected, f.Payload)
+		case f.Type == FrameMessage:
+			msg.declaredType = f.ContentType
+			msg.ContentType = f.ContentType
+			if msg.ContentType == "" {
+				msg.ContentType = Sniff(f.Payload)
+			}
+			msg.Body = f.Payload
+			return msg, f, nil
+		default:
+			return nil, Frame{}, fmt.Errorf("%w: unexpected frame type %d", ErrProtocol, f.Type)
+		}
+	}
+}
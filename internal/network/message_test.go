@@ -0,0 +1,154 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty", body: "", want: ContentTypeText},
+		{name: "plain text", body: "Have a nice day", want: ContentTypeText},
+		{name: "png", body: "\x89PNG\r\n\x1a\n\x00\x00", want: ContentTypePNG},
+		{name: "gif87a", body: "GIF87a\x01\x00", want: ContentTypeGIF},
+		{name: "gif89a", body: "GIF89a\x01\x00", want: ContentTypeGIF},
+		{name: "iterm2 image", body: "1337;File=inline=1:AAAA", want: ContentTypeOSC},
+		{name: "hyperlink", body: "8;;https://example.com", want: ContentTypeOSC},
+		{name: "ansi", body: "\033[1mBold\033[0m", want: ContentTypeANSI},
+		{name: "markdown heading", body: "intro\n## Heading\n", want: ContentTypeMarkdown},
+		{name: "markdown fence", body: "```\ncode\n```", want: ContentTypeMarkdown},
+		{name: "markdown link", body: "see [docs](https://example.com)", want: ContentTypeMarkdown},
+		{name: "hash without space", body: "#hashtag", want: ContentTypeText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sniff([]byte(tt.body)); got != tt.want {
+				t.Errorf("Sniff(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage_Expired(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{name: "no expiry", want: false},
+		{name: "future", expires: now.Add(time.Hour), want: false},
+		{name: "past", expires: now.Add(-time.Hour), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{Metadata: Metadata{Expires: tt.expires}}
+			if got := msg.Expired(now); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMessage_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "with metadata",
+			msg: Message{
+				ContentType: ContentTypeANSI,
+				Metadata:    Metadata{ID: "a.txt", Title: "Hello", Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+				Body:        []byte("\033[1mHello\033[0m"),
+			},
+		},
+		{
+			name: "without metadata",
+			msg:  Message{ContentType: ContentTypeText, Body: []byte("Hello")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteMessage(&buf, ProtocolVersion, &tt.msg, WriteOptions{Checksum: true}); err != nil {
+				t.Fatalf("WriteMessage() unexpected error: %v", err)
+			}
+
+			out, f, err := readMessage(&buf, MaxFrameSize)
+			if err != nil {
+				t.Fatalf("readMessage() unexpected error: %v", err)
+			}
+			if !f.Checksum {
+				t.Error("Expected the message frame to carry a checksum")
+			}
+			if out.ContentType != tt.msg.ContentType || out.Metadata != tt.msg.Metadata || !bytes.Equal(out.Body, tt.msg.Body) {
+				t.Errorf("readMessage() = %+v, want %+v", out, tt.msg)
+			}
+		})
+	}
+}
+
+func TestReadMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []Frame
+		want   error
+	}{
+		{
+			name:   "invalid metadata",
+			frames: []Frame{{Type: FrameMetadata, Payload: []byte("{")}},
+			want:   ErrProtocol,
+		},
+		{
+			name:   "metadata without message",
+			frames: []Frame{{Type: FrameMetadata, Payload: []byte("{}")}},
+			want:   ErrTruncated,
+		},
+		{
+			name:   "unexpected frame",
+			frames: []Frame{{Type: FrameGreeting}},
+			want:   ErrProtocol,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			for _, f := range tt.frames {
+				f.Version = ProtocolVersion
+				if err := WriteFrame(&buf, f); err != nil {
+					t.Fatalf("WriteFrame() unexpected error: %v", err)
+				}
+			}
+
+			_, _, err := readMessage(&buf, MaxFrameSize)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("readMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMessage_SniffsUndeclaredType(t *testing.T) {
+	var buf bytes.Buffer
+	WriteFrame(&buf, Frame{Version: ProtocolVersion, Type: FrameMessage, Payload: []byte("1337;File=inline=1:AAAA")})
+
+	msg, _, err := readMessage(&buf, MaxFrameSize)
+	if err != nil {
+		t.Fatalf("readMessage() unexpected error: %v", err)
+	}
+	if msg.ContentType != ContentTypeOSC {
+		t.Errorf("ContentType = %q, want %q", msg.ContentType, ContentTypeOSC)
+	}
+}
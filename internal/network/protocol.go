@@ -0,0 +1,270 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// Protocol modes accepted by WithProtocol.
+const (
+	// ProtocolAuto uses the versioned protocol when the server greets the
+	// client and falls back to reading until EOF otherwise.
+	ProtocolAuto = "auto"
+	// ProtocolV1 requires the versioned protocol.
+	ProtocolV1 = "v1"
+	// ProtocolLegacy never sends a hello and reads until EOF.
+	ProtocolLegacy = "legacy"
+)
+
+// Protocols lists the accepted protocol mode names.
+var Protocols = []string{ProtocolAuto, ProtocolV1, ProtocolLegacy}
+
+// ProtocolVersion is the highest protocol version this package speaks.
+const ProtocolVersion = 1
+
+// Frame types.
+const (
+	// FrameGreeting is sent by the server as soon as a client connects.
+	FrameGreeting byte = 1
+	// FrameHello is the client's reply, carrying a JSON encoded Hello.
+	FrameHello byte = 2
+	// FrameMessage carries the MOTD.
+	FrameMessage byte = 3
+	// FrameMetadata optionally precedes FrameMessage with a JSON encoded
+	// Metadata.
+	FrameMetadata byte = 4
+	// FrameSignature optionally precedes FrameMessage with a detached
+	// Ed25519 signature of the message.
+	FrameSignature byte = 5
+	// FrameError replaces FrameMessage when the server refuses the request.
+	// Its payload is a human readable reason.
+	FrameError byte = 6
+)
+
+// Frame flags.
+const (
+	// FlagChecksum marks a frame followed by a CRC-32 (IEEE) of its payload.
+	FlagChecksum byte = 1 << 0
+	// FlagEncoded marks a frame whose payload is compressed with the
+	// encoding named after the content type.
+	FlagEncoded byte = 1 << 1
+)
+
+// ContentTypeJSON is the content type of hello and metadata frames.
+const ContentTypeJSON = "application/json"
+
+// MaxFrameSize is the default bound on payload length.
+const MaxFrameSize = 16 << 20
+
+// maxHelloSize bounds the hello a server accepts from a client.
+const maxHelloSize = 64 << 10
+
+// Magic starts every frame. A legacy server sends its message immediately,
+// so a missing greeting identifies it without sending it anything.
+var Magic = []byte("MOTD")
+
+// Errors reported when a peer violates the protocol. ErrTruncated,
+// ErrChecksum and ErrTooLarge also match ErrProtocol.
+var (
+	ErrProtocol  = errors.New("protocol error")
+	ErrTruncated = fmt.Errorf("%w: message truncated", ErrProtocol)
+	ErrChecksum  = fmt.Errorf("%w: checksum mismatch", ErrProtocol)
+	ErrTooLarge  = fmt.Errorf("%w: message too large", ErrProtocol)
+)
+
+// Frame is a single protocol unit:
+//
+//	magic "MOTD" | version uint8 | type uint8 | flags uint8 |
+//	content type length uint8 | content type |
+//	encoding length uint8 | encoding (only with FlagEncoded) |
+//	payload length uint32 (big endian) | payload |
+//	CRC-32 of payload uint32 (only with FlagChecksum)
+type Frame struct {
+	Version     byte
+	Type        byte
+	ContentType string
+	Encoding    string // Compression of Payload, empty if none
+	Checksum    bool   // Append a CRC-32 of the payload when writing
+	Payload     []byte
+}
+
+// WriteFrame encodes f to w.
+func WriteFrame(w io.Writer, f Frame) error {
+	if len(f.ContentType) > 255 {
+		return fmt.Errorf("%w: content type %q too long", ErrProtocol, f.ContentType)
+	}
+	if len(f.Encoding) > 255 {
+		return fmt.Errorf("%w: encoding %q too long", ErrProtocol, f.Encoding)
+	}
+	if len(f.Payload) > MaxFrameSize {
+		return fmt.Errorf("%w: payload of %d bytes", ErrTooLarge, len(f.Payload))
+	}
+
+	var flags byte
+	if f.Checksum {
+		flags |= FlagChecksum
+	}
+	if f.Encoding != "" {
+		flags |= FlagEncoded
+	}
+
+	buf := make([]byte, 0, 13+len(f.ContentType)+len(f.Encoding)+len(f.Payload)+4)
+	buf = append(buf, Magic...)
+	buf = append(buf, f.Version, f.Type, flags, byte(len(f.ContentType)))
+	buf = append(buf, f.ContentType...)
+	if f.Encoding != "" {
+		buf = append(buf, byte(len(f.Encoding)))
+		buf = append(buf, f.Encoding...)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.Payload)))
+	buf = append(buf, f.Payload...)
+	if f.Checksum {
+		buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(f.Payload))
+	}
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	return nil
+}
+
+// ReadFrame decodes one frame from r, rejecting payloads longer than
+// maxSize bytes. A stream that ends mid-frame yields ErrTruncated. Encoded
+// payloads are returned as sent; see DecodePayload.
+func ReadFrame(r io.Reader, maxSize int) (Frame, error) {
+	var header [8]byte
+	if err := readFull(r, header[:], "frame header"); err != nil {
+		return Frame{}, err
+	}
+	if !bytes.Equal(header[:4], Magic) {
+		return Frame{}, fmt.Errorf("%w: bad frame magic %q", ErrProtocol, header[:4])
+	}
+
+	f := Frame{Version: header[4], Type: header[5], Checksum: header[6]&FlagChecksum != 0}
+
+	contentType := make([]byte, header[7])
+	if err := readFull(r, contentType, "content type"); err != nil {
+		return Frame{}, err
+	}
+	f.ContentType = string(contentType)
+
+	if header[6]&FlagEncoded != 0 {
+		var n [1]byte
+		if err := readFull(r, n[:], "encoding length"); err != nil {
+			return Frame{}, err
+		}
+		encoding := make([]byte, n[0])
+		if err := readFull(r, encoding, "encoding"); err != nil {
+			return Frame{}, err
+		}
+		f.Encoding = string(encoding)
+	}
+
+	var length [4]byte
+	if err := readFull(r, length[:], "payload length"); err != nil {
+		return Frame{}, err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if uint64(size) > uint64(maxSize) {
+		return Frame{}, fmt.Errorf("%w: payload of %d bytes exceeds limit of %d", ErrTooLarge, size, maxSize)
+	}
+
+	f.Payload = make([]byte, size)
+	if err := readFull(r, f.Payload, "payload"); err != nil {
+		return Frame{}, err
+	}
+
+	if f.Checksum {
+		var sum [4]byte
+		if err := readFull(r, sum[:], "checksum"); err != nil {
+			return Frame{}, err
+		}
+		if binary.BigEndian.Uint32(sum[:]) != crc32.ChecksumIEEE(f.Payload) {
+			return Frame{}, ErrChecksum
+		}
+	}
+
+	return f, nil
+}
+
+// readFull fills buf from r, reporting an early end of stream as ErrTruncated.
+func readFull(r io.Reader, buf []byte, what string) error {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: stream ended while reading %s", ErrTruncated, what)
+		}
+		return fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	return nil
+}
+
+// expectFrame reads a frame and checks that it has the wanted type.
+func expectFrame(r io.Reader, want byte, maxSize int) (Frame, error) {
+	f, err := ReadFrame(r, maxSize)
+	if err != nil {
+		return Frame{}, err
+	}
+	if f.Type != want {
+		return Frame{}, fmt.Errorf("%w: expected frame type %d, got %d", ErrProtocol, want, f.Type)
+	}
+	return f, nil
+}
+
+// Hello is sent by the client after the server's greeting so the server can
+// tailor its response to the terminal.
+type Hello struct {
+	ClientVersion string       `json:"client_version"`
+	Capabilities  Capabilities `json:"capabilities"`
+	Encodings     []string     `json:"encodings,omitempty"` // Payload encodings the client can decode
+	Auth          *AuthToken   `json:"auth,omitempty"`      // Set when the client authenticates
+	Watch         bool         `json:"watch,omitempty"`     // Keep the connection open for new messages
+}
+
+// Capabilities describes what the client's terminal can display.
+type Capabilities struct {
+	Graphics   []string `json:"graphics,omitempty"`    // Inline image protocols, e.g. "iterm2"
+	Columns    int      `json:"columns,omitempty"`     // Width in cells
+	Rows       int      `json:"rows,omitempty"`        // Height in cells
+	ColorDepth int      `json:"color_depth,omitempty"` // Bits per color
+	Background string   `json:"background,omitempty"`  // BackgroundLight or BackgroundDark, empty if unknown
+}
+
+// Terminal backgrounds reported in Capabilities.
+const (
+	BackgroundLight = "light"
+	BackgroundDark  = "dark"
+)
+
+// SupportsGraphics reports whether the named image protocol is supported.
+func (c Capabilities) SupportsGraphics(protocol string) bool {
+	return slices.Contains(c.Graphics, protocol)
+}
+
+// ReadHello reads and decodes a hello frame from r.
+func ReadHello(r io.Reader) (Hello, error) {
+	f, err := expectFrame(r, FrameHello, maxHelloSize)
+	if err != nil {
+		return Hello{}, err
+	}
+
+	var hello Hello
+	if err := json.Unmarshal(f.Payload, &hello); err != nil {
+		return Hello{}, fmt.Errorf("%w: invalid hello: %v", ErrProtocol, err)
+	}
+	return hello, nil
+}
+
+// writeHello encodes hello as a frame of the given version.
+func writeHello(w io.Writer, version byte, hello Hello) error {
+	payload, err := json.Marshal(hello)
+	if err != nil {
+		return fmt.Errorf("failed to encode hello: %w", err)
+	}
+	return WriteFrame(w, Frame{Version: version, Type: FrameHello, ContentType: ContentTypeJSON, Payload: payload})
+}
@@ -0,0 +1,138 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFrame_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    Frame
+		expected string
+	}{
+		{
+			name:     "without checksum",
+			frame:    Frame{Version: ProtocolVersion, Type: FrameMessage, ContentType: "text/plain", Payload: []byte("Hello")},
+			expected: "MOTD\x01\x03\x00\x0atext/plain\x00\x00\x00\x05Hello",
+		},
+		{
+			name:     "with checksum",
+			frame:    Frame{Version: ProtocolVersion, Type: FrameMessage, Checksum: true, Payload: []byte("abc")},
+			expected: "MOTD\x01\x03\x01\x00\x00\x00\x00\x03abc\x35\x24\x41\xc2",
+		},
+		{
+			name:     "empty greeting",
+			frame:    Frame{Version: ProtocolVersion, Type: FrameGreeting, Payload: []byte{}},
+			expected: "MOTD\x01\x01\x00\x00\x00\x00\x00\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteFrame(&buf, tt.frame); err != nil {
+				t.Fatalf("WriteFrame() unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Encoded frame = %q, want %q", buf.String(), tt.expected)
+			}
+
+			out, err := ReadFrame(&buf, MaxFrameSize)
+			if err != nil {
+				t.Fatalf("ReadFrame() unexpected error: %v", err)
+			}
+			if out.Version != tt.frame.Version || out.Type != tt.frame.Type || out.ContentType != tt.frame.ContentType ||
+				out.Checksum != tt.frame.Checksum || !bytes.Equal(out.Payload, tt.frame.Payload) {
+				t.Errorf("ReadFrame() = %+v, want %+v", out, tt.frame)
+			}
+		})
+	}
+}
+
+func TestReadFrame_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		maxSize int
+		want    error
+	}{
+		{name: "bad magic", data: "HTTP\x01\x03\x00\x00\x00\x00\x00\x00", maxSize: MaxFrameSize, want: ErrProtocol},
+		{name: "too large", data: "MOTD\x01\x03\x00\x00\x00\x00\x00\x06", maxSize: 5, want: ErrTooLarge},
+		{name: "empty stream", data: "", maxSize: MaxFrameSize, want: ErrTruncated},
+		{name: "short header", data: "MOTD\x01", maxSize: MaxFrameSize, want: ErrTruncated},
+		{name: "short content type", data: "MOTD\x01\x03\x00\x0atext", maxSize: MaxFrameSize, want: ErrTruncated},
+		{name: "short payload", data: "MOTD\x01\x03\x00\x00\x00\x00\x00\x05Hi", maxSize: MaxFrameSize, want: ErrTruncated},
+		{name: "missing checksum", data: "MOTD\x01\x03\x01\x00\x00\x00\x00\x03abc", maxSize: MaxFrameSize, want: ErrTruncated},
+		{name: "bad checksum", data: "MOTD\x01\x03\x01\x00\x00\x00\x00\x03abc\x00\x00\x00\x00", maxSize: MaxFrameSize, want: ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFrame(bytes.NewReader([]byte(tt.data)), tt.maxSize)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ReadFrame() error = %v, want %v", err, tt.want)
+			}
+			if !errors.Is(err, ErrProtocol) {
+				t.Errorf("ReadFrame() error = %v, want it to match ErrProtocol", err)
+			}
+		})
+	}
+}
+
+func TestWriteFrame_Errors(t *testing.T) {
+	var buf bytes.Buffer
+
+	longType := string(bytes.Repeat([]byte("x"), 256))
+	if err := WriteFrame(&buf, Frame{ContentType: longType}); !errors.Is(err, ErrProtocol) {
+		t.Errorf("WriteFrame() with long content type error = %v, want ErrProtocol", err)
+	}
+
+	tooLarge := make([]byte, MaxFrameSize+1)
+	if err := WriteFrame(&buf, Frame{Payload: tooLarge}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("WriteFrame() with large payload error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestHello_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	in := Hello{
+		ClientVersion: "1.2.3",
+		Capabilities: Capabilities{
+			Graphics:   []string{"iterm2"},
+			Columns:    80,
+			Rows:       24,
+			ColorDepth: 24,
+			Background: BackgroundDark,
+		},
+	}
+
+	if err := writeHello(&buf, ProtocolVersion, in); err != nil {
+		t.Fatalf("writeHello() unexpected error: %v", err)
+	}
+
+	out, err := ReadHello(&buf)
+	if err != nil {
+		t.Fatalf("ReadHello() unexpected error: %v", err)
+	}
+	if out.ClientVersion != in.ClientVersion || !slices.Equal(out.Capabilities.Graphics, in.Capabilities.Graphics) ||
+		out.Capabilities.Columns != 80 || out.Capabilities.Rows != 24 || out.Capabilities.ColorDepth != 24 ||
+		out.Capabilities.Background != BackgroundDark {
+		t.Errorf("ReadHello() = %+v, want %+v", out, in)
+	}
+	if !out.Capabilities.SupportsGraphics("iterm2") || out.Capabilities.SupportsGraphics("kitty") {
+		t.Error("SupportsGraphics() returned unexpected results")
+	}
+}
+
+func TestReadHello_WrongType(t *testing.T) {
+	var buf bytes.Buffer
+	WriteFrame(&buf, Frame{Version: ProtocolVersion, Type: FrameMessage})
+
+	_, err := ReadHello(&buf)
+	if !errors.Is(err, ErrProtocol) {
+		t.Errorf("ReadHello() error = %v, want ErrProtocol", err)
+	}
+}
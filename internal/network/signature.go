@@ -0,0 +1,118 @@
+package network
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// signatureContext prefixes the signed data so that signatures cannot be
+// reused for anything but MOTD messages.
+const signatureContext = "motd message signature v1\x00"
+
+// Errors reported when a message fails signature verification. ErrUnsigned
+// and ErrBadSignature also match ErrSignature.
+var (
+	ErrSignature    = errors.New("signature verification failed")
+	ErrUnsigned     = fmt.Errorf("%w: message is not signed", ErrSignature)
+	ErrBadSignature = fmt.Errorf("%w: no trusted key matches", ErrSignature)
+)
+
+// Sign attaches a detached Ed25519 signature covering the message's content
+// type, metadata and body. The metadata must not change afterwards.
+func (m *Message) Sign(key ed25519.PrivateKey) error {
+	m.rawMetadata = nil
+	if !m.Metadata.IsZero() {
+		raw, err := json.Marshal(m.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		m.rawMetadata = raw
+	}
+	m.declaredType = m.ContentType
+	m.Signature = ed25519.Sign(key, m.signedData())
+	return nil
+}
+
+// Verify checks the message's signature against the trusted keys. It
+// returns ErrUnsigned if the message carries no signature and
+// ErrBadSignature if none of the keys produced it.
+func (m *Message) Verify(keys []ed25519.PublicKey) error {
+	if len(m.Signature) == 0 {
+		return ErrUnsigned
+	}
+
+	data := m.signedData()
+	for _, key := range keys {
+		if ed25519.Verify(key, data, m.Signature) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+// SignedFields returns the content type and metadata exactly as sent, which
+// the signature covers along with the body, so that a stored message can
+// be verified again.
+func (m *Message) SignedFields() (declaredType string, rawMetadata []byte) {
+	return m.declaredType, m.rawMetadata
+}
+
+// SetSignedFields restores the fields returned by SignedFields to a stored
+// message.
+func (m *Message) SetSignedFields(declaredType string, rawMetadata []byte) {
+	m.declaredType, m.rawMetadata = declaredType, rawMetadata
+}
+
+// signedData returns the bytes covered by the signature: the content type
+// and metadata exactly as sent, and the body, each length-prefixed.
+func (m *Message) signedData() []byte {
+	buf := make([]byte, 0, len(signatureContext)+12+len(m.declaredType)+len(m.rawMetadata)+len(m.Body))
+	buf = append(buf, signatureContext...)
+	for _, field := range [][]byte{[]byte(m.declaredType), m.rawMetadata, m.Body} {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(field)))
+		buf = append(buf, field...)
+	}
+	return buf
+}
+
+// ParsePublicKey decodes a base64 encoded 32 byte Ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 Ed25519 private key, as
+// written by "openssl genpkey -algorithm ed25519".
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an Ed25519 key", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,211 @@
+package network
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newKey generates an Ed25519 key pair for tests.
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return pub, priv
+}
+
+// signedMessage returns the wire encoding of a message signed with key.
+func signedMessage(t *testing.T, key ed25519.PrivateKey, msg *Message) []byte {
+	t.Helper()
+	if err := msg.Sign(key); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, ProtocolVersion, msg, WriteOptions{Checksum: true}); err != nil {
+		t.Fatalf("WriteMessage() unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMessage_Verify(t *testing.T) {
+	pub, priv := newKey(t)
+	other, _ := newKey(t)
+
+	tests := []struct {
+		name   string
+		keys   []ed25519.PublicKey
+		tamper func(data []byte) []byte
+		want   error
+	}{
+		{name: "trusted key", keys: []ed25519.PublicKey{pub}, want: nil},
+		{name: "one of several keys", keys: []ed25519.PublicKey{other, pub}, want: nil},
+		{name: "untrusted key", keys: []ed25519.PublicKey{other}, want: ErrBadSignature},
+		{
+			name: "tampered body",
+			keys: []ed25519.PublicKey{pub},
+			tamper: func(data []byte) []byte {
+				return bytes.Replace(data, []byte("Deploy"), []byte("Deplot"), 1)
+			},
+			want: ErrBadSignature,
+		},
+		{
+			name: "tampered metadata",
+			keys: []ed25519.PublicKey{pub},
+			tamper: func(data []byte) []byte {
+				return bytes.Replace(data, []byte("example.com"), []byte("exbmple.com"), 1)
+			},
+			want: ErrBadSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := signedMessage(t, priv, &Message{
+				ContentType: ContentTypeText,
+				Metadata:    Metadata{URL: "https://example.com"},
+				Body:        []byte("Deploy freeze tonight"),
+			})
+			if tt.tamper != nil {
+				// Tampering would also break the checksums, so drop them
+				// to reach signature verification.
+				data = tt.tamper(unchecked(t, data))
+			}
+
+			msg, _, err := readMessage(bytes.NewReader(data), MaxFrameSize)
+			if err != nil {
+				t.Fatalf("readMessage() unexpected error: %v", err)
+			}
+			if err := msg.Verify(tt.keys); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// unchecked re-encodes the frames in data without checksums.
+func unchecked(t *testing.T, data []byte) []byte {
+	t.Helper()
+	r := bytes.NewReader(data)
+	var buf bytes.Buffer
+	for r.Len() > 0 {
+		f, err := ReadFrame(r, MaxFrameSize)
+		if err != nil {
+			t.Fatalf("ReadFrame() unexpected error: %v", err)
+		}
+		f.Checksum = false
+		WriteFrame(&buf, f)
+	}
+	return buf.Bytes()
+}
+
+func TestMessage_Verify_Unsigned(t *testing.T) {
+	pub, _ := newKey(t)
+	msg := &Message{ContentType: ContentTypeText, Body: []byte("Hello")}
+
+	if err := msg.Verify([]ed25519.PublicKey{pub}); !errors.Is(err, ErrUnsigned) || !errors.Is(err, ErrSignature) {
+		t.Errorf("Verify() error = %v, want ErrUnsigned", err)
+	}
+}
+
+func TestMessage_Sign_SniffedContentType(t *testing.T) {
+	pub, priv := newKey(t)
+	data := signedMessage(t, priv, &Message{
+		Metadata: Metadata{Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Body:     []byte("\033[1mBold\033[0m"),
+	})
+
+	msg, _, err := readMessage(bytes.NewReader(data), MaxFrameSize)
+	if err != nil {
+		t.Fatalf("readMessage() unexpected error: %v", err)
+	}
+	if msg.ContentType != ContentTypeANSI {
+		t.Errorf("ContentType = %q, want %q", msg.ContentType, ContentTypeANSI)
+	}
+	if err := msg.Verify([]ed25519.PublicKey{pub}); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _ := newKey(t)
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "valid", input: base64.StdEncoding.EncodeToString(pub), wantErr: false},
+		{name: "not base64", input: "not a key!", wantErr: true},
+		{name: "wrong length", input: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePublicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !key.Equal(pub) {
+				t.Error("ParsePublicKey() returned a different key")
+			}
+		})
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	_, priv := newKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+
+	writeKey := func(key any) string {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Failed to marshal key: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "key.pem")
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("Failed to write key: %v", err)
+		}
+		return path
+	}
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	os.WriteFile(notPEM, []byte("garbage"), 0o600)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "ed25519", path: writeKey(priv), wantErr: false},
+		{name: "ecdsa", path: writeKey(ecKey), wantErr: true},
+		{name: "not pem", path: notPEM, wantErr: true},
+		{name: "missing", path: filepath.Join(t.TempDir(), "missing.pem"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadPrivateKey(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadPrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !key.Equal(priv) {
+				t.Error("LoadPrivateKey() returned a different key")
+			}
+		})
+	}
+}
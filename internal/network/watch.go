@@ -0,0 +1,100 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Subscriber is implemented by clients that can keep a connection open and
+// receive each new message as the server publishes it. Client implements
+// it; HTTPClient does not, as it holds its Client in a field rather than
+// embedding it, and has to be polled.
+type Subscriber interface {
+	Watch(ctx context.Context, conn net.Conn, hello Hello, fn func(*Message) error) error
+}
+
+// Watch asks the server to keep the connection open and calls fn with every
+// message it sends, the first one immediately. It returns nil when the
+// server closes the connection between messages, as servers that do not
+// support watching do after the first one, and ctx.Err() once ctx is
+// canceled. Errors returned by fn stop the watch and are returned as is.
+func (c *Client) Watch(ctx context.Context, conn net.Conn, hello Hello, fn func(*Message) error) error {
+	// Unblock reads when ctx is canceled.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// The handshake and first message must arrive within the timeout.
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	if c.protocol == ProtocolLegacy {
+		return c.watchLegacy(ctx, conn, fn)
+	}
+	r, greeted, err := c.detect(conn)
+	if err != nil {
+		return canceled(ctx, err)
+	}
+	if !greeted {
+		return c.watchLegacy(ctx, r, fn)
+	}
+
+	hello.Watch = true
+	if err := c.handshake(r, conn, hello); err != nil {
+		return canceled(ctx, err)
+	}
+
+	for first := true; ; first = false {
+		if !first {
+			// Later messages arrive whenever the server publishes them.
+			if err := conn.SetDeadline(time.Time{}); err != nil {
+				return fmt.Errorf("failed to clear deadline: %w", err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if _, err := r.Peek(1); err != nil {
+				if errors.Is(err, io.EOF) {
+					slog.Debug("Server closed the watch")
+					return nil
+				}
+				return canceled(ctx, fmt.Errorf("failed to read from connection: %w", err))
+			}
+			// Once a message starts it must arrive within the timeout.
+			if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+				return fmt.Errorf("failed to set deadline: %w", err)
+			}
+		}
+
+		msg, err := c.receive(r)
+		if err != nil {
+			return canceled(ctx, err)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// watchLegacy reads the single message a legacy server sends.
+func (c *Client) watchLegacy(ctx context.Context, r io.Reader, fn func(*Message) error) error {
+	msg, err := c.readLegacy(r)
+	if err != nil {
+		return canceled(ctx, err)
+	}
+	return fn(msg)
+}
+
+// canceled returns ctx.Err() instead of err if the read failed because ctx
+// was canceled.
+func canceled(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/motdtest"
+)
+
+// watch connects client to srv and collects the bodies passed to fn until
+// Watch returns.
+func watch(t *testing.T, ctx context.Context, srv *motdtest.Server, fn func(*Message) error) ([]string, error) {
+	t.Helper()
+
+	client := NewClient(srv.Host(), srv.Port(), time.Second)
+	conn, err := client.Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect to test server: %v", err)
+	}
+	defer conn.Close()
+
+	var bodies []string
+	err = client.Watch(ctx, conn, Hello{}, func(msg *Message) error {
+		bodies = append(bodies, string(msg.Body))
+		if fn != nil {
+			return fn(msg)
+		}
+		return nil
+	})
+	return bodies, err
+}
+
+func TestClient_Watch(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior motdtest.Behavior
+		want     []string
+	}{
+		{
+			name:     "stream",
+			behavior: motdtest.Stream(10*time.Millisecond, []byte("one"), []byte("two"), []byte("three")),
+			want:     []string{"one", "two", "three"},
+		},
+		{
+			name:     "versioned server without watch support",
+			behavior: motdtest.Versioned([]byte("only")),
+			want:     []string{"only"},
+		},
+		{
+			name:     "legacy server",
+			behavior: motdtest.PayloadString("legacy"),
+			want:     []string{"legacy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := motdtest.NewServer(tt.behavior)
+			defer srv.Close()
+
+			got, err := watch(t, context.Background(), srv, nil)
+			if err != nil {
+				t.Fatalf("Watch() unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Watch() messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Watch_Canceled(t *testing.T) {
+	srv := motdtest.NewServer(motdtest.Stream(time.Minute, []byte("one"), []byte("two")))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	got, err := watch(t, ctx, srv, func(*Message) error {
+		time.AfterFunc(20*time.Millisecond, cancel)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch() error = %v, want context.Canceled", err)
+	}
+	if !slices.Equal(got, []string{"one"}) {
+		t.Errorf("Watch() messages = %q, want only the first", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Watch() took %v to notice cancellation", elapsed)
+	}
+}
+
+func TestClient_Watch_HandlerError(t *testing.T) {
+	srv := motdtest.NewServer(motdtest.Stream(10*time.Millisecond, []byte("one"), []byte("two")))
+	defer srv.Close()
+
+	stop := errors.New("stop")
+	got, err := watch(t, context.Background(), srv, func(*Message) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Watch() error = %v, want the handler's error", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("Watch() delivered %d messages, want 1", len(got))
+	}
+}
+
+func TestClient_Watch_Truncated(t *testing.T) {
+	frame := motdtest.EncodeFrame(motdtest.FrameMessage, motdtest.Response{ContentType: "text/plain", Body: []byte("one")})
+	srv := motdtest.NewServer(motdtest.VersionedRaw(func(motdtest.Hello) []byte {
+		return append(frame, frame[:6]...)
+	}))
+	defer srv.Close()
+
+	got, err := watch(t, context.Background(), srv, nil)
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("Watch() error = %v, want ErrTruncated", err)
+	}
+	if !slices.Equal(got, []string{"one"}) {
+		t.Errorf("Watch() messages = %q, want only the complete one", got)
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// authenticator checks client tokens against shared secrets and rejects
+// nonces it has already seen.
+type authenticator struct {
+	keys map[string][]byte
+	now  func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time // Nonce to the time it can be forgotten
+}
+
+// newAuthenticator creates an authenticator for the given key IDs and
+// secrets.
+func newAuthenticator(keys map[string][]byte) *authenticator {
+	return &authenticator{
+		keys: keys,
+		now:  time.Now,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// verify accepts a token signed with a known key, within the allowed clock
+// skew, whose nonce has not been used before.
+func (a *authenticator) verify(token *network.AuthToken) error {
+	if token == nil {
+		return fmt.Errorf("%w: no token", network.ErrUnauthorized)
+	}
+	secret, ok := a.keys[token.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", network.ErrUnauthorized, token.KeyID)
+	}
+
+	now := a.now()
+	if err := token.Verify(secret, now); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for nonce, expiry := range a.seen {
+		if now.After(expiry) {
+			delete(a.seen, nonce)
+		}
+	}
+	if _, ok := a.seen[token.Nonce]; ok {
+		return fmt.Errorf("%w: replayed nonce", network.ErrUnauthorized)
+	}
+	a.seen[token.Nonce] = now.Add(2 * network.AuthMaxSkew)
+	return nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestAuthenticator_Verify(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	secret := []byte("s3cret")
+
+	a := newAuthenticator(map[string][]byte{"ops": secret})
+	a.now = func() time.Time { return now }
+
+	token, err := network.NewAuthToken("ops", secret, now)
+	if err != nil {
+		t.Fatalf("NewAuthToken() unexpected error: %v", err)
+	}
+	unknown, _ := network.NewAuthToken("dev", secret, now)
+	stale, _ := network.NewAuthToken("ops", secret, now.Add(-time.Hour))
+
+	tests := []struct {
+		name  string
+		token *network.AuthToken
+		want  error
+	}{
+		{name: "valid", token: token, want: nil},
+		{name: "replayed", token: token, want: network.ErrUnauthorized},
+		{name: "missing", token: nil, want: network.ErrUnauthorized},
+		{name: "unknown key", token: unknown, want: network.ErrUnauthorized},
+		{name: "stale", token: stale, want: network.ErrUnauthorized},
+	}
+
+	// The cases share the authenticator, so they run in order.
+	for _, tt := range tests {
+		if err := a.verify(tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("%s: verify() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAuthenticator_ForgetsOldNonces(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	secret := []byte("s3cret")
+
+	a := newAuthenticator(map[string][]byte{"ops": secret})
+	a.now = func() time.Time { return now }
+
+	token, _ := network.NewAuthToken("ops", secret, now)
+	if err := a.verify(token); err != nil {
+		t.Fatalf("verify() unexpected error: %v", err)
+	}
+
+	now = now.Add(3 * network.AuthMaxSkew)
+	fresh, _ := network.NewAuthToken("ops", secret, now)
+	if err := a.verify(fresh); err != nil {
+		t.Fatalf("verify() unexpected error: %v", err)
+	}
+	if len(a.seen) != 1 {
+		t.Errorf("Remembered %d nonces, want 1", len(a.seen))
+	}
+}
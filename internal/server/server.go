@@ -0,0 +1,330 @@
+// Package server implements a reference MOTD server that serves messages
+// from a directory of files over the TCP protocol read by network.Client.
+package server
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Protocols spoken by the server.
+const (
+	// ProtocolV1 greets clients and answers their hello with a framed message.
+	ProtocolV1 = network.ProtocolV1
+	// ProtocolLegacy writes the message immediately and closes the connection.
+	ProtocolLegacy = network.ProtocolLegacy
+)
+
+// compressThreshold is the smallest message compressed for clients that
+// accept gzip; smaller ones do not benefit.
+const compressThreshold = 1024
+
+// Protocols lists the accepted server protocol names.
+var Protocols = []string{ProtocolV1, ProtocolLegacy}
+
+// DefaultWatchInterval is how often the store is checked for a new message
+// on behalf of watching clients.
+const DefaultWatchInterval = time.Minute
+
+// Server accepts connections and answers each one with a single message
+// before closing it, unless the client asks to watch.
+type Server struct {
+	store         *Store
+	timeout       time.Duration
+	protocol      string
+	key           ed25519.PrivateKey
+	auth          *authenticator
+	watchInterval time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+	done     chan struct{} // Closed by Close to end watches
+	conns    sync.WaitGroup
+}
+
+// Option configures optional Server behavior.
+type Option func(*Server)
+
+// WithProtocol selects the protocol: ProtocolV1 (the default) or
+// ProtocolLegacy for clients that predate the handshake.
+func WithProtocol(protocol string) Option {
+	return func(s *Server) {
+		s.protocol = protocol
+	}
+}
+
+// WithSigningKey signs every v1 message with key so that clients can verify
+// it with the matching public key.
+func WithSigningKey(key ed25519.PrivateKey) Option {
+	return func(s *Server) {
+		s.key = key
+	}
+}
+
+// WithAuthKeys requires v1 clients to authenticate with one of the shared
+// secrets in keys, indexed by key ID. Other clients receive an error frame.
+func WithAuthKeys(keys map[string][]byte) Option {
+	return func(s *Server) {
+		s.auth = newAuthenticator(keys)
+	}
+}
+
+// WithWatchInterval sets how often the store is checked for a new message
+// for clients that watch. Zero answers watching clients like any other.
+func WithWatchInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.watchInterval = interval
+	}
+}
+
+// New creates a server that picks messages from store. Each exchange with
+// a client must complete within timeout.
+func New(store *Store, timeout time.Duration, opts ...Option) *Server {
+	s := &Server{
+		store:         store,
+		timeout:       timeout,
+		protocol:      ProtocolV1,
+		watchInterval: DefaultWatchInterval,
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListenAndServe listens on the TCP address addr and serves connections
+// until Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen failed: %w", err)
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener until Close is called. It always
+// returns a non-nil error; after Close it returns net.ErrClosed.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	if s.closed {
+		listener.Close()
+	}
+	s.mu.Unlock()
+
+	slog.Info("MOTD server listening", "address", listener.Addr().String(), "dir", s.store.Dir())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				s.conns.Wait()
+			}
+			return err
+		}
+
+		s.conns.Add(1)
+		go func() {
+			defer s.conns.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Addr returns the address the server is listening on, or nil before Serve.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections and ends watches. In-flight responses
+// are completed. Calling Close before Serve makes Serve return immediately.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		close(s.done)
+	}
+	s.closed = true
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// handle answers one client and closes the connection.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	remote := conn.RemoteAddr().String()
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		slog.Error("Failed to set deadline", "remote", remote, "error", err)
+		return
+	}
+
+	var err error
+	if s.protocol == ProtocolLegacy {
+		err = s.handleLegacy(conn)
+	} else {
+		err = s.handleV1(conn)
+	}
+	if err != nil {
+		slog.Warn("Failed to serve client", "remote", remote, "error", err)
+	}
+}
+
+// handleLegacy writes the message and lets the caller close the connection.
+func (s *Server) handleLegacy(conn net.Conn) error {
+	msg, err := s.store.Next()
+	if err != nil {
+		return fmt.Errorf("failed to select message: %w", err)
+	}
+
+	if _, err := conn.Write(msg.Payload); err != nil {
+		return fmt.Errorf("failed to send %s: %w", msg.Name, err)
+	}
+
+	slog.Debug("Message sent", "remote", conn.RemoteAddr().String(), "file", msg.Name, "length", len(msg.Payload))
+	return nil
+}
+
+// handleV1 greets the client, reads its hello and replies with a message
+// suited to its capabilities.
+func (s *Server) handleV1(conn net.Conn) error {
+	greeting := network.Frame{Version: network.ProtocolVersion, Type: network.FrameGreeting}
+	if err := network.WriteFrame(conn, greeting); err != nil {
+		return err
+	}
+
+	hello, err := network.ReadHello(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read hello: %w", err)
+	}
+
+	slog.Debug("Client hello received",
+		"remote", conn.RemoteAddr().String(),
+		"client_version", hello.ClientVersion,
+		"graphics", hello.Capabilities.Graphics,
+		"columns", hello.Capabilities.Columns,
+		"rows", hello.Capabilities.Rows,
+		"color_depth", hello.Capabilities.ColorDepth,
+		"background", hello.Capabilities.Background,
+		"encodings", hello.Encodings)
+
+	if s.auth != nil {
+		if err := s.auth.verify(hello.Auth); err != nil {
+			reject := network.Frame{
+				Version:     network.ProtocolVersion,
+				Type:        network.FrameError,
+				ContentType: network.ContentTypeText,
+				Payload:     []byte("authentication required"),
+			}
+			if werr := network.WriteFrame(conn, reject); werr != nil {
+				return werr
+			}
+			return err
+		}
+		slog.Debug("Client authenticated", "remote", conn.RemoteAddr().String(), "key_id", hello.Auth.KeyID)
+	}
+
+	msg, err := s.store.NextFor(hello.Capabilities)
+	if err != nil {
+		return fmt.Errorf("failed to select message: %w", err)
+	}
+	if err := s.send(conn, hello, msg); err != nil || !hello.Watch || s.watchInterval <= 0 {
+		return err
+	}
+	return s.watch(conn, hello, msg)
+}
+
+// send writes msg in the form the client's hello asks for.
+func (s *Server) send(conn net.Conn, hello network.Hello, msg *Message) error {
+	reply := &network.Message{
+		ContentType: msg.ContentType,
+		Metadata:    network.Metadata{ID: msg.Name},
+		Body:        msg.Payload,
+	}
+	if s.key != nil {
+		if err := reply.Sign(s.key); err != nil {
+			return fmt.Errorf("failed to sign %s: %w", msg.Name, err)
+		}
+	}
+	opts := network.WriteOptions{Checksum: true}
+	if len(reply.Body) >= compressThreshold && slices.Contains(hello.Encodings, network.EncodingGzip) {
+		opts.Encoding = network.EncodingGzip
+	}
+	if err := network.WriteMessage(conn, network.ProtocolVersion, reply, opts); err != nil {
+		return fmt.Errorf("failed to send %s: %w", msg.Name, err)
+	}
+
+	slog.Debug("Message sent",
+		"remote", conn.RemoteAddr().String(),
+		"file", msg.Name,
+		"length", len(msg.Payload),
+		"encoding", opts.Encoding)
+	return nil
+}
+
+// watch keeps the connection open, checking the store every watch interval
+// and sending the selected message whenever it differs from the last one,
+// until the client disconnects or the server is closed.
+func (s *Server) watch(conn net.Conn, hello network.Hello, last *Message) error {
+	remote := conn.RemoteAddr().String()
+	slog.Debug("Client is watching", "remote", remote, "interval", s.watchInterval)
+
+	// Clients send nothing more, so a read returns once they disconnect.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("failed to clear deadline: %w", err)
+	}
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-gone:
+			slog.Debug("Watching client disconnected", "remote", remote)
+			return nil
+		case <-s.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		msg, err := s.store.NextFor(hello.Capabilities)
+		if err != nil {
+			return fmt.Errorf("failed to select message: %w", err)
+		}
+		if msg.Name == last.Name && bytes.Equal(msg.Payload, last.Payload) {
+			continue
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+			return fmt.Errorf("failed to set deadline: %w", err)
+		}
+		if err := s.send(conn, hello, msg); err != nil {
+			return err
+		}
+		last = msg
+	}
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// startServer serves store on a random local port until the test ends and
+// returns the port.
+func startServer(t *testing.T, store *Store, opts ...Option) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := New(store, time.Second, opts...)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(listener) }()
+
+	t.Cleanup(func() {
+		srv.Close()
+		if err := <-done; !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Serve() returned %v, want net.ErrClosed", err)
+		}
+	})
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestServer_ServesMessages(t *testing.T) {
+	store, err := NewStore(writeFiles(t, map[string]string{"a.txt": "First", "b.txt": "Second"}), SelectSequential)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	port := startServer(t, store)
+
+	client := network.NewClient("localhost", port, time.Second)
+	for _, want := range []string{"First", "Second"} {
+		conn, err := client.Connect()
+		if err != nil {
+			t.Fatalf("Connect() unexpected error: %v", err)
+		}
+		message, err := client.FetchMessage(conn, network.Hello{})
+		conn.Close()
+		if err != nil {
+			t.Fatalf("FetchMessage() unexpected error: %v", err)
+		}
+		if string(message.Body) != want {
+			t.Errorf("FetchMessage() = %q, want %q", message.Body, want)
+		}
+		if message.Metadata.ID == "" {
+			t.Error("Expected the message ID to be set from the file name")
+		}
+	}
+}
+
+func TestServer_NoMessagesClosesConnection(t *testing.T) {
+	store, err := NewStore(t.TempDir(), SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	port := startServer(t, store)
+
+	client := network.NewClient("localhost", port, time.Second)
+	conn, err := client.Connect()
+	if err != nil {
+		t.Fatalf("Connect() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = client.FetchMessage(conn, network.Hello{})
+	if err == nil {
+		t.Error("Expected an error when the server has no message to send")
+	}
+}
+
+func TestServer_Legacy(t *testing.T) {
+	store, err := NewStore(writeFiles(t, map[string]string{"a.txt": "Legacy"}), SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	port := startServer(t, store, WithProtocol(ProtocolLegacy))
+
+	client := network.NewClient("localhost", port, time.Second, network.WithProtocol(network.ProtocolLegacy))
+	conn, err := client.Connect()
+	if err != nil {
+		t.Fatalf("Connect() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	message, err := client.FetchMessage(conn, network.Hello{})
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(message.Body) != "Legacy" {
+		t.Errorf("FetchMessage() = %q, want %q", message.Body, "Legacy")
+	}
+}
+
+func TestServer_TailorsToCapabilities(t *testing.T) {
+	files := map[string]string{"a.png": "image", "b.txt": "text"}
+
+	tests := []struct {
+		name      string
+		caps      network.Capabilities
+		wantImage bool
+	}{
+		{name: "no graphics", caps: network.Capabilities{}, wantImage: false},
+		{name: "iterm2", caps: network.Capabilities{Graphics: []string{"iterm2"}}, wantImage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(writeFiles(t, files), SelectSequential)
+			if err != nil {
+				t.Fatalf("NewStore() unexpected error: %v", err)
+			}
+			port := startServer(t, store)
+
+			client := network.NewClient("localhost", port, time.Second, network.WithProtocol(network.ProtocolV1))
+			conn, err := client.Connect()
+			if err != nil {
+				t.Fatalf("Connect() unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			message, err := client.FetchMessage(conn, network.Hello{Capabilities: tt.caps})
+			if err != nil {
+				t.Fatalf("FetchMessage() unexpected error: %v", err)
+			}
+			if isImage := strings.HasPrefix(string(message.Body), "1337;File="); isImage != tt.wantImage {
+				t.Errorf("FetchMessage() = %q, wantImage %v", message.Body, tt.wantImage)
+			}
+			wantType := network.ContentTypeText
+			if tt.wantImage {
+				wantType = network.ContentTypeOSC
+			}
+			if message.ContentType != wantType {
+				t.Errorf("FetchMessage() content type = %q, want %q", message.ContentType, wantType)
+			}
+		})
+	}
+}
+
+func TestServer_CloseBeforeServe(t *testing.T) {
+	store, err := NewStore(t.TempDir(), SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+
+	srv := New(store, time.Second)
+	if srv.Addr() != nil {
+		t.Error("Expected nil address before Serve")
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("Close() unexpected error: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	if err := srv.Serve(listener); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Serve() after Close returned %v, want net.ErrClosed", err)
+	}
+}
+
+func TestServer_SignsMessages(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	store, err := NewStore(writeFiles(t, map[string]string{"a.txt": "Signed"}), SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	port := startServer(t, store, WithSigningKey(priv))
+
+	client := network.NewClient("localhost", port, time.Second,
+		network.WithTrustedKeys([]ed25519.PublicKey{pub}))
+	conn, err := client.Connect()
+	if err != nil {
+		t.Fatalf("Connect() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	message, err := client.FetchMessage(conn, network.Hello{})
+	if err != nil {
+		t.Fatalf("FetchMessage() unexpected error: %v", err)
+	}
+	if string(message.Body) != "Signed" {
+		t.Errorf("FetchMessage() = %q, want %q", message.Body, "Signed")
+	}
+}
+
+func TestServer_RequiresAuth(t *testing.T) {
+	store, err := NewStore(writeFiles(t, map[string]string{"a.txt": "Internal"}), SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	port := startServer(t, store, WithAuthKeys(map[string][]byte{"ops": []byte("s3cret")}))
+
+	tests := []struct {
+		name string
+		opts []network.Option
+		want error
+	}{
+		{name: "authenticated", opts: []network.Option{network.WithAuth("ops", []byte("s3cret"))}, want: nil},
+		{name: "wrong secret", opts: []network.Option{network.WithAuth("ops", []byte("guess"))}, want: network.ErrRejected},
+		{name: "anonymous", want: network.ErrRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := network.NewClient("localhost", port, time.Second, tt.opts...)
+			conn, err := client.Connect()
+			if err != nil {
+				t.Fatalf("Connect() unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			message, err := client.FetchMessage(conn, network.Hello{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("FetchMessage() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && string(message.Body) != "Internal" {
+				t.Errorf("FetchMessage() = %q, want %q", message.Body, "Internal")
+			}
+		})
+	}
+}
+
+func TestServer_CompressesLargeMessages(t *testing.T) {
+	large := strings.Repeat("Large MOTD ", 200)
+	store, err := NewStore(writeFiles(t, map[string]string{"large.txt": large, "small.txt": "Small"}), SelectSequential)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	port := startServer(t, store)
+
+	tests := []struct {
+		name      string
+		encodings []string
+		want      string
+		wantGzip  bool
+	}{
+		{name: "large with gzip", encodings: []string{network.EncodingGzip}, want: large, wantGzip: true},
+		{name: "small with gzip", encodings: []string{network.EncodingGzip}, want: "Small", wantGzip: false},
+		{name: "large without gzip", encodings: []string{network.EncodingDeflate}, want: large, wantGzip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+			if err != nil {
+				t.Fatalf("Dial() unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			if _, err := network.ReadFrame(conn, network.MaxFrameSize); err != nil {
+				t.Fatalf("Failed to read greeting: %v", err)
+			}
+			hello, _ := json.Marshal(network.Hello{Encodings: tt.encodings})
+			err = network.WriteFrame(conn, network.Frame{Version: network.ProtocolVersion, Type: network.FrameHello, Payload: hello})
+			if err != nil {
+				t.Fatalf("Failed to send hello: %v", err)
+			}
+
+			f, err := network.ReadFrame(conn, network.MaxFrameSize)
+			for err == nil && f.Type != network.FrameMessage {
+				f, err = network.ReadFrame(conn, network.MaxFrameSize)
+			}
+			if err != nil {
+				t.Fatalf("Failed to read message: %v", err)
+			}
+			if gzipped := f.Encoding == network.EncodingGzip; gzipped != tt.wantGzip {
+				t.Errorf("Encoding = %q, want gzip %v", f.Encoding, tt.wantGzip)
+			}
+			body, err := network.DecodePayload(f, network.MaxFrameSize)
+			if err != nil || string(body) != tt.want {
+				t.Errorf("DecodePayload() returned %d bytes, %v, want %d bytes", len(body), err, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestServer_Watch(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{name: "new messages are sent", files: map[string]string{"a.txt": "First", "b.txt": "Second"}, want: []string{"First", "Second", "First"}},
+		{name: "unchanged message is not resent", files: map[string]string{"a.txt": "Only"}, want: []string{"Only"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(writeFiles(t, tt.files), SelectSequential)
+			if err != nil {
+				t.Fatalf("NewStore() unexpected error: %v", err)
+			}
+			port := startServer(t, store, WithWatchInterval(20*time.Millisecond))
+
+			client := network.NewClient("localhost", port, time.Second)
+			conn, err := client.Connect()
+			if err != nil {
+				t.Fatalf("Connect() unexpected error: %v", err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			var got []string
+			err = client.Watch(ctx, conn, network.Hello{}, func(msg *network.Message) error {
+				got = append(got, string(msg.Body))
+				if len(got) == len(tt.want) && len(got) > 1 {
+					cancel()
+				}
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Watch() error = %v, want it to run until canceled", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Watch() messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_CloseEndsWatch(t *testing.T) {
+	store, err := NewStore(writeFiles(t, map[string]string{"a.txt": "Only"}), SelectSequential)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := New(store, time.Second, WithWatchInterval(time.Hour))
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(listener) }()
+
+	client := network.NewClient("localhost", listener.Addr().(*net.TCPAddr).Port, time.Second)
+	conn, err := client.Connect()
+	if err != nil {
+		t.Fatalf("Connect() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	watched := make(chan error, 1)
+	go func() {
+		watched <- client.Watch(context.Background(), conn, network.Hello{}, func(*network.Message) error {
+			srv.Close()
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-watched:
+		if err != nil {
+			t.Errorf("Watch() error = %v, want nil when the server closes", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() did not return after the server closed")
+	}
+	if err := <-done; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Serve() returned %v, want net.ErrClosed", err)
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/terminal"
+)
+
+// Selection strategies for choosing the next message.
+const (
+	SelectRandom     = "random"
+	SelectSequential = "sequential"
+	SelectDate       = "date"
+)
+
+// Selections lists the accepted selection strategy names.
+var Selections = []string{SelectRandom, SelectSequential, SelectDate}
+
+// ErrNoMessages is returned when the directory holds no servable files.
+var ErrNoMessages = errors.New("no messages available")
+
+// imageExtensions are sent as iTerm2 inline images; every other file is
+// sent verbatim.
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif"}
+
+// markdownExtensions are sent as Markdown; other text files are sent with
+// the content type sniffed from their contents.
+var markdownExtensions = []string{".md", ".markdown"}
+
+// Message is a single encoded MOTD ready to be written to a client.
+type Message struct {
+	Name        string // File name the message was read from
+	ContentType string // Content type announced in v1 frames
+	Payload     []byte // Bytes sent on the wire
+}
+
+// Store selects messages from a directory. The directory is re-read on
+// every selection so files can be added or removed while serving.
+type Store struct {
+	dir       string
+	selection string
+
+	mu   sync.Mutex
+	next int
+
+	// now and intN are replaceable for tests.
+	now  func() time.Time
+	intN func(n int) int
+}
+
+// NewStore creates a store for dir using the named selection strategy.
+func NewStore(dir, selection string) (*Store, error) {
+	if !slices.Contains(Selections, selection) {
+		return nil, fmt.Errorf("unknown selection %q, must be one of %s", selection, strings.Join(Selections, ", "))
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("message path %s is not a directory", dir)
+	}
+
+	return &Store{
+		dir:       dir,
+		selection: selection,
+		now:       time.Now,
+		intN:      rand.IntN,
+	}, nil
+}
+
+// Dir returns the directory messages are read from.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Next selects and encodes the next message.
+func (s *Store) Next() (*Message, error) {
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	return s.selectFrom(names)
+}
+
+// NextFor selects the next message the client can display. Images are
+// skipped for terminals without iTerm2 inline image support unless there
+// is nothing else to send.
+func (s *Store) NextFor(caps network.Capabilities) (*Message, error) {
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+
+	if !caps.SupportsGraphics(terminal.GraphicsITerm2) {
+		text := slices.DeleteFunc(slices.Clone(names), isImage)
+		if len(text) > 0 {
+			names = text
+		}
+	}
+	return s.selectFrom(names)
+}
+
+// selectFrom picks and loads one of names.
+func (s *Store) selectFrom(names []string) (*Message, error) {
+	if len(names) == 0 {
+		return nil, ErrNoMessages
+	}
+
+	name := s.pick(names)
+	if name == "" {
+		return nil, ErrNoMessages
+	}
+	return s.load(name)
+}
+
+// list returns the sorted names of regular, non-hidden files in the directory.
+func (s *Store) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// pick chooses one of names according to the selection strategy.
+func (s *Store) pick(names []string) string {
+	switch s.selection {
+	case SelectSequential:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		name := names[s.next%len(names)]
+		s.next++
+		return name
+	case SelectDate:
+		return s.pickScheduled(names)
+	default:
+		return names[s.intN(len(names))]
+	}
+}
+
+// datePrefix matches file names scheduled for a date: YYYY-MM-DD for a
+// single day or MM-DD for every year.
+var datePrefix = regexp.MustCompile(`^(\d{4}-)?\d{2}-\d{2}`)
+
+// pickScheduled prefers files scheduled for today's exact date, then for
+// today's month and day, and otherwise picks randomly among undated files.
+// It returns "" if nothing is eligible today.
+func (s *Store) pickScheduled(names []string) string {
+	today := s.now()
+	exact := today.Format("2006-01-02")
+	annual := today.Format("01-02")
+
+	var exactNames, annualNames, undated []string
+	for _, name := range names {
+		switch {
+		case strings.HasPrefix(name, exact):
+			exactNames = append(exactNames, name)
+		case strings.HasPrefix(name, annual):
+			annualNames = append(annualNames, name)
+		case !datePrefix.MatchString(name):
+			undated = append(undated, name)
+		}
+	}
+
+	for _, candidates := range [][]string{exactNames, annualNames, undated} {
+		if len(candidates) > 0 {
+			return candidates[s.intN(len(candidates))]
+		}
+	}
+	return ""
+}
+
+// load reads and encodes the named file.
+func (s *Store) load(name string) (*Message, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	var contentType string
+	switch {
+	case isImage(name):
+		data = encodeInlineImage(name, data)
+		contentType = network.ContentTypeOSC
+	case isMarkdown(name):
+		contentType = network.ContentTypeMarkdown
+	default:
+		contentType = network.Sniff(data)
+	}
+	return &Message{Name: name, ContentType: contentType, Payload: data}, nil
+}
+
+// isMarkdown reports whether name is sent as Markdown.
+func isMarkdown(name string) bool {
+	return slices.Contains(markdownExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// isImage reports whether name is sent as an inline image.
+func isImage(name string) bool {
+	return slices.Contains(imageExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// encodeInlineImage wraps image data in the body of an iTerm2 inline image
+// escape sequence. The client supplies the surrounding OSC introducer and
+// terminator for its terminal.
+func encodeInlineImage(name string, data []byte) []byte {
+	return fmt.Appendf(nil, "1337;File=name=%s;size=%d;inline=1:%s",
+		base64.StdEncoding.EncodeToString([]byte(name)),
+		len(data),
+		base64.StdEncoding.EncodeToString(data))
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// writeFiles creates a temporary directory containing the given files.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestNewStore(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "A"})
+
+	tests := []struct {
+		name      string
+		dir       string
+		selection string
+		wantErr   bool
+	}{
+		{name: "valid", dir: dir, selection: SelectRandom, wantErr: false},
+		{name: "unknown selection", dir: dir, selection: "shuffle", wantErr: true},
+		{name: "missing directory", dir: filepath.Join(dir, "missing"), selection: SelectRandom, wantErr: true},
+		{name: "not a directory", dir: filepath.Join(dir, "a.txt"), selection: SelectRandom, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStore(tt.dir, tt.selection)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_Sequential(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"b.txt": "B", "a.txt": "A", ".hidden": "H"})
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o755); err != nil {
+		t.Fatalf("Failed to create subdir: %v", err)
+	}
+
+	store, err := NewStore(dir, SelectSequential)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		msg, err := store.Next()
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		got = append(got, string(msg.Payload))
+	}
+
+	if strings.Join(got, "") != "ABA" {
+		t.Errorf("Sequential payloads = %v, want [A B A]", got)
+	}
+}
+
+func TestStore_Random(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "A", "b.txt": "B", "c.txt": "C"})
+
+	store, err := NewStore(dir, SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+	store.intN = func(n int) int { return n - 1 }
+
+	msg, err := store.Next()
+	if err != nil {
+		t.Fatalf("Next() unexpected error: %v", err)
+	}
+	if msg.Name != "c.txt" {
+		t.Errorf("Next() picked %q, want c.txt", msg.Name)
+	}
+}
+
+func TestStore_Date(t *testing.T) {
+	files := map[string]string{
+		"2026-10-19-release.txt": "exact",
+		"10-19-anniversary.txt":  "annual",
+		"12-25-holiday.txt":      "other day",
+		"general.txt":            "undated",
+	}
+	at := func(date string) func() time.Time {
+		return func() time.Time {
+			d, _ := time.Parse("2006-01-02", date)
+			return d
+		}
+	}
+
+	tests := []struct {
+		name  string
+		today string
+		files map[string]string
+		want  string
+	}{
+		{name: "exact date wins", today: "2026-10-19", files: files, want: "exact"},
+		{name: "annual date", today: "2027-10-19", files: files, want: "annual"},
+		{name: "undated fallback", today: "2026-03-01", files: files, want: "undated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(writeFiles(t, tt.files), SelectDate)
+			if err != nil {
+				t.Fatalf("NewStore() unexpected error: %v", err)
+			}
+			store.now = at(tt.today)
+
+			msg, err := store.Next()
+			if err != nil {
+				t.Fatalf("Next() unexpected error: %v", err)
+			}
+			if string(msg.Payload) != tt.want {
+				t.Errorf("Next() = %q, want %q", msg.Payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_NoMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		selection string
+	}{
+		{name: "empty directory", files: map[string]string{}, selection: SelectRandom},
+		{name: "only other dates", files: map[string]string{"12-25.txt": "x"}, selection: SelectDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(writeFiles(t, tt.files), tt.selection)
+			if err != nil {
+				t.Fatalf("NewStore() unexpected error: %v", err)
+			}
+			store.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+			_, err = store.Next()
+			if !errors.Is(err, ErrNoMessages) {
+				t.Errorf("Next() error = %v, want ErrNoMessages", err)
+			}
+		})
+	}
+}
+
+func TestStore_Image(t *testing.T) {
+	image := "\x89PNG fake image data"
+	store, err := NewStore(writeFiles(t, map[string]string{"logo.PNG": image}), SelectRandom)
+	if err != nil {
+		t.Fatalf("NewStore() unexpected error: %v", err)
+	}
+
+	msg, err := store.Next()
+	if err != nil {
+		t.Fatalf("Next() unexpected error: %v", err)
+	}
+
+	expected := "1337;File=name=" + base64.StdEncoding.EncodeToString([]byte("logo.PNG")) +
+		";size=20;inline=1:" + base64.StdEncoding.EncodeToString([]byte(image))
+	if string(msg.Payload) != expected {
+		t.Errorf("Payload = %q, want %q", msg.Payload, expected)
+	}
+	if msg.ContentType != network.ContentTypeOSC {
+		t.Errorf("ContentType = %q, want %q", msg.ContentType, network.ContentTypeOSC)
+	}
+}
+
+func TestStore_ContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		want     string
+	}{
+		{name: "plain text", file: "a.txt", contents: "Hello", want: network.ContentTypeText},
+		{name: "ansi art", file: "a.txt", contents: "\033[1mHello\033[0m", want: network.ContentTypeANSI},
+		{name: "markdown extension", file: "a.md", contents: "Hello", want: network.ContentTypeMarkdown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(writeFiles(t, map[string]string{tt.file: tt.contents}), SelectRandom)
+			if err != nil {
+				t.Fatalf("NewStore() unexpected error: %v", err)
+			}
+
+			msg, err := store.Next()
+			if err != nil {
+				t.Fatalf("Next() unexpected error: %v", err)
+			}
+			if msg.ContentType != tt.want {
+				t.Errorf("ContentType = %q, want %q", msg.ContentType, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_NextFor(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		caps  network.Capabilities
+		want  string
+	}{
+		{name: "text only terminal skips images", files: map[string]string{"a.png": "img", "b.txt": "text"}, caps: network.Capabilities{}, want: "b.txt"},
+		{name: "only images falls back", files: map[string]string{"a.png": "img"}, caps: network.Capabilities{}, want: "a.png"},
+		{name: "graphics terminal gets images", files: map[string]string{"a.png": "img", "b.txt": "text"}, caps: network.Capabilities{Graphics: []string{"iterm2"}}, want: "a.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(writeFiles(t, tt.files), SelectSequential)
+			if err != nil {
+				t.Fatalf("NewStore() unexpected error: %v", err)
+			}
+
+			msg, err := store.NextFor(tt.caps)
+			if err != nil {
+				t.Fatalf("NextFor() unexpected error: %v", err)
+			}
+			if msg.Name != tt.want {
+				t.Errorf("NextFor() picked %q, want %q", msg.Name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// maxStderr limits how much of a failed command's error output is kept.
+const maxStderr = 512
+
+// commandWaitDelay bounds how long a killed command's output is drained.
+const commandWaitDelay = 100 * time.Millisecond
+
+// Command runs a shell command and uses its standard output as the message.
+// The command learns about the terminal from the MOTD_COLUMNS, MOTD_ROWS,
+// MOTD_COLOR_DEPTH and MOTD_GRAPHICS environment variables.
+type Command struct {
+	command string
+	opts    options
+}
+
+// Fetch runs the command, failing if it exits unsuccessfully, runs longer
+// than the timeout or writes more than the maximum message size. The
+// content type of the output is sniffed.
+func (c *Command) Fetch(ctx context.Context, hello network.Hello) (*network.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	var stdout limitedBuffer
+	var stderr truncatingBuffer
+	stdout.max = c.opts.maxSize
+	// One byte more than is shown, so that truncate marks cut output.
+	stderr.max = maxStderr + 1
+	cmd := shellCommand(ctx, c.command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Children of the shell may keep its output open after it is killed.
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Env = append(os.Environ(),
+		"MOTD_COLUMNS="+strconv.Itoa(hello.Capabilities.Columns),
+		"MOTD_ROWS="+strconv.Itoa(hello.Capabilities.Rows),
+		"MOTD_COLOR_DEPTH="+strconv.Itoa(hello.Capabilities.ColorDepth),
+		"MOTD_GRAPHICS="+strings.Join(hello.Capabilities.Graphics, ","),
+		"MOTD_BACKGROUND="+hello.Capabilities.Background,
+	)
+
+	err := cmd.Run()
+	switch {
+	case stdout.exceeded:
+		return nil, fmt.Errorf("command output %w: more than %d bytes", network.ErrTooLarge, c.opts.maxSize)
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("command did not finish within %v: %w", c.opts.timeout, ctx.Err())
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, fmt.Errorf("command failed: %w: %s", err, truncate(msg, maxStderr))
+		}
+		return nil, fmt.Errorf("command failed: %w", err)
+	}
+
+	body := stdout.buf.Bytes()
+	return &network.Message{ContentType: network.Sniff(body), Body: body}, nil
+}
+
+// errOutputTooLarge stops a command that writes too much.
+var errOutputTooLarge = errors.New("output too large")
+
+// limitedBuffer collects up to max bytes and fails writes beyond that. The
+// buffer is not embedded so that its ReadFrom cannot bypass the limit.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+}
+
+// Write implements io.Writer.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.max {
+		b.exceeded = true
+		return 0, errOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// truncatingBuffer keeps the first max bytes written to it and discards the
+// rest without failing, so a command's error output takes bounded memory
+// and the command is not stopped by a broken pipe.
+type truncatingBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+// Write implements io.Writer.
+func (b *truncatingBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// truncate shortens s to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,129 @@
+//go:build unix
+
+package source
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestCommand_Fetch(t *testing.T) {
+	hello := network.Hello{Capabilities: network.Capabilities{
+		Columns: 120, Rows: 40, ColorDepth: 24, Graphics: []string{"iterm2", "kitty"}, Background: network.BackgroundDark,
+	}}
+
+	tests := []struct {
+		name            string
+		command         string
+		maxSize         int
+		timeout         time.Duration
+		want            string
+		wantContentType string
+		wantErr         string
+	}{
+		{
+			name:            "stdout",
+			command:         "echo 'Hello from' $(echo a shell)",
+			want:            "Hello from a shell\n",
+			wantContentType: network.ContentTypeText,
+		},
+		{
+			name:            "ansi output is sniffed",
+			command:         `printf '\033[1mBold\033[0m'`,
+			want:            "\033[1mBold\033[0m",
+			wantContentType: network.ContentTypeANSI,
+		},
+		{
+			name:            "terminal capabilities",
+			command:         `echo "$MOTD_COLUMNS $MOTD_ROWS $MOTD_COLOR_DEPTH $MOTD_GRAPHICS $MOTD_BACKGROUND"`,
+			want:            "120 40 24 iterm2,kitty dark\n",
+			wantContentType: network.ContentTypeText,
+		},
+		{
+			name:    "failure includes stderr",
+			command: "echo 'fortune: not found' >&2; exit 127",
+			wantErr: "fortune: not found",
+		},
+		{
+			name:    "stderr is truncated",
+			command: "i=0; while [ $i -lt 2000 ]; do echo error >&2; i=$((i+1)); done; exit 1",
+			wantErr: "error\ner...",
+		},
+		{
+			name:    "timeout",
+			command: "sleep 5",
+			timeout: 50 * time.Millisecond,
+			wantErr: "did not finish",
+		},
+		{
+			name:    "output too large",
+			command: "echo 0123456789",
+			maxSize: 5,
+			wantErr: "too large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.maxSize > 0 {
+				opts = append(opts, WithMaxSize(tt.maxSize))
+			}
+			if tt.timeout > 0 {
+				opts = append(opts, WithTimeout(tt.timeout))
+			}
+			src, err := New(Spec{Kind: KindCommand, Arg: tt.command}, opts...)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			start := time.Now()
+			msg, err := src.Fetch(context.Background(), hello)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Fetch() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if elapsed := time.Since(start); elapsed > 2*time.Second {
+					t.Errorf("Fetch() took %v", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() unexpected error: %v", err)
+			}
+			if string(msg.Body) != tt.want {
+				t.Errorf("Body = %q, want %q", msg.Body, tt.want)
+			}
+			if msg.ContentType != tt.wantContentType {
+				t.Errorf("ContentType = %q, want %q", msg.ContentType, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestTruncatingBuffer(t *testing.T) {
+	b := &truncatingBuffer{max: 8}
+	for _, s := range []string{"abc", "defgh", "ijk", "l"} {
+		if n, err := b.Write([]byte(s)); n != len(s) || err != nil {
+			t.Errorf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+		}
+	}
+	if got := b.buf.String(); got != "abcdefgh" {
+		t.Errorf("Buffer = %q, want the first 8 bytes", got)
+	}
+}
+
+func TestCommand_Fetch_TooLargeIsProtocolError(t *testing.T) {
+	src, err := New(Spec{Kind: KindCommand, Arg: "echo 0123456789"}, WithMaxSize(5))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := src.Fetch(context.Background(), network.Hello{}); !errors.Is(err, network.ErrTooLarge) {
+		t.Errorf("Fetch() error = %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,105 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// fortuneIndexExtension marks the strfile(1) index next to a fortune file.
+const fortuneIndexExtension = ".dat"
+
+// Fortune reads the message from a fortune(6) database: a text file of
+// entries separated by lines holding a single "%", or a directory of them.
+type Fortune struct {
+	path string
+	opts options
+}
+
+// Fetch picks an entry, at random or in order, across every file of the
+// database. Each file may be at most the maximum message size.
+func (f *Fortune) Fetch(_ context.Context, _ network.Hello) (*network.Message, error) {
+	files, err := f.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries [][]byte
+	var names []string
+	for _, file := range files {
+		data, err := readFile(file, f.opts.maxSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range parseFortunes(data) {
+			entries = append(entries, entry)
+			names = append(names, filepath.Base(file))
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: %w", f.path, ErrNoMessages)
+	}
+
+	i := f.opts.pick(KindFortune+":"+f.path, len(entries))
+	return &network.Message{
+		ContentType: network.ContentTypeText,
+		Metadata:    network.Metadata{ID: fmt.Sprintf("%s#%d", names[i], i)},
+		Body:        entries[i],
+	}, nil
+}
+
+// files returns the fortune files of the database: the file itself, or the
+// files in the directory other than strfile indexes.
+func (f *Fortune) files() ([]string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fortune database: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{f.path}, nil
+	}
+
+	names, err := listFiles(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range names {
+		if !strings.EqualFold(filepath.Ext(name), fortuneIndexExtension) {
+			files = append(files, filepath.Join(f.path, name))
+		}
+	}
+	return files, nil
+}
+
+// parseFortunes splits a fortune file into its non-empty entries.
+func parseFortunes(data []byte) [][]byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	var entries [][]byte
+	var entry []byte
+	for line := range bytes.Lines(data) {
+		if string(bytes.TrimRight(line, "\n")) == "%" {
+			entries = appendFortune(entries, entry)
+			entry = nil
+			continue
+		}
+		entry = append(entry, line...)
+	}
+	return appendFortune(entries, entry)
+}
+
+// appendFortune appends entry to entries without its trailing newlines,
+// unless it is blank.
+func appendFortune(entries [][]byte, entry []byte) [][]byte {
+	entry = bytes.TrimRight(entry, "\n")
+	if len(bytes.TrimSpace(entry)) == 0 {
+		return entries
+	}
+	return append(entries, entry)
+}
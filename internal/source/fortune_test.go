@@ -0,0 +1,95 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestParseFortunes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "entries", data: "One\n%\nTwo\nlines\n%\nThree\n", want: []string{"One", "Two\nlines", "Three"}},
+		{name: "leading and trailing separators", data: "%\nOne\n%\n", want: []string{"One"}},
+		{name: "blank entries are skipped", data: "One\n%\n\n%\nTwo", want: []string{"One", "Two"}},
+		{name: "crlf", data: "One\r\n%\r\nTwo\r\n", want: []string{"One", "Two"}},
+		{name: "percent inside an entry", data: "100% sure\n%\n", want: []string{"100% sure"}},
+		{name: "empty", data: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range parseFortunes([]byte(tt.data)) {
+				got = append(got, string(entry))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFortunes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFortune_Fetch(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"computers":     "One\n%\nTwo\n%\n",
+		"computers.dat": "\x00\x00\x00\x02binary index",
+		"science":       "Three\n",
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		state := filepath.Join(t.TempDir(), DefaultStateFile)
+		var got []string
+		for range 4 {
+			src, err := New(Spec{Kind: KindFortune, Arg: dir}, WithSelection(SelectSequential), WithStateFile(state))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			msg, err := src.Fetch(context.Background(), network.Hello{})
+			if err != nil {
+				t.Fatalf("Fetch() unexpected error: %v", err)
+			}
+			if msg.ContentType != network.ContentTypeText {
+				t.Errorf("ContentType = %q, want %q", msg.ContentType, network.ContentTypeText)
+			}
+			got = append(got, string(msg.Body))
+		}
+		if want := []string{"One", "Two", "Three", "One"}; !slices.Equal(got, want) {
+			t.Errorf("Fortunes = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		src, err := New(Spec{Kind: KindFortune, Arg: filepath.Join(dir, "computers")})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		src.(*Fortune).opts.intN = func(n int) int { return n - 1 }
+
+		msg, err := src.Fetch(context.Background(), network.Hello{})
+		if err != nil {
+			t.Fatalf("Fetch() unexpected error: %v", err)
+		}
+		if string(msg.Body) != "Two" || msg.Metadata.ID != "computers#1" {
+			t.Errorf("Fetch() = %q (%s), want the second fortune", msg.Body, msg.Metadata.ID)
+		}
+	})
+
+	t.Run("no entries", func(t *testing.T) {
+		empty := writeFiles(t, map[string]string{"empty": "%\n%\n"})
+		src, err := New(Spec{Kind: KindFortune, Arg: empty})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if _, err := src.Fetch(context.Background(), network.Hello{}); !errors.Is(err, ErrNoMessages) {
+			t.Errorf("Fetch() error = %v, want ErrNoMessages", err)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package source
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+)
+
+// shellCommand runs command with cmd.exe on Windows and the POSIX shell
+// elsewhere. Platforms without process groups only kill the shell itself.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd.exe", "/C", command)
+	}
+	return exec.CommandContext(ctx, "/bin/sh", "-c", command)
+}
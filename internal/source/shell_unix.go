@@ -0,0 +1,20 @@
+//go:build unix
+
+package source
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs command with the POSIX shell in its own process group,
+// so that the commands it starts are killed with it.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd
+}
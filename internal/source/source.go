@@ -0,0 +1,266 @@
+// Package source provides MOTD sources that work without a server: a file,
+// a directory of files, a fortune(6) database and a command's output.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Source kinds, written as "kind:argument" in source specifications.
+const (
+	KindNetwork = "network" // The configured server; takes no argument
+	KindFile    = "file"    // A single file
+	KindDir     = "dir"     // One file from a directory
+	KindFortune = "fortune" // One entry from a fortune file or directory
+	KindCommand = "command" // The standard output of a shell command
+)
+
+// Kinds lists the accepted source kinds.
+var Kinds = []string{KindNetwork, KindFile, KindDir, KindFortune, KindCommand}
+
+// Selection strategies for sources holding several messages.
+const (
+	SelectRandom     = "random"
+	SelectSequential = "sequential"
+)
+
+// Selections lists the accepted selection strategy names.
+var Selections = []string{SelectRandom, SelectSequential}
+
+// DefaultCommandTimeout bounds how long a command source may run.
+const DefaultCommandTimeout = 2 * time.Second
+
+// ErrNoMessages is returned when a directory or fortune database holds no
+// messages.
+var ErrNoMessages = errors.New("no messages available")
+
+// markdownExtensions are read as Markdown; other files are sniffed.
+var markdownExtensions = []string{".md", ".markdown"}
+
+// Source produces a message to display. hello describes the terminal the
+// message is for.
+type Source interface {
+	Fetch(ctx context.Context, hello network.Hello) (*network.Message, error)
+}
+
+// Spec is a parsed source specification such as "dir:/etc/motd.d".
+type Spec struct {
+	Kind string
+	Arg  string // Path or command line; empty for KindNetwork
+}
+
+// String returns the specification in the form Parse accepts.
+func (s Spec) String() string {
+	if s.Arg == "" {
+		return s.Kind
+	}
+	return s.Kind + ":" + s.Arg
+}
+
+// Parse parses a source specification: "network", or a local kind and its
+// argument separated by a colon, e.g. "fortune:/usr/share/games/fortunes"
+// or "command:fortune -s".
+func Parse(spec string) (Spec, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case KindNetwork:
+		if arg != "" {
+			return Spec{}, fmt.Errorf("source %s takes no argument", KindNetwork)
+		}
+	case KindFile, KindDir, KindFortune, KindCommand:
+		if strings.TrimSpace(arg) == "" {
+			return Spec{}, fmt.Errorf("source %s requires an argument, e.g. %s:PATH", kind, kind)
+		}
+	default:
+		return Spec{}, fmt.Errorf("unknown source %q, must be one of %s", kind, strings.Join(Kinds, ", "))
+	}
+	return Spec{Kind: kind, Arg: arg}, nil
+}
+
+// options holds the settings shared by local sources.
+type options struct {
+	maxSize   int
+	selection string
+	stateFile string
+	timeout   time.Duration
+
+	// intN is replaceable for tests.
+	intN func(n int) int
+}
+
+// Option configures optional source behavior.
+type Option func(*options)
+
+// WithMaxSize limits messages to maxSize bytes. The default is
+// network.MaxFrameSize.
+func WithMaxSize(maxSize int) Option {
+	return func(o *options) {
+		o.maxSize = maxSize
+	}
+}
+
+// WithSelection chooses how directory and fortune sources pick a message:
+// SelectRandom (the default) or SelectSequential.
+func WithSelection(selection string) Option {
+	return func(o *options) {
+		o.selection = selection
+	}
+}
+
+// WithStateFile sets the file recording the position of sequential
+// selection between runs. Without one every run starts at the first
+// message.
+func WithStateFile(path string) Option {
+	return func(o *options) {
+		o.stateFile = path
+	}
+}
+
+// WithTimeout bounds how long a command source may run. The default is
+// DefaultCommandTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// New creates the local source described by spec.
+func New(spec Spec, opts ...Option) (Source, error) {
+	o := options{
+		maxSize:   network.MaxFrameSize,
+		selection: SelectRandom,
+		timeout:   DefaultCommandTimeout,
+		intN:      rand.IntN,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !slices.Contains(Selections, o.selection) {
+		return nil, fmt.Errorf("unknown selection %q, must be one of %s", o.selection, strings.Join(Selections, ", "))
+	}
+
+	switch spec.Kind {
+	case KindFile:
+		return &File{path: expandHome(spec.Arg), opts: o}, nil
+	case KindDir:
+		return &Dir{path: expandHome(spec.Arg), opts: o}, nil
+	case KindFortune:
+		return &Fortune{path: expandHome(spec.Arg), opts: o}, nil
+	case KindCommand:
+		return &Command{command: spec.Arg, opts: o}, nil
+	default:
+		return nil, fmt.Errorf("source %q is not a local source", spec.Kind)
+	}
+}
+
+// File reads the message from a single file.
+type File struct {
+	path string
+	opts options
+}
+
+// Fetch reads the file. Markdown files are recognised by their extension;
+// the content type of other files is sniffed.
+func (f *File) Fetch(_ context.Context, _ network.Hello) (*network.Message, error) {
+	return readMessage(f.path, f.opts.maxSize)
+}
+
+// Dir reads the message from one of the files in a directory. Hidden files
+// and subdirectories are ignored.
+type Dir struct {
+	path string
+	opts options
+}
+
+// Fetch picks a file, at random or in name order, and reads it.
+func (d *Dir) Fetch(_ context.Context, _ network.Hello) (*network.Message, error) {
+	names, err := listFiles(d.path)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: %w", d.path, ErrNoMessages)
+	}
+
+	name := names[d.opts.pick(KindDir+":"+d.path, len(names))]
+	return readMessage(filepath.Join(d.path, name), d.opts.maxSize)
+}
+
+// readMessage reads the file at path as a message of at most maxSize
+// bytes.
+func readMessage(path string, maxSize int) (*network.Message, error) {
+	data, err := readFile(path, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := network.Sniff(data)
+	if slices.Contains(markdownExtensions, strings.ToLower(filepath.Ext(path))) {
+		contentType = network.ContentTypeMarkdown
+	}
+	return &network.Message{
+		ContentType: contentType,
+		Metadata:    network.Metadata{ID: filepath.Base(path)},
+		Body:        data,
+	}, nil
+}
+
+// readFile reads the file at path, failing with network.ErrTooLarge if it
+// holds more than maxSize bytes.
+func readFile(path string, maxSize int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message: %w", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%s: %w: more than %d bytes", path, network.ErrTooLarge, maxSize)
+	}
+	return data, nil
+}
+
+// listFiles returns the names of the regular, non-hidden files in dir in
+// name order.
+func listFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,191 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// writeFiles creates files with the given contents in a new directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Spec
+		wantErr bool
+	}{
+		{spec: "network", want: Spec{Kind: KindNetwork}},
+		{spec: "file:/etc/motd", want: Spec{Kind: KindFile, Arg: "/etc/motd"}},
+		{spec: "dir:C:\\motd", want: Spec{Kind: KindDir, Arg: "C:\\motd"}},
+		{spec: "fortune:/usr/share/games/fortunes", want: Spec{Kind: KindFortune, Arg: "/usr/share/games/fortunes"}},
+		{spec: "command:fortune -s | cowsay", want: Spec{Kind: KindCommand, Arg: "fortune -s | cowsay"}},
+		{spec: "network:host", wantErr: true},
+		{spec: "file:", wantErr: true},
+		{spec: "command", wantErr: true},
+		{spec: "/etc/motd", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.spec {
+				t.Errorf("String() = %q, want %q", got.String(), tt.spec)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(Spec{Kind: KindNetwork}); err == nil {
+		t.Error("New(network) expected error, got nil")
+	}
+	if _, err := New(Spec{Kind: KindDir, Arg: "."}, WithSelection("date")); err == nil {
+		t.Error("New() with unknown selection expected error, got nil")
+	}
+}
+
+func TestFile_Fetch(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"motd.txt":  "Welcome",
+		"motd.md":   "Welcome",
+		"motd.ansi": "\033[1mWelcome\033[0m",
+	})
+
+	tests := []struct {
+		name            string
+		file            string
+		maxSize         int
+		wantContentType string
+		wantErr         error
+	}{
+		{name: "text", file: "motd.txt", wantContentType: network.ContentTypeText},
+		{name: "markdown by extension", file: "motd.md", wantContentType: network.ContentTypeMarkdown},
+		{name: "ansi is sniffed", file: "motd.ansi", wantContentType: network.ContentTypeANSI},
+		{name: "too large", file: "motd.txt", maxSize: 3, wantErr: network.ErrTooLarge},
+		{name: "missing", file: "missing.txt", wantErr: os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.maxSize > 0 {
+				opts = append(opts, WithMaxSize(tt.maxSize))
+			}
+			src, err := New(Spec{Kind: KindFile, Arg: filepath.Join(dir, tt.file)}, opts...)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			msg, err := src.Fetch(context.Background(), network.Hello{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() unexpected error: %v", err)
+			}
+			if msg.ContentType != tt.wantContentType {
+				t.Errorf("ContentType = %q, want %q", msg.ContentType, tt.wantContentType)
+			}
+			if msg.Metadata.ID != tt.file {
+				t.Errorf("ID = %q, want %q", msg.Metadata.ID, tt.file)
+			}
+		})
+	}
+}
+
+func TestDir_Fetch(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "A", "b.txt": "B", "c.md": "C", ".hidden": "H"})
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("random", func(t *testing.T) {
+		src, err := New(Spec{Kind: KindDir, Arg: dir})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		var candidates int
+		src.(*Dir).opts.intN = func(n int) int {
+			candidates = n
+			return n - 1
+		}
+
+		msg, err := src.Fetch(context.Background(), network.Hello{})
+		if err != nil {
+			t.Fatalf("Fetch() unexpected error: %v", err)
+		}
+		if candidates != 3 {
+			t.Errorf("Picked among %d files, want 3", candidates)
+		}
+		if string(msg.Body) != "C" || msg.ContentType != network.ContentTypeMarkdown {
+			t.Errorf("Fetch() = %q (%s), want the last file as Markdown", msg.Body, msg.ContentType)
+		}
+	})
+
+	t.Run("sequential", func(t *testing.T) {
+		state := filepath.Join(t.TempDir(), "state", DefaultStateFile)
+		var got string
+		for range 4 {
+			// A new source each time, as every run of the client creates one.
+			src, err := New(Spec{Kind: KindDir, Arg: dir}, WithSelection(SelectSequential), WithStateFile(state))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			msg, err := src.Fetch(context.Background(), network.Hello{})
+			if err != nil {
+				t.Fatalf("Fetch() unexpected error: %v", err)
+			}
+			got += string(msg.Body)
+		}
+		if got != "ABCA" {
+			t.Errorf("Sequential bodies = %q, want %q", got, "ABCA")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		src, err := New(Spec{Kind: KindDir, Arg: t.TempDir()})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if _, err := src.Fetch(context.Background(), network.Hello{}); !errors.Is(err, ErrNoMessages) {
+			t.Errorf("Fetch() error = %v, want ErrNoMessages", err)
+		}
+	})
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	if got, want := expandHome("~/motd.txt"), filepath.Join(home, "motd.txt"); got != want {
+		t.Errorf("expandHome() = %q, want %q", got, want)
+	}
+	if got := expandHome("/etc/motd"); got != "/etc/motd" {
+		t.Errorf("expandHome() = %q, want the path unchanged", got)
+	}
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// DefaultStateFile is the state file name, kept next to the message cache.
+const DefaultStateFile = "sources.json"
+
+// pick returns the index of the next of n messages. Random selection picks
+// any; sequential selection continues from the position recorded for key
+// in the state file, wrapping around, and records the next one.
+func (o *options) pick(key string, n int) int {
+	if o.selection != SelectSequential {
+		return o.intN(n)
+	}
+	if o.stateFile == "" {
+		return 0
+	}
+
+	positions, err := loadPositions(o.stateFile)
+	if err != nil {
+		slog.Warn("Failed to read source state, starting from the first message", "error", err)
+		positions = map[string]int{}
+	}
+	i := positions[key] % n
+	if i < 0 {
+		i = 0
+	}
+	positions[key] = i + 1
+	if err := storePositions(o.stateFile, positions); err != nil {
+		slog.Warn("Failed to save source state", "error", err)
+	}
+	return i
+}
+
+// loadPositions reads the recorded positions. A missing file holds none.
+func loadPositions(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]int{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	positions := map[string]int{}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("failed to decode state file: %w", err)
+	}
+	return positions, nil
+}
+
+// storePositions writes positions atomically, readable only by the current
+// user.
+func storePositions(path string, positions map[string]int) error {
+	data, err := json.Marshal(positions)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sources-*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
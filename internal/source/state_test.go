@@ -0,0 +1,59 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptions_pick_Sequential(t *testing.T) {
+	state := filepath.Join(t.TempDir(), DefaultStateFile)
+	o := &options{selection: SelectSequential, stateFile: state}
+
+	var got []int
+	for range 3 {
+		got = append(got, o.pick("a", 2))
+	}
+	got = append(got, o.pick("b", 2))
+
+	want := []int{0, 1, 0, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pick() sequence = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestOptions_pick_ShrunkSource(t *testing.T) {
+	state := filepath.Join(t.TempDir(), DefaultStateFile)
+	if err := os.WriteFile(state, []byte(`{"a":7}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o := &options{selection: SelectSequential, stateFile: state}
+
+	if got := o.pick("a", 3); got != 1 {
+		t.Errorf("pick() = %d, want 1", got)
+	}
+}
+
+func TestOptions_pick_CorruptState(t *testing.T) {
+	state := filepath.Join(t.TempDir(), DefaultStateFile)
+	if err := os.WriteFile(state, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o := &options{selection: SelectSequential, stateFile: state}
+
+	if got := o.pick("a", 3); got != 0 {
+		t.Errorf("pick() = %d, want 0", got)
+	}
+	if got := o.pick("a", 3); got != 1 {
+		t.Errorf("pick() after rewriting the state = %d, want 1", got)
+	}
+}
+
+func TestOptions_pick_NoStateFile(t *testing.T) {
+	o := &options{selection: SelectSequential}
+	if got := o.pick("a", 3); got != 0 {
+		t.Errorf("pick() = %d, want 0", got)
+	}
+}
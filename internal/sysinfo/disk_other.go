@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || dragonfly)
+
+package sysinfo
+
+import "errors"
+
+// Disk is not supported on this platform.
+func Disk(path string) (Usage, error) {
+	return Usage{}, errors.New("disk usage is not supported on this platform")
+}
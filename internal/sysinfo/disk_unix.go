@@ -0,0 +1,20 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package sysinfo
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Disk returns how much of the file system containing path is in use.
+// Blocks reserved for the superuser count as used, as in df(1).
+func Disk(path string) (Usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Usage{}, fmt.Errorf("failed to read disk usage of %s: %w", path, err)
+	}
+	size := uint64(st.Bsize)
+	total := uint64(st.Blocks) * size
+	return Usage{Used: total - uint64(st.Bavail)*size, Total: total}, nil
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package sysinfo
+
+import "testing"
+
+func TestDisk(t *testing.T) {
+	got, err := Disk(t.TempDir())
+	if err != nil {
+		t.Fatalf("Disk() unexpected error: %v", err)
+	}
+	if got.Total == 0 || got.Used > got.Total {
+		t.Errorf("Disk() = %+v, want a used share of a non-zero total", got)
+	}
+
+	if _, err := Disk("/nonexistent/motd"); err == nil {
+		t.Error("Disk() of a missing path expected error, got nil")
+	}
+}
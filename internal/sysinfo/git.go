@@ -0,0 +1,75 @@
+package sysinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned by GitBranch outside a git work tree.
+var ErrNotRepository = errors.New("not a git repository")
+
+// shortHashLength is how much of the commit hash a detached HEAD shows.
+const shortHashLength = 7
+
+// GitBranch returns the branch checked out in the git work tree containing
+// dir, or the abbreviated commit hash when HEAD is detached. It reads the
+// repository files directly rather than running git.
+func GitBranch(dir string) (string, error) {
+	gitDir, err := findGitDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read git HEAD: %w", err)
+	}
+	head = bytes.TrimSpace(head)
+	if ref, ok := bytes.CutPrefix(head, []byte("ref: ")); ok {
+		return strings.TrimPrefix(string(ref), "refs/heads/"), nil
+	}
+	if len(head) < shortHashLength {
+		return "", fmt.Errorf("failed to parse git HEAD %q", head)
+	}
+	return string(head[:shortHashLength]), nil
+}
+
+// findGitDir returns the git directory of the work tree containing dir.
+// Linked work trees and submodules have a .git file pointing to it.
+func findGitDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ".git")
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			return path, nil
+		case err == nil:
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+			if !ok {
+				return "", fmt.Errorf("failed to parse %s", path)
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			return target, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNotRepository
+		}
+		dir = parent
+	}
+}
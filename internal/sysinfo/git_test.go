@@ -0,0 +1,74 @@
+package sysinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		head    string
+		want    string
+		wantErr bool
+	}{
+		{name: "branch", head: "ref: refs/heads/main\n", want: "main"},
+		{name: "branch with slash", head: "ref: refs/heads/feature/login\n", want: "feature/login"},
+		{name: "detached", head: "1d68e3e0c5a1f1d2b0b8c9e6d7a4f3b2c1d0e9f8\n", want: "1d68e3e"},
+		{name: "corrupt", head: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte(tt.head), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			sub := filepath.Join(repo, "cmd", "tool")
+			if err := os.MkdirAll(sub, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := GitBranch(sub)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GitBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GitBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitBranch_Worktree(t *testing.T) {
+	gitDir := filepath.Join(t.TempDir(), "worktrees", "feature")
+	if err := os.MkdirAll(gitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/feature\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	worktree := t.TempDir()
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+gitDir+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GitBranch(worktree)
+	if err != nil {
+		t.Fatalf("GitBranch() unexpected error: %v", err)
+	}
+	if got != "feature" {
+		t.Errorf("GitBranch() = %q, want %q", got, "feature")
+	}
+}
+
+func TestGitBranch_NotRepository(t *testing.T) {
+	if _, err := GitBranch(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Skipf("Temporary directory is inside a git work tree (error: %v)", err)
+	}
+}
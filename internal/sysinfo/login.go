@@ -0,0 +1,117 @@
+package sysinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoLogin is returned by LastLogin when no earlier login is recorded.
+var ErrNoLogin = errors.New("no previous login recorded")
+
+// wtmpFile is the login history, a variable so tests can use fixtures.
+var wtmpFile = "/var/log/wtmp"
+
+// Layout of a glibc utmp record on Linux, which is the same on 32 and 64
+// bit systems.
+const (
+	utmpSize       = 384
+	utmpUserProc   = 7 // ut_type of a user login
+	utmpLineOffset = 8
+	utmpLineSize   = 32
+	utmpUserOffset = 44
+	utmpUserSize   = 32
+	utmpHostOffset = 76
+	utmpHostSize   = 256
+	utmpTimeOffset = 340
+)
+
+// maxLoginRecords bounds how much of a long login history is searched.
+const maxLoginRecords = 1 << 16
+
+// Login is a login session recorded in the login history.
+type Login struct {
+	Time time.Time
+	Line string // Terminal, e.g. "pts/0"
+	Host string // Remote host, empty for local logins
+}
+
+// String formats the login like the "Last login" line of login(1), e.g.
+// "Mon Jan 2 15:04 from 192.0.2.1".
+func (l Login) String() string {
+	when := l.Time.Local().Format("Mon Jan 2 15:04")
+	if l.Host != "" {
+		return when + " from " + l.Host
+	}
+	return when + " on " + l.Line
+}
+
+// LastLogin returns the most recent login of username recorded in the
+// login history, other than the newest one on the current terminal, which
+// is the session being started. Earlier logins on the same terminal are
+// reported like any other.
+func LastLogin(username string) (Login, error) {
+	f, err := os.Open(wtmpFile)
+	if err != nil {
+		return Login{}, fmt.Errorf("failed to read login history: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return Login{}, fmt.Errorf("failed to read login history: %w", err)
+	}
+
+	current, skipped := currentLine(), false
+	record := make([]byte, utmpSize)
+	for i := info.Size()/utmpSize - 1; i >= 0 && i >= info.Size()/utmpSize-maxLoginRecords; i-- {
+		if _, err := f.ReadAt(record, i*utmpSize); err != nil && !errors.Is(err, io.EOF) {
+			return Login{}, fmt.Errorf("failed to read login history: %w", err)
+		}
+		if binary.LittleEndian.Uint16(record) != utmpUserProc ||
+			cString(record[utmpUserOffset:utmpUserOffset+utmpUserSize]) != username {
+			continue
+		}
+
+		line := cString(record[utmpLineOffset : utmpLineOffset+utmpLineSize])
+		if line == current && current != "" && !skipped {
+			skipped = true
+			continue
+		}
+		return Login{
+			Time: time.Unix(int64(int32(binary.LittleEndian.Uint32(record[utmpTimeOffset:]))), 0),
+			Line: line,
+			Host: cString(record[utmpHostOffset : utmpHostOffset+utmpHostSize]),
+		}, nil
+	}
+	return Login{}, ErrNoLogin
+}
+
+// currentLine returns the terminal of standard input as recorded in the
+// login history, e.g. "pts/0", or "" if it is not a terminal. It is a
+// variable so tests can replace it.
+var currentLine = func() string {
+	path, err := os.Readlink(filepath.Join(procDir, "self", "fd", "0"))
+	if err != nil {
+		return ""
+	}
+	line, ok := strings.CutPrefix(path, "/dev/")
+	if !ok {
+		return ""
+	}
+	return line
+}
+
+// cString returns the NUL-terminated string at the start of b.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
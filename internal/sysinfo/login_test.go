@@ -0,0 +1,103 @@
+package sysinfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// utmpRecord encodes a login history record.
+func utmpRecord(kind uint16, user, line, host string, at time.Time) []byte {
+	record := make([]byte, utmpSize)
+	binary.LittleEndian.PutUint16(record, kind)
+	copy(record[utmpLineOffset:utmpLineOffset+utmpLineSize], line)
+	copy(record[utmpUserOffset:utmpUserOffset+utmpUserSize], user)
+	copy(record[utmpHostOffset:utmpHostOffset+utmpHostSize], host)
+	binary.LittleEndian.PutUint32(record[utmpTimeOffset:], uint32(at.Unix()))
+	return record
+}
+
+// useWtmp points wtmpFile at a file holding records and pretends the
+// current terminal is line.
+func useWtmp(t *testing.T, line string, records ...[]byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wtmp")
+	var data []byte
+	for _, record := range records {
+		data = append(data, record...)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldFile, oldLine := wtmpFile, currentLine
+	wtmpFile = path
+	currentLine = func() string { return line }
+	t.Cleanup(func() { wtmpFile, currentLine = oldFile, oldLine })
+}
+
+func TestLastLogin(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+	const deadProcess = 8
+
+	history := [][]byte{
+		utmpRecord(utmpUserProc, "alice", "pts/0", "192.0.2.1", monday.Add(-48*time.Hour)),
+		utmpRecord(utmpUserProc, "alice", "tty1", "", monday.Add(-24*time.Hour)),
+		utmpRecord(utmpUserProc, "bob", "pts/2", "192.0.2.9", monday.Add(-time.Hour)),
+		utmpRecord(deadProcess, "alice", "tty1", "", monday.Add(-time.Minute)),
+		utmpRecord(utmpUserProc, "alice", "pts/1", "192.0.2.7", monday),
+	}
+	sameLine := [][]byte{
+		utmpRecord(utmpUserProc, "alice", "pts/0", "192.0.2.1", monday.Add(-48*time.Hour)),
+		utmpRecord(deadProcess, "alice", "pts/0", "", monday.Add(-47*time.Hour)),
+		utmpRecord(utmpUserProc, "alice", "pts/0", "192.0.2.7", monday),
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		records [][]byte
+		user    string
+		want    Login
+		wantErr error
+	}{
+		{name: "other terminal", line: "pts/1", records: history, user: "alice", want: Login{Time: monday.Add(-24 * time.Hour), Line: "tty1"}},
+		{name: "same terminal", line: "pts/0", records: sameLine, user: "alice", want: Login{Time: monday.Add(-48 * time.Hour), Line: "pts/0", Host: "192.0.2.1"}},
+		{name: "not a terminal", line: "", records: history, user: "alice", want: Login{Time: monday, Line: "pts/1", Host: "192.0.2.7"}},
+		{name: "only the current session", line: "pts/0", records: sameLine[2:], user: "alice", wantErr: ErrNoLogin},
+		{name: "unknown user", line: "pts/1", records: history, user: "carol", wantErr: ErrNoLogin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useWtmp(t, tt.line, tt.records...)
+
+			got, err := LastLogin(tt.user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LastLogin() error = %v, want %v", err, tt.wantErr)
+			}
+			if !got.Time.Equal(tt.want.Time) || got.Line != tt.want.Line || got.Host != tt.want.Host {
+				t.Errorf("LastLogin() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogin_String(t *testing.T) {
+	at := time.Date(2026, 10, 19, 8, 30, 0, 0, time.Local)
+	tests := []struct {
+		login Login
+		want  string
+	}{
+		{login: Login{Time: at, Line: "pts/0", Host: "192.0.2.1"}, want: "Mon Oct 19 08:30 from 192.0.2.1"},
+		{login: Login{Time: at, Line: "tty1"}, want: "Mon Oct 19 08:30 on tty1"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.login.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Package sysinfo reads facts about the local system, such as its uptime
+// and load, for messages that mention them. Facts are read from /proc and
+// similar files where possible; those that cannot be read on the current
+// system return an error.
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procDir is the proc filesystem, a variable so tests can use fixtures.
+var procDir = "/proc"
+
+// Load is the system load average over one, five and fifteen minutes.
+type Load struct {
+	One, Five, Fifteen float64
+}
+
+// String formats the load like uptime(1), e.g. "0.52 0.58 0.59".
+func (l Load) String() string {
+	return fmt.Sprintf("%.2f %.2f %.2f", l.One, l.Five, l.Fifteen)
+}
+
+// Hostname returns the host name reported by the kernel.
+func Hostname() (string, error) {
+	return os.Hostname()
+}
+
+// Username returns the login name of the current user, falling back to the
+// USER and USERNAME environment variables.
+func Username() (string, error) {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username, nil
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(key); name != "" {
+			return name, nil
+		}
+	}
+	return "", errors.New("current user is unknown")
+}
+
+// Uptime returns how long the system has been running.
+func Uptime() (time.Duration, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "uptime"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read uptime: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, errors.New("failed to read uptime: empty file")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse uptime: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// LoadAverage returns the system load average.
+func LoadAverage() (Load, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "loadavg"))
+	if err != nil {
+		return Load{}, fmt.Errorf("failed to read load average: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return Load{}, fmt.Errorf("failed to parse load average %q", strings.TrimSpace(string(data)))
+	}
+	var load [3]float64
+	for i := range load {
+		if load[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return Load{}, fmt.Errorf("failed to parse load average: %w", err)
+		}
+	}
+	return Load{One: load[0], Five: load[1], Fifteen: load[2]}, nil
+}
+
+// FormatDuration describes d in days, hours and minutes, keeping the two
+// largest units, e.g. "3 days, 4 hours" or "12 minutes".
+func FormatDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+	minutes := int(d/time.Minute) % 60
+
+	switch {
+	case days > 0 && hours > 0:
+		return plural(days, "day") + ", " + plural(hours, "hour")
+	case days > 0:
+		return plural(days, "day")
+	case hours > 0 && minutes > 0:
+		return plural(hours, "hour") + ", " + plural(minutes, "minute")
+	case hours > 0:
+		return plural(hours, "hour")
+	default:
+		return plural(minutes, "minute")
+	}
+}
+
+// plural formats n followed by unit, pluralized unless n is one.
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
+
+// osReleaseFiles are read in order for the operating system name.
+var osReleaseFiles = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// rebootRequiredFile exists on Debian and Ubuntu while updates wait for a
+// reboot.
+var rebootRequiredFile = "/var/run/reboot-required"
+
+// Usage is how much of a resource, such as memory, is in use.
+type Usage struct {
+	Used, Total uint64 // Bytes
+}
+
+// Percent returns the used share of the total, from 0 to 100.
+func (u Usage) Percent() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Total) * 100
+}
+
+// String formats the usage, e.g. "2.1 GiB of 7.7 GiB (27%)".
+func (u Usage) String() string {
+	return fmt.Sprintf("%s of %s (%.0f%%)", FormatBytes(u.Used), FormatBytes(u.Total), u.Percent())
+}
+
+// FormatBytes formats n with binary units, e.g. "7.7 GiB" or "512 B".
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, exp := float64(n)/unit, 0
+	for size >= unit && exp < 4 {
+		size /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", size, "KMGTP"[exp])
+}
+
+// OSRelease returns the operating system name and version from
+// os-release(5), e.g. "Ubuntu 24.04.1 LTS".
+func OSRelease() (string, error) {
+	var err error
+	for _, path := range osReleaseFiles {
+		var data []byte
+		if data, err = os.ReadFile(path); err == nil {
+			return parseOSRelease(data)
+		}
+	}
+	return "", fmt.Errorf("failed to read os-release: %w", err)
+}
+
+// parseOSRelease returns PRETTY_NAME, or NAME and VERSION, from the
+// contents of an os-release file.
+func parseOSRelease(data []byte) (string, error) {
+	vars := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		vars[key] = unquoteShell(value)
+	}
+
+	switch {
+	case vars["PRETTY_NAME"] != "":
+		return vars["PRETTY_NAME"], nil
+	case vars["NAME"] != "":
+		return strings.TrimSpace(vars["NAME"] + " " + vars["VERSION"]), nil
+	default:
+		return "", errors.New("os-release has no name")
+	}
+}
+
+// unquoteShell removes the quotes around an os-release value and the
+// backslashes escaping characters within double quotes.
+func unquoteShell(value string) string {
+	if len(value) < 2 || value[0] != value[len(value)-1] {
+		return value
+	}
+	switch value[0] {
+	case '\'':
+		return value[1 : len(value)-1]
+	case '"':
+		var b strings.Builder
+		value = value[1 : len(value)-1]
+		for i := 0; i < len(value); i++ {
+			if value[i] == '\\' && i+1 < len(value) && strings.IndexByte("\"\\$`", value[i+1]) >= 0 {
+				i++
+			}
+			b.WriteByte(value[i])
+		}
+		return b.String()
+	default:
+		return value
+	}
+}
+
+// Kernel returns the kernel release, e.g. "6.8.0-45-generic".
+func Kernel() (string, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "sys", "kernel", "osrelease"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read kernel release: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Memory returns how much memory is in use, counting memory the kernel
+// can reclaim, such as the page cache, as available.
+func Memory() (Usage, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "meminfo"))
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to read memory usage: %w", err)
+	}
+
+	fields := map[string]uint64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Values are in kibibytes, e.g. "MemTotal:  8048432 kB".
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err == nil {
+			fields[key] = kb * 1024
+		}
+	}
+
+	total, ok := fields["MemTotal"]
+	if !ok || total == 0 {
+		return Usage{}, errors.New("failed to parse memory usage: no MemTotal")
+	}
+	available, ok := fields["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14 do not estimate the available memory.
+		available = fields["MemFree"] + fields["Buffers"] + fields["Cached"]
+	}
+	return Usage{Used: total - min(available, total), Total: total}, nil
+}
+
+// RebootRequired reports whether installed updates wait for a reboot.
+func RebootRequired() bool {
+	_, err := os.Stat(rebootRequiredFile)
+	return err == nil
+}
@@ -0,0 +1,247 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useProc points procDir at a directory holding the given files.
+func useProc(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := procDir
+	procDir = dir
+	t.Cleanup(func() { procDir = old })
+}
+
+func TestUptime(t *testing.T) {
+	useProc(t, map[string]string{"uptime": "273615.37 1062583.74\n"})
+
+	got, err := Uptime()
+	if err != nil {
+		t.Fatalf("Uptime() unexpected error: %v", err)
+	}
+	if want := 273615370 * time.Millisecond; got != want {
+		t.Errorf("Uptime() = %v, want %v", got, want)
+	}
+}
+
+func TestUptime_Errors(t *testing.T) {
+	for _, contents := range []string{"", "soon"} {
+		useProc(t, map[string]string{"uptime": contents})
+		if _, err := Uptime(); err == nil {
+			t.Errorf("Uptime() with %q expected error, got nil", contents)
+		}
+	}
+
+	useProc(t, nil)
+	if _, err := Uptime(); err == nil {
+		t.Error("Uptime() without /proc expected error, got nil")
+	}
+}
+
+func TestLoadAverage(t *testing.T) {
+	useProc(t, map[string]string{"loadavg": "0.52 0.58 1.00 1/189 4022\n"})
+
+	got, err := LoadAverage()
+	if err != nil {
+		t.Fatalf("LoadAverage() unexpected error: %v", err)
+	}
+	if want := (Load{One: 0.52, Five: 0.58, Fifteen: 1}); got != want {
+		t.Errorf("LoadAverage() = %+v, want %+v", got, want)
+	}
+	if got.String() != "0.52 0.58 1.00" {
+		t.Errorf("String() = %q, want %q", got.String(), "0.52 0.58 1.00")
+	}
+
+	useProc(t, map[string]string{"loadavg": "0.52"})
+	if _, err := LoadAverage(); err == nil {
+		t.Error("LoadAverage() with a short file expected error, got nil")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0 minutes"},
+		{d: 59 * time.Second, want: "0 minutes"},
+		{d: time.Minute, want: "1 minute"},
+		{d: 12 * time.Minute, want: "12 minutes"},
+		{d: time.Hour, want: "1 hour"},
+		{d: 5*time.Hour + 12*time.Minute, want: "5 hours, 12 minutes"},
+		{d: 24 * time.Hour, want: "1 day"},
+		{d: 76*time.Hour + 30*time.Minute, want: "3 days, 4 hours"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestUsername(t *testing.T) {
+	name, err := Username()
+	if err != nil {
+		t.Fatalf("Username() unexpected error: %v", err)
+	}
+	if name == "" {
+		t.Error("Username() returned an empty name")
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "pretty name",
+			data: "NAME=\"Ubuntu\"\nVERSION=\"24.04.1 LTS (Noble Numbat)\"\nPRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\n",
+			want: "Ubuntu 24.04.1 LTS",
+		},
+		{name: "name and version", data: "NAME=Alpine\nVERSION='3.20'\n", want: "Alpine 3.20"},
+		{name: "escapes", data: `PRETTY_NAME="Say \"hi\" \$HOME"`, want: `Say "hi" $HOME`},
+		{name: "comments", data: "# PRETTY_NAME=Nope\nNAME=Arch Linux\n", want: "Arch Linux"},
+		{name: "no name", data: "ID=debian\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOSRelease([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOSRelease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseOSRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSRelease(t *testing.T) {
+	dir := t.TempDir()
+	fallback := filepath.Join(dir, "usr-lib-os-release")
+	if err := os.WriteFile(fallback, []byte("PRETTY_NAME=\"Fedora Linux 40\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := osReleaseFiles
+	osReleaseFiles = []string{filepath.Join(dir, "missing"), fallback}
+	t.Cleanup(func() { osReleaseFiles = old })
+
+	got, err := OSRelease()
+	if err != nil {
+		t.Fatalf("OSRelease() unexpected error: %v", err)
+	}
+	if got != "Fedora Linux 40" {
+		t.Errorf("OSRelease() = %q, want %q", got, "Fedora Linux 40")
+	}
+}
+
+func TestKernel(t *testing.T) {
+	useProc(t, map[string]string{"sys/kernel/osrelease": "6.8.0-45-generic\n"})
+
+	got, err := Kernel()
+	if err != nil {
+		t.Fatalf("Kernel() unexpected error: %v", err)
+	}
+	if got != "6.8.0-45-generic" {
+		t.Errorf("Kernel() = %q, want %q", got, "6.8.0-45-generic")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	tests := []struct {
+		name    string
+		meminfo string
+		want    Usage
+		wantErr bool
+	}{
+		{
+			name:    "available",
+			meminfo: "MemTotal:        8000 kB\nMemFree:         1000 kB\nMemAvailable:    6000 kB\nCached:          4000 kB\n",
+			want:    Usage{Used: 2000 * 1024, Total: 8000 * 1024},
+		},
+		{
+			name:    "old kernel",
+			meminfo: "MemTotal:        8000 kB\nMemFree:         1000 kB\nBuffers:          500 kB\nCached:          2500 kB\n",
+			want:    Usage{Used: 4000 * 1024, Total: 8000 * 1024},
+		},
+		{name: "no total", meminfo: "MemFree: 1000 kB\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProc(t, map[string]string{"meminfo": tt.meminfo})
+			got, err := Memory()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Memory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Memory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsage_String(t *testing.T) {
+	u := Usage{Used: 2254857830, Total: 8267812044}
+	if got := u.String(); got != "2.1 GiB of 7.7 GiB (27%)" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Usage{}).Percent(); got != 0 {
+		t.Errorf("Percent() of nothing = %v, want 0", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 5 << 20, want: "5.0 MiB"},
+		{n: 3 << 40, want: "3.0 TiB"},
+		{n: 1 << 60, want: "1024.0 PiB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRebootRequired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reboot-required")
+	old := rebootRequiredFile
+	rebootRequiredFile = path
+	t.Cleanup(func() { rebootRequiredFile = old })
+
+	if RebootRequired() {
+		t.Error("RebootRequired() = true without the flag file")
+	}
+	if err := os.WriteFile(path, []byte("*** System restart required ***\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !RebootRequired() {
+		t.Error("RebootRequired() = false with the flag file")
+	}
+}
@@ -0,0 +1,164 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Animation is an animated GIF drawn with half blocks, which the client
+// plays itself because the terminal cannot.
+type Animation struct {
+	frames []string
+	delays []time.Duration
+	plays  int // Times the GIF asks to be played, 0 for forever
+	rows   int // Terminal height, 0 if unknown
+}
+
+// Animation returns the animation of msg, whose first frame Render draws,
+// or nil if msg is not an animated GIF or the terminal animates it itself.
+func (f *Formatter) Animation(msg *network.Message) *Animation {
+	if mediaType(msg) != network.ContentTypeGIF || len(f.env.Graphics) > 0 || !halfBlocks(f.env) {
+		return nil
+	}
+
+	box, _ := placeImage(f.env, msg)
+	if box.columns == 0 {
+		return nil
+	}
+	a := &Animation{rows: f.env.Rows}
+	plays, err := eachFrame(msg.Body, func(canvas *image.RGBA, delay time.Duration) error {
+		a.frames = append(a.frames, halfBlock(f.env, canvas, box))
+		a.delays = append(a.delays, delay)
+		return nil
+	})
+	if err != nil || len(a.frames) < 2 {
+		return nil
+	}
+	a.plays = plays
+	return a
+}
+
+// Play draws the frames of the animation over its first frame until it
+// has played maxLoops times, or as often as the GIF asks if that is fewer,
+// maxDuration has passed or ctx is done. printed is everything written
+// since the first frame was drawn, which tells how far up it is. The
+// animation is not played if it has scrolled out of view, or the terminal
+// height is unknown, as when the output is not a terminal. The cursor is
+// hidden while playing and left where it was.
+func (a *Animation) Play(ctx context.Context, w io.Writer, printed string, maxLoops int, maxDuration time.Duration) {
+	up := cursorRows(printed)
+	if maxLoops <= 0 || maxDuration <= 0 || up <= 0 || up >= a.rows {
+		return
+	}
+	if a.plays > 0 {
+		maxLoops = min(maxLoops, a.plays)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxDuration)
+	defer cancel()
+	fmt.Fprint(w, "\033[?25l")
+	defer fmt.Fprint(w, "\033[?25h")
+
+	timer := time.NewTimer(a.delays[0])
+	defer timer.Stop()
+	for loop := range maxLoops {
+		for i, frame := range a.frames {
+			if loop == 0 && i == 0 {
+				// Render already drew the first frame.
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			fmt.Fprintf(w, "\0337\033[%dA\r%s\0338", up, frame)
+			timer.Reset(a.delays[i])
+		}
+	}
+}
+
+// cursorRows returns how many rows below the start of s the cursor is
+// after writing it, following newlines and cursor movements but not text
+// wrapping.
+func cursorRows(s string) int {
+	row, saved := 0, 0
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "\n\033")
+		if i < 0 {
+			break
+		}
+		if s[i] == '\n' {
+			row++
+			s = s[i+1:]
+			continue
+		}
+
+		seq, n := escapeSequence(s[i:])
+		s = s[i+n:]
+		switch {
+		case seq == "\0337":
+			saved = row
+		case seq == "\0338":
+			row = saved
+		case len(seq) > 2 && seq[1] == '[' && (seq[len(seq)-1] == 'A' || seq[len(seq)-1] == 'B'):
+			count := 1
+			fmt.Sscanf(seq[2:len(seq)-1], "%d", &count)
+			if seq[len(seq)-1] == 'A' {
+				count = -count
+			}
+			row = max(row+count, 0)
+		}
+	}
+	return row
+}
+
+// kittyAnimation encodes a GIF as kitty animation frames scaled to box, or
+// at its own size if box is empty. kitty only accepts PNG frames, so each
+// frame is converted. It fails if the GIF cannot be decoded.
+func kittyAnimation(data []byte, box imageBox) (string, bool) {
+	var frames []string
+	var delays []time.Duration
+	plays, err := eachFrame(data, func(canvas *image.RGBA, delay time.Duration) error {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, canvas); err != nil {
+			return err
+		}
+		frames = append(frames, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		delays = append(delays, delay)
+		return nil
+	})
+	if err != nil || len(frames) == 0 {
+		return "", false
+	}
+	if len(frames) == 1 {
+		return kittyImage(frames[0], box), true
+	}
+
+	size := ""
+	if box.columns > 0 {
+		size = fmt.Sprintf(",c=%d,r=%d,C=1", box.columns, box.rows)
+	}
+	// Replies are suppressed, since the shell would read them as input.
+	id := crc32.ChecksumIEEE(data) | 1
+	var b strings.Builder
+	b.WriteString(kittyCommand(fmt.Sprintf("f=100,a=T,i=%d,q=2%s", id, size), frames[0]))
+	for i, frame := range frames[1:] {
+		b.WriteString(kittyCommand(fmt.Sprintf("a=f,i=%d,q=2,f=100,z=%d", id, delays[i+1].Milliseconds()), frame))
+	}
+	// Frame numbers start at 1. A loop count of 1 loops forever and n
+	// plays the animation n-1 times.
+	fmt.Fprintf(&b, "\033_Ga=a,i=%d,q=2,r=1,z=%d\033\\", id, delays[0].Milliseconds())
+	fmt.Fprintf(&b, "\033_Ga=a,i=%d,q=2,s=3,v=%d\033\\", id, plays+1)
+	return b.String(), true
+}
@@ -0,0 +1,165 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestFormatter_Animation(t *testing.T) {
+	animated := testGIF(t, 0, 0, red, blue)
+	halfBlocks := &Environment{ColorDepth: 24, Columns: 80, Rows: 24}
+
+	tests := []struct {
+		name string
+		env  *Environment
+		msg  *network.Message
+		want bool
+	}{
+		{name: "animated gif", env: halfBlocks, msg: &network.Message{ContentType: network.ContentTypeGIF, Body: animated}, want: true},
+		{name: "single frame", env: halfBlocks, msg: &network.Message{ContentType: network.ContentTypeGIF, Body: testGIF(t, 0, 0, red)}},
+		{name: "png", env: halfBlocks, msg: &network.Message{ContentType: network.ContentTypePNG, Body: testPNG(t, 2, 2)}},
+		{name: "terminal animates", env: &Environment{Graphics: []string{GraphicsKitty}, ColorDepth: 24}, msg: &network.Message{ContentType: network.ContentTypeGIF, Body: animated}},
+		{name: "too few colors", env: &Environment{ColorDepth: 4}, msg: &network.Message{ContentType: network.ContentTypeGIF, Body: animated}},
+		{name: "invalid gif", env: halfBlocks, msg: &network.Message{ContentType: network.ContentTypeGIF, Body: []byte("GIF89a")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFormatter(tt.env).Animation(tt.msg); (got != nil) != tt.want {
+				t.Errorf("Animation() = %v, want animation %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnimation_Play(t *testing.T) {
+	animation := &Animation{
+		frames: []string{"A", "B"},
+		delays: []time.Duration{time.Millisecond, time.Millisecond},
+		rows:   24,
+	}
+
+	tests := []struct {
+		name     string
+		plays    int
+		maxLoops int
+		printed  string
+		want     string
+	}{
+		{
+			name:     "bounded loops",
+			maxLoops: 2,
+			printed:  "A\nText\n",
+			want:     "\033[?25l\0337\033[2A\rB\0338\0337\033[2A\rA\0338\0337\033[2A\rB\0338\033[?25h",
+		},
+		{
+			name:     "gif plays fewer times",
+			plays:    1,
+			maxLoops: 3,
+			printed:  "A\n",
+			want:     "\033[?25l\0337\033[1A\rB\0338\033[?25h",
+		},
+		{name: "disabled", maxLoops: 0, printed: "A\n", want: ""},
+		{name: "scrolled out of view", maxLoops: 1, printed: strings.Repeat("\n", 24), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			animation.plays = tt.plays
+			var out bytes.Buffer
+			animation.Play(context.Background(), &out, tt.printed, tt.maxLoops, time.Second)
+			if out.String() != tt.want {
+				t.Errorf("Play() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAnimation_Play_MaxDuration(t *testing.T) {
+	animation := &Animation{frames: []string{"A", "B"}, delays: []time.Duration{time.Hour, time.Hour}, rows: 24}
+
+	done := make(chan struct{})
+	var out bytes.Buffer
+	go func() {
+		animation.Play(context.Background(), &out, "A\n", 3, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Play() did not stop after its maximum duration")
+	}
+	if out.String() != "\033[?25l\033[?25h" {
+		t.Errorf("Play() wrote %q, want the cursor hidden and shown again", out.String())
+	}
+}
+
+func TestCursorRows(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "one\ntwo\n", want: 2},
+		{s: "\n\n\n\033[3A\r\0337image\n\n\0338caption\n\n\n", want: 3},
+		{s: "\033[2Bx\033[A", want: 1},
+		{s: "\033[38;5;1m\n", want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := cursorRows(tt.s); got != tt.want {
+			t.Errorf("cursorRows(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestKittyAnimation(t *testing.T) {
+	got, ok := kittyAnimation(testGIF(t, 2, 0, red, blue), imageBox{columns: 4, rows: 2})
+	if !ok {
+		t.Fatal("kittyAnimation() failed")
+	}
+
+	for _, want := range []string{",a=T,i=", ",q=2,c=4,r=2,C=1,m=0;", "\033_Ga=f,i=", ",q=2,f=100,z=50,m=0;", ",r=1,z=50\033\\", ",s=3,v=4\033\\"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("kittyAnimation() = %q, want it to contain %q", got, want)
+		}
+	}
+	if n := strings.Count(got, "\033_G"); n != 4 {
+		t.Errorf("kittyAnimation() sent %d commands, want 4", n)
+	}
+
+	if _, ok := kittyAnimation([]byte("GIF89a"), imageBox{}); ok {
+		t.Error("kittyAnimation() of an invalid GIF succeeded")
+	}
+}
+
+func TestFormatter_Render_GIF(t *testing.T) {
+	data := testGIF(t, 0, 0, red, blue)
+	msg := &network.Message{ContentType: network.ContentTypeGIF, Body: data}
+
+	iterm := &Environment{StartSeq: "\033]", EndSeq: "\a", Graphics: []string{GraphicsITerm2}}
+	if got := NewFormatter(iterm).Render(msg); !strings.Contains(got, "1337;File=inline=1;") || !strings.Contains(got, base64.StdEncoding.EncodeToString(data)) {
+		t.Errorf("Render() = %q, want the GIF passed to iTerm2 as is", got)
+	}
+
+	kitty := &Environment{Graphics: []string{GraphicsKitty}}
+	if got := NewFormatter(kitty).Render(msg); !strings.Contains(got, "\033_Ga=f,") {
+		t.Errorf("Render() = %q, want kitty animation frames", got)
+	}
+
+	halfBlocks := &Environment{ColorDepth: 24, Columns: 80, Rows: 24}
+	if got := NewFormatter(halfBlocks).Render(msg); got != "\033[38;2;255;0;0m\033[48;2;255;0;0m▀\033[49m▀\033[0m" {
+		t.Errorf("Render() = %q, want the first frame in half blocks", got)
+	}
+
+	if got := NewFormatter(&Environment{}).Render(msg); got != "[image]" {
+		t.Errorf("Render() = %q, want alternative text", got)
+	}
+}
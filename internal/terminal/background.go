@@ -0,0 +1,126 @@
+package terminal
+
+import (
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultBackgroundTimeout is how long Detect waits for the terminal to
+// report its background color.
+const defaultBackgroundTimeout = 100 * time.Millisecond
+
+// remoteBackgroundTimeout is the least Detect waits over SSH, where the
+// answer takes a network round trip. Terminals answer the device
+// attributes query sent along, so a longer wait costs nothing.
+const remoteBackgroundTimeout = time.Second
+
+// replyGrace is how much longer a terminal that has started answering is
+// read after the timeout, so that the rest of its answer does not end up
+// in the shell's input.
+const replyGrace = time.Second
+
+// Queries sent to learn the background color. Every terminal answers the
+// primary device attributes query, so when its answer comes without one to
+// the background query, the terminal does not support it and there is no
+// need to wait for the timeout.
+const (
+	backgroundQuery       = "\033]11;?\033\\"
+	deviceAttributesQuery = "\033[c"
+)
+
+// lightLuminance is the relative luminance above which a background is
+// light.
+const lightLuminance = 0.5
+
+// Answers to the queries.
+var (
+	backgroundReply       = regexp.MustCompile(`\x1b\]11;rgba?:([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})(?:/[0-9a-fA-F]{1,4})?(?:\x07|\x1b\\)`)
+	deviceAttributesReply = regexp.MustCompile(`\x1b\[\?[0-9;]*c`)
+)
+
+// DetectorOption configures optional Detector behavior.
+type DetectorOption func(*Detector)
+
+// WithBackgroundTimeout sets how long Detect waits for the terminal to
+// report its background color. Zero skips the query. The default is 100ms;
+// over SSH Detect waits at least a second.
+func WithBackgroundTimeout(timeout time.Duration) DetectorOption {
+	return func(d *Detector) {
+		d.backgroundTimeout = timeout
+	}
+}
+
+// LightBackground reports whether the terminal background is light, and
+// whether it is known at all.
+func (env *Environment) LightBackground() (light, known bool) {
+	if env.Background == (Color{}) {
+		return false, false
+	}
+	return env.BackgroundLuminance > lightLuminance, true
+}
+
+// detectBackground returns the background color of the terminal, asking
+// it with an OSC 11 query or else reading COLORFGBG. Remote terminals are
+// given longer to answer.
+func (d *Detector) detectBackground(term string, remote bool) (Color, bool) {
+	if d.backgroundTimeout > 0 && term != "dumb" && isTerminal(os.Stdout) {
+		timeout := d.backgroundTimeout
+		if remote {
+			timeout = max(timeout, remoteBackgroundTimeout)
+		}
+		if reply := queryTerminal(backgroundQuery+deviceAttributesQuery, deviceAttributesReply, timeout); reply != nil {
+			if c, ok := parseBackgroundReply(reply); ok {
+				return c, true
+			}
+		}
+	}
+	return colorFGBG()
+}
+
+// parseBackgroundReply extracts the color from an answer to the background
+// query, e.g. "\033]11;rgb:1e1e/1e1e/2e2e\033\\". Components have one to
+// four hex digits.
+func parseBackgroundReply(reply []byte) (Color, bool) {
+	match := backgroundReply.FindSubmatch(reply)
+	if match == nil {
+		return Color{}, false
+	}
+
+	var rgb [3]uint8
+	for i, component := range match[1:4] {
+		v, _ := strconv.ParseUint(string(component), 16, 16)
+		limit := uint64(1)<<(4*len(component)) - 1
+		rgb[i] = uint8((v*255 + limit/2) / limit)
+	}
+	return RGB(rgb[0], rgb[1], rgb[2]), true
+}
+
+// colorFGBG returns the background color in COLORFGBG, which rxvt, Konsole
+// and others set to the indexes of the foreground and background colors,
+// e.g. "15;0" for white on black.
+func colorFGBG() (Color, bool) {
+	fields := strings.Split(os.Getenv("COLORFGBG"), ";")
+	bg, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || bg < 0 || bg > 15 {
+		return Color{}, false
+	}
+	return Color{kind: colorANSI, index: uint8(bg)}, true
+}
+
+// luminance returns the relative luminance of c, from 0 for black to 1 for
+// white, as defined by WCAG.
+func luminance(c Color) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	r, g, b := c.rgb()
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
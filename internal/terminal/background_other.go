@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package terminal
+
+import (
+	"regexp"
+	"time"
+)
+
+// queryTerminal is unsupported on this platform; callers fall back to
+// environment variables.
+var queryTerminal = func(query string, done *regexp.Regexp, timeout time.Duration) []byte {
+	return nil
+}
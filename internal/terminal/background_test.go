@@ -0,0 +1,150 @@
+package terminal
+
+import (
+	"math"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseBackgroundReply(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  string
+		want   Color
+		wantOK bool
+	}{
+		{name: "four digits with ST", reply: "\033]11;rgb:ffff/8787/0000\033\\\033[?62;22c", want: RGB(255, 135, 0), wantOK: true},
+		{name: "two digits with BEL", reply: "\033]11;rgb:1e/1e/2e\a", want: RGB(30, 30, 46), wantOK: true},
+		{name: "one digit", reply: "\033]11;rgb:f/8/0\a", want: RGB(255, 136, 0), wantOK: true},
+		{name: "three digits", reply: "\033]11;rgb:fff/000/800\a", want: RGB(255, 0, 128), wantOK: true},
+		{name: "with alpha", reply: "\033]11;rgba:ffff/ffff/ffff/ffff\033\\", want: RGB(255, 255, 255), wantOK: true},
+		{name: "device attributes only", reply: "\033[?1;2c"},
+		{name: "unterminated", reply: "\033]11;rgb:ffff/ffff/ffff"},
+		{name: "other format", reply: "\033]11;#ffffff\a"},
+		{name: "empty", reply: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseBackgroundReply([]byte(tt.reply))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseBackgroundReply(%q) = %+v, %v, want %+v, %v", tt.reply, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLuminance(t *testing.T) {
+	tests := []struct {
+		c    Color
+		want float64
+	}{
+		{c: RGB(0, 0, 0), want: 0},
+		{c: RGB(255, 255, 255), want: 1},
+		{c: RGB(255, 0, 0), want: 0.2126},
+		{c: RGB(128, 128, 128), want: 0.2159},
+		{c: Color{kind: colorANSI, index: 15}, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := luminance(tt.c); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("luminance(%+v) = %.4f, want %.4f", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironment_LightBackground(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       *Environment
+		wantLight bool
+		wantKnown bool
+	}{
+		{name: "unknown", env: &Environment{}},
+		{name: "black", env: &Environment{Background: RGB(0, 0, 0)}, wantKnown: true},
+		{name: "solarized light", env: &Environment{Background: RGB(253, 246, 227), BackgroundLuminance: 0.91}, wantLight: true, wantKnown: true},
+		{name: "gray", env: &Environment{Background: RGB(128, 128, 128), BackgroundLuminance: 0.22}, wantKnown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			light, known := tt.env.LightBackground()
+			if light != tt.wantLight || known != tt.wantKnown {
+				t.Errorf("LightBackground() = %v, %v, want %v, %v", light, known, tt.wantLight, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestDetector_detectBackground(t *testing.T) {
+	const reply = "\033]11;rgb:ffff/ffff/ffff\033\\\033[?62c"
+
+	tests := []struct {
+		name        string
+		term        string
+		remote      bool
+		timeout     time.Duration
+		terminal    bool
+		reply       string
+		colorfgbg   string
+		want        Color
+		wantOK      bool
+		wantQuery   bool
+		wantTimeout time.Duration
+	}{
+		{name: "terminal answers", term: "xterm", timeout: time.Second, terminal: true, reply: reply, colorfgbg: "15;0", want: RGB(255, 255, 255), wantOK: true, wantQuery: true, wantTimeout: time.Second},
+		{name: "terminal does not answer", term: "xterm", timeout: time.Second, terminal: true, reply: "\033[?62c", colorfgbg: "0;15", want: Color{kind: colorANSI, index: 15}, wantOK: true, wantQuery: true, wantTimeout: time.Second},
+		{name: "remote terminal is given longer", term: "xterm", remote: true, timeout: 100 * time.Millisecond, terminal: true, reply: reply, want: RGB(255, 255, 255), wantOK: true, wantQuery: true, wantTimeout: remoteBackgroundTimeout},
+		{name: "remote query disabled", term: "xterm", remote: true, terminal: true, reply: reply},
+		{name: "query disabled", term: "xterm", terminal: true, reply: reply, colorfgbg: "15;default;0", want: Color{kind: colorANSI, index: 0}, wantOK: true},
+		{name: "not a terminal", term: "xterm", timeout: time.Second, reply: reply},
+		{name: "dumb terminal", term: "dumb", timeout: time.Second, terminal: true, reply: reply},
+		{name: "invalid colorfgbg", term: "xterm", colorfgbg: "15;default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTerminal(t, tt.terminal)
+			queried := useQuery(t, tt.reply)
+			t.Setenv("COLORFGBG", tt.colorfgbg)
+
+			got, ok := NewDetector(WithBackgroundTimeout(tt.timeout)).detectBackground(tt.term, tt.remote)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("detectBackground() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if (*queried != 0) != tt.wantQuery {
+				t.Errorf("queried terminal = %v, want %v", *queried != 0, tt.wantQuery)
+			}
+			if tt.wantQuery && *queried != tt.wantTimeout {
+				t.Errorf("query timeout = %v, want %v", *queried, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+// useQuery makes queries to the terminal answer reply, and reports the
+// timeout of the one made, or 0 if none was.
+func useQuery(t *testing.T, reply string) *time.Duration {
+	t.Helper()
+	queried := new(time.Duration)
+	original := queryTerminal
+	queryTerminal = func(query string, done *regexp.Regexp, timeout time.Duration) []byte {
+		*queried = timeout
+		if query != backgroundQuery+deviceAttributesQuery || !done.MatchString(reply) {
+			t.Errorf("queryTerminal(%q) would not stop on reply %q", query, reply)
+		}
+		return []byte(reply)
+	}
+	t.Cleanup(func() { queryTerminal = original })
+	return queried
+}
+
+func TestNewDetector_BackgroundTimeout(t *testing.T) {
+	if d := NewDetector(); d.backgroundTimeout != defaultBackgroundTimeout {
+		t.Errorf("NewDetector() timeout = %v, want %v", d.backgroundTimeout, defaultBackgroundTimeout)
+	}
+	if d := NewDetector(WithBackgroundTimeout(0)); d.backgroundTimeout != 0 {
+		t.Errorf("NewDetector(WithBackgroundTimeout(0)) timeout = %v, want 0", d.backgroundTimeout)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package terminal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// queryTerminal writes query to the controlling terminal and returns what
+// it answers, reading until the answer matches done or the timeout
+// expires. A terminal that has started answering gets replyGrace more to
+// finish. The answer is read a byte at a time and nothing after it, so
+// input typed meanwhile is left for the shell. It returns nil if there is no controlling
+// terminal or the process is in the background, where reading from it
+// would stop the process. It is a variable so tests can replace it.
+var queryTerminal = func(query string, done *regexp.Regexp, timeout time.Duration) []byte {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	defer tty.Close()
+
+	fd := tty.Fd()
+	var pgrp int32
+	if ioctl(fd, syscall.TIOCGPGRP, unsafe.Pointer(&pgrp)) != nil || int(pgrp) != syscall.Getpgrp() {
+		return nil
+	}
+
+	// Read the answer as it comes, without echoing it, in reads that
+	// return after a tenth of a second without input.
+	var saved syscall.Termios
+	if ioctl(fd, ioctlGetTermios, unsafe.Pointer(&saved)) != nil {
+		return nil
+	}
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+	if ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw)) != nil {
+		return nil
+	}
+	defer ioctl(fd, ioctlSetTermios, unsafe.Pointer(&saved))
+
+	if _, err := tty.WriteString(query); err != nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	// The deadline also bounds reads when the terminal is polled rather
+	// than read in raw mode.
+	_ = tty.SetReadDeadline(deadline)
+	var reply bytes.Buffer
+	buf := make([]byte, 1)
+	extended := false
+	for !done.Match(reply.Bytes()) {
+		if !time.Now().Before(deadline) {
+			if reply.Len() == 0 || extended {
+				break
+			}
+			extended = true
+			deadline = deadline.Add(replyGrace)
+			_ = tty.SetReadDeadline(deadline)
+		}
+		n, err := tty.Read(buf)
+		reply.Write(buf[:n])
+		// Reads that time out in raw mode end with io.EOF.
+		if err != nil && err != io.EOF && !os.IsTimeout(err) {
+			break
+		}
+	}
+	return reply.Bytes()
+}
+
+// ioctl performs the request on the file descriptor fd.
+func ioctl(fd uintptr, request uint, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(request), uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package terminal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of Color.
+const (
+	colorDefault uint8 = iota
+	colorANSI          // One of the 16 ANSI colors
+	colorIndexed       // An entry of the 256 color palette
+	colorRGB
+)
+
+// Color is a text color: one of the 16 ANSI colors, an entry of the 256
+// color palette or an RGB color. The zero Color is the terminal's default.
+type Color struct {
+	kind    uint8
+	index   uint8
+	r, g, b uint8
+}
+
+// RGB returns the color with the given red, green and blue components.
+func RGB(r, g, b uint8) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// colorNames are the names of the 16 ANSI colors, in order.
+var colorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright-black", "bright-red", "bright-green", "bright-yellow",
+	"bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+}
+
+// ansiRGB are xterm's default values of the 16 ANSI colors, used to find
+// the one closest to other colors.
+var ansiRGB = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the channel values of the 6x6x6 color cube of the 256
+// color palette.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// ParseColor parses a color name such as "cyan" or "bright-red", an index
+// of the 256 color palette such as "208", or an RGB color such as
+// "#ff8700". "" and "default" are the terminal's default color.
+func ParseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "default" {
+		return Color{}, nil
+	}
+	for i, name := range colorNames {
+		if s == name {
+			return Color{kind: colorANSI, index: uint8(i)}, nil
+		}
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && len(hex) == 6 {
+			return RGB(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+		}
+		return Color{}, fmt.Errorf("invalid RGB color %q, want #rrggbb", s)
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return Color{kind: colorIndexed, index: uint8(n)}, nil
+	}
+	return Color{}, fmt.Errorf("unknown color %q, want a name such as cyan, a number from 0 to 255 or #rrggbb", s)
+}
+
+// sgr returns the SGR parameters that select c as the foreground or
+// background color, downgraded to the closest color a terminal with the
+// given color depth shows. It returns "" for the default color and on
+// terminals without colors.
+func (c Color) sgr(depth int, background bool) string {
+	if c.kind == colorDefault || depth <= 0 {
+		return ""
+	}
+	base := 30
+	if background {
+		base = 40
+	}
+
+	c = c.downgrade(depth)
+	switch c.kind {
+	case colorANSI:
+		if c.index >= 8 {
+			return strconv.Itoa(base + 60 + int(c.index) - 8)
+		}
+		return strconv.Itoa(base + int(c.index))
+	case colorIndexed:
+		return fmt.Sprintf("%d;5;%d", base+8, c.index)
+	default:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, c.r, c.g, c.b)
+	}
+}
+
+// downgrade returns the color closest to c that a terminal with the given
+// color depth shows: 256 colors from 8 bits and 16 below.
+func (c Color) downgrade(depth int) Color {
+	switch {
+	case depth >= 24 || c.kind == colorANSI:
+		return c
+	case depth >= 8 && c.kind == colorRGB:
+		return Color{kind: colorIndexed, index: uint8(color256(c.r, c.g, c.b))}
+	case depth >= 8:
+		return c
+	case c.kind == colorIndexed && c.index < 16:
+		return Color{kind: colorANSI, index: c.index}
+	default:
+		r, g, b := c.rgb()
+		return Color{kind: colorANSI, index: uint8(nearestANSI(r, g, b))}
+	}
+}
+
+// rgb returns the red, green and blue components of c.
+func (c Color) rgb() (r, g, b uint8) {
+	switch {
+	case c.kind == colorRGB:
+		return c.r, c.g, c.b
+	case c.index < 16:
+		return ansiRGB[c.index][0], ansiRGB[c.index][1], ansiRGB[c.index][2]
+	case c.index < 232:
+		i := int(c.index) - 16
+		return uint8(cubeLevels[i/36]), uint8(cubeLevels[i/6%6]), uint8(cubeLevels[i%6])
+	default:
+		level := uint8(8 + 10*(int(c.index)-232))
+		return level, level, level
+	}
+}
+
+// color256 returns the index of the 256 color palette entry closest to an
+// RGB color, from the color cube or the gray ramp.
+func color256(r, g, b uint8) int {
+	level := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	ri, gi, bi := level(r), level(g), level(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDistance := distance(r, g, b, cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	// The gray ramp runs from 8 to 238 in steps of 10.
+	average := (int(r) + int(g) + int(b)) / 3
+	gray := min(max((average-3)/10, 0), 23)
+	grayLevel := 8 + 10*gray
+	if distance(r, g, b, grayLevel, grayLevel, grayLevel) < cubeDistance {
+		return 232 + gray
+	}
+	return cube
+}
+
+// nearestANSI returns the index of the ANSI color closest to an RGB color.
+func nearestANSI(r, g, b uint8) int {
+	best, bestDistance := 0, -1
+	for i, c := range ansiRGB {
+		if d := distance(r, g, b, int(c[0]), int(c[1]), int(c[2])); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// distance returns the squared distance between two RGB colors.
+func distance(r, g, b uint8, r2, g2, b2 int) int {
+	dr, dg, db := int(r)-r2, int(g)-g2, int(b)-b2
+	return dr*dr + dg*dg + db*db
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// adaptSGR rewrites the colors set by an SGR sequence for a terminal with
+// the given color depth, downgrading those it cannot show and removing
+// them all if it has no colors. Other attributes are kept. It returns ""
+// if nothing is left of the sequence.
+func adaptSGR(seq string, depth int) string {
+	body := seq[2 : len(seq)-1]
+	if depth >= 24 || body == "" {
+		return seq
+	}
+
+	params := strings.Split(body, ";")
+	var out []string
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		n, err := strconv.Atoi(p)
+		switch {
+		case err != nil:
+			// Colon separated colors, e.g. 38:2::255:135:0, are only
+			// understood by truecolor terminals. Other subparameters,
+			// such as the underline style 4:3, are kept.
+			if !strings.HasPrefix(p, "38:") && !strings.HasPrefix(p, "48:") && !strings.HasPrefix(p, "58:") {
+				out = append(out, p)
+			}
+		case n == 38 || n == 48:
+			c, used := extendedColor(params[i+1:])
+			i += used
+			if s := c.sgr(depth, n == 48); s != "" {
+				out = append(out, s)
+			}
+		case n == 58:
+			// Underline colors are rarely supported without truecolor.
+			_, used := extendedColor(params[i+1:])
+			i += used
+		case n >= 30 && n <= 39 || n >= 40 && n <= 49 || n >= 90 && n <= 97 || n >= 100 && n <= 107:
+			if depth > 0 {
+				out = append(out, p)
+			}
+		default:
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(out, ";") + "m"
+}
+
+// extendedColor parses the parameters after 38, 48 or 58, "5;n" or
+// "2;r;g;b", and returns the color and the number of parameters used.
+// Malformed colors use up the remaining parameters, which cannot be read
+// reliably, and are the default color.
+func extendedColor(params []string) (Color, int) {
+	values := make([]uint8, 0, 4)
+	for _, p := range params[:min(len(params), 4)] {
+		n, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			break
+		}
+		values = append(values, uint8(n))
+	}
+
+	switch {
+	case len(values) >= 2 && values[0] == 5:
+		return Color{kind: colorIndexed, index: values[1]}, 2
+	case len(values) >= 4 && values[0] == 2:
+		return RGB(values[1], values[2], values[3]), 4
+	default:
+		return Color{}, len(params)
+	}
+}
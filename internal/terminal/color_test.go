@@ -0,0 +1,107 @@
+package terminal
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Color
+		wantErr bool
+	}{
+		{s: "", want: Color{}},
+		{s: "default", want: Color{}},
+		{s: "cyan", want: Color{kind: colorANSI, index: 6}},
+		{s: "Bright-Red", want: Color{kind: colorANSI, index: 9}},
+		{s: "208", want: Color{kind: colorIndexed, index: 208}},
+		{s: "#FF8700", want: RGB(255, 135, 0)},
+		{s: "#fff", wantErr: true},
+		{s: "256", wantErr: true},
+		{s: "teal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseColor(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseColor(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestColor_sgr(t *testing.T) {
+	orange := RGB(255, 135, 0)
+	tests := []struct {
+		name       string
+		color      Color
+		depth      int
+		background bool
+		want       string
+	}{
+		{name: "truecolor", color: orange, depth: 24, want: "38;2;255;135;0"},
+		{name: "truecolor background", color: orange, depth: 24, background: true, want: "48;2;255;135;0"},
+		{name: "rgb to 256 colors", color: orange, depth: 8, want: "38;5;208"},
+		{name: "rgb to 16 colors", color: orange, depth: 4, want: "33"},
+		{name: "256 colors to 16", color: Color{kind: colorIndexed, index: 21}, depth: 4, want: "34"},
+		{name: "low palette entry to 16", color: Color{kind: colorIndexed, index: 9}, depth: 4, want: "91"},
+		{name: "ansi background", color: Color{kind: colorANSI, index: 1}, depth: 4, background: true, want: "41"},
+		{name: "no colors", color: orange, depth: 0, want: ""},
+		{name: "default", color: Color{}, depth: 24, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.color.sgr(tt.depth, tt.background); got != tt.want {
+				t.Errorf("sgr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColor256(t *testing.T) {
+	tests := []struct {
+		r, g, b uint8
+		want    int
+	}{
+		{r: 0, g: 0, b: 0, want: 16},
+		{r: 255, g: 255, b: 255, want: 231},
+		{r: 255, g: 135, b: 0, want: 208},
+		{r: 128, g: 128, b: 128, want: 244},
+	}
+
+	for _, tt := range tests {
+		if got := color256(tt.r, tt.g, tt.b); got != tt.want {
+			t.Errorf("color256(%d, %d, %d) = %d, want %d", tt.r, tt.g, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptSGR(t *testing.T) {
+	tests := []struct {
+		name  string
+		seq   string
+		depth int
+		want  string
+	}{
+		{name: "truecolor kept", seq: "\033[1;38;2;255;135;0m", depth: 24, want: "\033[1;38;2;255;135;0m"},
+		{name: "rgb to 256 colors", seq: "\033[1;38;2;255;135;0m", depth: 8, want: "\033[1;38;5;208m"},
+		{name: "rgb background to 16 colors", seq: "\033[48;2;0;0;238;4m", depth: 4, want: "\033[44;4m"},
+		{name: "256 colors kept", seq: "\033[38;5;208m", depth: 8, want: "\033[38;5;208m"},
+		{name: "no colors keeps styles", seq: "\033[1;31;48;5;4m", depth: 0, want: "\033[1m"},
+		{name: "only colors", seq: "\033[31m", depth: 0, want: ""},
+		{name: "reset", seq: "\033[m", depth: 0, want: "\033[m"},
+		{name: "colon color dropped", seq: "\033[38:2::255:0:0;4:3m", depth: 8, want: "\033[4:3m"},
+		{name: "underline color dropped", seq: "\033[4;58;5;1m", depth: 8, want: "\033[4m"},
+		{name: "malformed color", seq: "\033[38;5m", depth: 8, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adaptSGR(tt.seq, tt.depth); got != tt.want {
+				t.Errorf("adaptSGR(%q, %d) = %q, want %q", tt.seq, tt.depth, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package terminal
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// hyperlinkPrograms are TERM_PROGRAM values of terminals that support OSC 8
+// hyperlinks.
+var hyperlinkPrograms = []string{"iTerm.app", "WezTerm", "vscode", "ghostty", "Tabby", "Hyper"}
+
+// linkSchemes are the URL schemes links may use. Others are shown as text.
+var linkSchemes = []string{"http", "https", "mailto"}
+
+// bareURL matches URLs in running text. Trailing punctuation is removed by
+// trimURL.
+var bareURL = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// detectHyperlinks reports whether the terminal supports OSC 8 hyperlinks.
+// FORCE_HYPERLINK overrides detection: "0" disables them, any other value
+// enables them.
+func detectHyperlinks(env *Environment, term string) bool {
+	if force, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		return force != "0"
+	}
+
+	switch {
+	case term == "dumb":
+		return false
+	case env.IsITerm2, slices.Contains(hyperlinkPrograms, os.Getenv("TERM_PROGRAM")):
+		return true
+	case term == "xterm-kitty", strings.HasPrefix(term, "foot"), strings.HasPrefix(term, "alacritty"):
+		return true
+	}
+	if _, ok := os.LookupEnv("KITTY_WINDOW_ID"); ok {
+		return true
+	}
+	if _, ok := os.LookupEnv("WT_SESSION"); ok {
+		return true
+	}
+	// GNOME Terminal and other VTE terminals since 0.50, Konsole since 20.04.
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	if v, err := strconv.Atoi(os.Getenv("KONSOLE_VERSION")); err == nil && v >= 200400 {
+		return true
+	}
+	return false
+}
+
+// Link returns text linked to target with an OSC 8 hyperlink, or text alone
+// if the terminal does not support hyperlinks or target is not an allowed
+// URL.
+func (f *Formatter) Link(target, text string) string {
+	if !f.env.Hyperlinks || !allowedLink(target) {
+		return text
+	}
+	return linkStart(f.env, target) + text + linkEnd(f.env)
+}
+
+// linkify turns the URLs in text into hyperlinks on terminals that support
+// them.
+func linkify(env *Environment, text string) string {
+	if !env.Hyperlinks {
+		return text
+	}
+
+	f := &Formatter{env: env}
+	var b strings.Builder
+	last := 0
+	for _, loc := range bareURL.FindAllStringIndex(text, -1) {
+		target := trimURL(text[loc[0]:loc[1]])
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(f.Link(target, target))
+		last = loc[0] + len(target)
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// linkStart opens an OSC 8 hyperlink to target.
+func linkStart(env *Environment, target string) string {
+	return env.StartSeq + "8;;" + target + env.EndSeq
+}
+
+// linkEnd closes an OSC 8 hyperlink.
+func linkEnd(env *Environment) string {
+	return env.StartSeq + "8;;" + env.EndSeq
+}
+
+// trimURL removes punctuation that ends the sentence around a URL rather
+// than the URL itself, including closing parentheses without a match.
+func trimURL(s string) string {
+	for len(s) > 0 {
+		switch c := s[len(s)-1]; {
+		case strings.IndexByte(".,:;!?'*_", c) >= 0:
+			s = s[:len(s)-1]
+		case c == ')' && strings.Count(s, ")") > strings.Count(s, "("):
+			s = s[:len(s)-1]
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+// allowedLink reports whether target is an absolute URL with a scheme in
+// linkSchemes.
+func allowedLink(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && slices.Contains(linkSchemes, strings.ToLower(u.Scheme))
+}
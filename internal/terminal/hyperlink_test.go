@@ -0,0 +1,168 @@
+package terminal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestDetector_Hyperlinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    bool
+	}{
+		{name: "plain xterm", envVars: map[string]string{"TERM": "xterm"}, want: false},
+		{name: "iTerm2", envVars: map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "iTerm.app"}, want: true},
+		{name: "WezTerm", envVars: map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, want: true},
+		{name: "kitty", envVars: map[string]string{"TERM": "xterm-kitty"}, want: true},
+		{name: "kitty inside tmux", envVars: map[string]string{"TERM": "screen-256color", "KITTY_WINDOW_ID": "1"}, want: true},
+		{name: "Windows Terminal", envVars: map[string]string{"TERM": "xterm-256color", "WT_SESSION": "abc"}, want: true},
+		{name: "recent VTE", envVars: map[string]string{"TERM": "xterm-256color", "VTE_VERSION": "7600"}, want: true},
+		{name: "old VTE", envVars: map[string]string{"TERM": "xterm-256color", "VTE_VERSION": "4800"}, want: false},
+		{name: "Konsole", envVars: map[string]string{"TERM": "xterm-256color", "KONSOLE_VERSION": "230804"}, want: true},
+		{name: "forced on", envVars: map[string]string{"TERM": "xterm", "FORCE_HYPERLINK": "1"}, want: true},
+		{name: "forced off", envVars: map[string]string{"TERM": "xterm-kitty", "FORCE_HYPERLINK": "0"}, want: false},
+		{name: "dumb terminal", envVars: map[string]string{"TERM": "dumb", "WT_SESSION": "abc"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"TERM_PROGRAM", "SSH_CLIENT", "KITTY_WINDOW_ID", "WT_SESSION", "VTE_VERSION", "KONSOLE_VERSION", "FORCE_HYPERLINK"} {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			env, err := NewDetector().Detect()
+			if err != nil {
+				t.Fatalf("Detect() unexpected error: %v", err)
+			}
+			if env.Hyperlinks != tt.want {
+				t.Errorf("Hyperlinks = %v, want %v", env.Hyperlinks, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter_Link(t *testing.T) {
+	links := &Environment{StartSeq: "\033]", EndSeq: "\a", Hyperlinks: true}
+	tmux := &Environment{StartSeq: "\033Ptmux;\033\033]", EndSeq: "\a\033\\", Hyperlinks: true}
+	plain := &Environment{StartSeq: "\033]", EndSeq: "\a"}
+
+	tests := []struct {
+		name   string
+		env    *Environment
+		target string
+		want   string
+	}{
+		{name: "hyperlink", env: links, target: "https://example.com", want: "\033]8;;https://example.com\aDocs\033]8;;\a"},
+		{name: "tmux passthrough", env: tmux, target: "https://example.com", want: "\033Ptmux;\033\033]8;;https://example.com\a\033\\Docs\033Ptmux;\033\033]8;;\a\033\\"},
+		{name: "unsupported terminal", env: plain, target: "https://example.com", want: "Docs"},
+		{name: "disallowed scheme", env: links, target: "javascript:alert(1)", want: "Docs"},
+		{name: "relative url", env: links, target: "/wiki", want: "Docs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFormatter(tt.env).Link(tt.target, "Docs"); got != tt.want {
+				t.Errorf("Link() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter_Render_URLs(t *testing.T) {
+	links := &Environment{StartSeq: "\033]", EndSeq: "\a", ColorDepth: 4, Hyperlinks: true}
+	plain := &Environment{StartSeq: "\033]", EndSeq: "\a"}
+	link := func(target string) string {
+		return "\033]8;;" + target + "\a" + target + "\033]8;;\a"
+	}
+
+	tests := []struct {
+		name string
+		env  *Environment
+		msg  *network.Message
+		want string
+	}{
+		{
+			name: "inline url in text",
+			env:  links,
+			msg:  &network.Message{ContentType: network.ContentTypeText, Body: []byte("See https://wiki.example.com/Outage_(2024), then reply.")},
+			want: "See " + link("https://wiki.example.com/Outage_(2024)") + ", then reply.",
+		},
+		{
+			name: "inline url in parentheses",
+			env:  links,
+			msg:  &network.Message{ContentType: network.ContentTypeText, Body: []byte("(https://example.com/a)")},
+			want: "(" + link("https://example.com/a") + ")",
+		},
+		{
+			name: "inline url in ansi",
+			env:  links,
+			msg:  &network.Message{ContentType: network.ContentTypeANSI, Body: []byte("\033[31mhttps://example.com\033[0m")},
+			want: "\033[31m" + link("https://example.com") + "\033[0m",
+		},
+		{
+			name: "metadata url",
+			env:  links,
+			msg: &network.Message{
+				ContentType: network.ContentTypeText,
+				Metadata:    network.Metadata{URL: "https://status.example.com/incidents/42"},
+				Body:        []byte("Maintenance tonight"),
+			},
+			want: "Maintenance tonight\n" + link("https://status.example.com/incidents/42"),
+		},
+		{
+			name: "plain fallback",
+			env:  plain,
+			msg: &network.Message{
+				ContentType: network.ContentTypeText,
+				Metadata:    network.Metadata{URL: "https://status.example.com"},
+				Body:        []byte("See https://wiki.example.com"),
+			},
+			want: "See https://wiki.example.com\nhttps://status.example.com",
+		},
+		{
+			name: "unsafe metadata url is dropped",
+			env:  links,
+			msg: &network.Message{
+				ContentType: network.ContentTypeText,
+				Metadata:    network.Metadata{URL: "https://e.com/\033]0;x\a"},
+				Body:        []byte("Hi"),
+			},
+			want: "Hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFormatter(tt.env).Render(tt.msg); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "https://example.com", want: "https://example.com"},
+		{input: "https://example.com.", want: "https://example.com"},
+		{input: "https://example.com/?q=1!", want: "https://example.com/?q=1"},
+		{input: "https://en.wikipedia.org/wiki/Go_(game)", want: "https://en.wikipedia.org/wiki/Go_(game)"},
+		{input: "https://example.com/a)", want: "https://example.com/a"},
+		{input: "https://example.com/a).", want: "https://example.com/a"},
+	}
+
+	for _, tt := range tests {
+		if got := trimURL(tt.input); got != tt.want {
+			t.Errorf("trimURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package terminal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"strings"
+	"time"
+)
+
+// Limits on the images and GIF animations decoded, which bound the memory
+// and time they take. They are checked before anything is decoded.
+const (
+	maxAnimationFrames = 200
+	maxAnimationPixels = 1 << 22 // Canvas pixels, e.g. 2048x2048
+	maxFramePixels     = 1 << 25 // Pixels of all frames decoded together
+)
+
+// defaultFrameDelay is used for frames that declare no delay, as browsers
+// do, so they do not flash past.
+const defaultFrameDelay = 100 * time.Millisecond
+
+// defaultImageRows is the most rows a half-block image takes on a terminal
+// whose height is unknown.
+const defaultImageRows = 24
+
+// errImageTooLarge is returned for images and animations whose canvas
+// exceeds maxAnimationPixels.
+var errImageTooLarge = errors.New("image is too large")
+
+// eachFrame decodes a GIF and calls fn with each frame composited over the
+// ones before it, as their disposal methods require, and the time it is
+// shown. The canvas is reused, so fn must not keep it. It returns the
+// number of times the animation plays, or 0 if it loops forever.
+func eachFrame(data []byte, fn func(canvas *image.RGBA, delay time.Duration) error) (int, error) {
+	cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode GIF: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxAnimationPixels {
+		return 0, errImageTooLarge
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(limitFrames(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode GIF: %w", err)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	var previous *image.RGBA
+	for i, frame := range g.Image {
+		bounds := frame.Bounds()
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(canvas.Bounds())
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, bounds, frame, bounds.Min, draw.Over)
+		if err := fn(canvas, frameDelay(g.Delay[i])); err != nil {
+			return 0, err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, bounds, image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			copy(canvas.Pix, previous.Pix)
+		}
+	}
+
+	switch {
+	case g.LoopCount == 0:
+		return 0, nil
+	case g.LoopCount < 0:
+		return 1, nil
+	default:
+		return g.LoopCount + 1, nil
+	}
+}
+
+// limitFrames returns a GIF cut after the frames within maxAnimationFrames
+// and maxFramePixels, so that only those are decoded. The first frame is
+// always kept. The blocks are walked without decompressing them; malformed
+// data is returned as it is, for the decoder to report.
+func limitFrames(data []byte) []byte {
+	const (
+		headerSize     = 13 // Signature, version and logical screen descriptor
+		descriptorSize = 10 // Image separator and descriptor
+	)
+	colorTable := func(flags byte) int {
+		if flags&0x80 == 0 {
+			return 0
+		}
+		return 3 << (flags&7 + 1)
+	}
+	// subBlocks returns the offset after the data sub-blocks at i.
+	subBlocks := func(i int) int {
+		for i < len(data) && data[i] != 0 {
+			i += 1 + int(data[i])
+		}
+		return i + 1
+	}
+
+	if len(data) < headerSize {
+		return data
+	}
+	i := headerSize + colorTable(data[10])
+	frames, pixels := 0, 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // Extension: a label, then data sub-blocks
+			i = subBlocks(i + 2)
+		case 0x2c: // Image: a descriptor, a color table, the LZW code size and data sub-blocks
+			if i+descriptorSize > len(data) {
+				return data
+			}
+			d := data[i : i+descriptorSize]
+			width, height := int(d[5])|int(d[6])<<8, int(d[7])|int(d[8])<<8
+			if frames > 0 && (frames == maxAnimationFrames || pixels+width*height > maxFramePixels) {
+				return append(data[:i:i], 0x3b)
+			}
+			frames++
+			pixels += width * height
+			i = subBlocks(i + descriptorSize + colorTable(d[9]) + 1)
+		default: // Trailer, or data the decoder rejects
+			return data
+		}
+	}
+	return data
+}
+
+// decodeImage decodes a still image, or the first frame of an animation,
+// refusing ones larger than maxAnimationPixels before decoding them.
+func decodeImage(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxAnimationPixels {
+		return nil, errImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// frameDelay converts a GIF frame delay in hundredths of a second.
+func frameDelay(delay int) time.Duration {
+	if delay <= 1 {
+		return defaultFrameDelay
+	}
+	return time.Duration(delay) * 10 * time.Millisecond
+}
+
+// halfBlocks reports whether images can be drawn with half block
+// characters, which takes at least 256 colors to be recognizable.
+func halfBlocks(env *Environment) bool {
+	return env.ColorDepth >= 8
+}
+
+// fitHalfBlock returns the cells an image of the given pixel size occupies
+// when drawn with half blocks, one column and half a row per pixel, scaled
+// down to at most maxColumns and the height of the terminal.
+func fitHalfBlock(env *Environment, width, height, maxColumns int) imageBox {
+	maxRows := defaultImageRows
+	if env.Rows > 1 {
+		maxRows = env.Rows - 1
+	}
+
+	columns := max(min(width, maxColumns), 1)
+	if rows := ceilDiv(height*columns, width*2); rows > maxRows {
+		columns = max(min(width*maxRows*2/height, columns), 1)
+	}
+	return imageBox{columns: columns, rows: min(max(ceilDiv(height*columns, width*2), 1), maxRows)}
+}
+
+// halfBlock draws img scaled to box with upper half block characters, the
+// foreground color painting the top pixel of each cell and the background
+// the bottom one. Transparent pixels show the terminal's background.
+func halfBlock(env *Environment, img image.Image, box imageBox) string {
+	bounds := img.Bounds()
+	var b strings.Builder
+	for row := range box.rows {
+		if row > 0 {
+			b.WriteByte('\n')
+		}
+		fg, bg := "", ""
+		for column := range box.columns {
+			x := bounds.Min.X + column*bounds.Dx()/box.columns
+			top := opaque(img.At(x, bounds.Min.Y+2*row*bounds.Dy()/(2*box.rows)))
+			bottom := opaque(img.At(x, bounds.Min.Y+(2*row+1)*bounds.Dy()/(2*box.rows)))
+
+			cell := "▀"
+			switch {
+			case top == nil && bottom == nil:
+				cell = " "
+			case top == nil:
+				top, bottom, cell = bottom, nil, "▄"
+			}
+			if top != nil {
+				if seq := "\033[" + top.sgr(env.ColorDepth, false) + "m"; seq != fg {
+					b.WriteString(seq)
+					fg = seq
+				}
+			}
+			seq := "\033[49m"
+			if bottom != nil {
+				seq = "\033[" + bottom.sgr(env.ColorDepth, true) + "m"
+			}
+			if seq != bg && (bg != "" || bottom != nil) {
+				b.WriteString(seq)
+			}
+			bg = seq
+			b.WriteString(cell)
+		}
+		b.WriteString("\033[0m")
+	}
+	return b.String()
+}
+
+// opaque returns c without alpha, or nil if it is mostly transparent.
+func opaque(c color.Color) *Color {
+	r, g, b, a := c.RGBA()
+	if a < 0x8000 {
+		return nil
+	}
+	rgb := RGB(uint8(r*0xffff/a>>8), uint8(g*0xffff/a>>8), uint8(b*0xffff/a>>8))
+	return &rgb
+}
@@ -0,0 +1,201 @@
+package terminal
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Colors of the test GIF palette.
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// testGIF encodes a 2x2 GIF whose frames fill the top row with the given
+// colors, over a transparent background.
+func testGIF(t *testing.T, loopCount int, disposal byte, colors ...color.Color) []byte {
+	t.Helper()
+	g := &gif.GIF{LoopCount: loopCount, Config: image.Config{Width: 2, Height: 2}}
+	palette := color.Palette{color.Transparent, red, blue}
+	for i, c := range colors {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), palette)
+		frame.Set(0, 0, c)
+		frame.Set(1, 0, c)
+		if i == 0 {
+			// Only the first frame paints the bottom left pixel.
+			frame.Set(0, 1, red)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 5)
+		g.Disposal = append(g.Disposal, disposal)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEachFrame(t *testing.T) {
+	tests := []struct {
+		name      string
+		loopCount int
+		disposal  byte
+		wantPlays int
+		wantKept  bool // Whether the first frame's bottom left pixel is still shown
+	}{
+		{name: "kept", loopCount: 0, disposal: gif.DisposalNone, wantPlays: 0, wantKept: true},
+		{name: "cleared", loopCount: -1, disposal: gif.DisposalBackground, wantPlays: 1, wantKept: false},
+		{name: "restored", loopCount: 2, disposal: gif.DisposalPrevious, wantPlays: 3, wantKept: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kept []bool
+			var delays []time.Duration
+			plays, err := eachFrame(testGIF(t, tt.loopCount, tt.disposal, red, blue), func(canvas *image.RGBA, delay time.Duration) error {
+				kept = append(kept, canvas.RGBAAt(0, 1) == red)
+				delays = append(delays, delay)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("eachFrame() error = %v", err)
+			}
+			if plays != tt.wantPlays {
+				t.Errorf("eachFrame() plays = %d, want %d", plays, tt.wantPlays)
+			}
+			if len(kept) != 2 || !kept[0] || kept[1] != tt.wantKept {
+				t.Errorf("eachFrame() bottom left pixel shown = %v, want [true %v]", kept, tt.wantKept)
+			}
+			if len(delays) != 2 || delays[1] != 50*time.Millisecond {
+				t.Errorf("eachFrame() delays = %v, want 50ms each", delays)
+			}
+		})
+	}
+
+	if _, err := eachFrame([]byte("GIF89a"), func(*image.RGBA, time.Duration) error { return nil }); err == nil {
+		t.Error("eachFrame() of a truncated GIF succeeded")
+	}
+}
+
+func TestLimitFrames(t *testing.T) {
+	colors := make([]color.Color, maxAnimationFrames+50)
+	for i := range colors {
+		colors[i] = []color.Color{red, blue}[i%2]
+	}
+	data := testGIF(t, 0, gif.DisposalNone, colors...)
+
+	g, err := gif.DecodeAll(bytes.NewReader(limitFrames(data)))
+	if err != nil {
+		t.Fatalf("Decoding the limited GIF failed: %v", err)
+	}
+	if len(g.Image) != maxAnimationFrames {
+		t.Errorf("Limited GIF has %d frames, want %d", len(g.Image), maxAnimationFrames)
+	}
+
+	short := testGIF(t, 0, gif.DisposalNone, red, blue)
+	if got := limitFrames(short); !bytes.Equal(got, short) {
+		t.Error("limitFrames() changed a GIF within the limits")
+	}
+	if got := limitFrames([]byte("GIF89a")); string(got) != "GIF89a" {
+		t.Errorf("limitFrames() = %q, want malformed data as it is", got)
+	}
+}
+
+func TestDecodeImage_TooLarge(t *testing.T) {
+	// A 4096x4096 logical screen, refused before any frame is read.
+	data := []byte("GIF89a\x00\x10\x00\x10\x00\x00\x00")
+	if _, err := decodeImage(data); err != errImageTooLarge {
+		t.Errorf("decodeImage() error = %v, want %v", err, errImageTooLarge)
+	}
+	if _, err := decodeImage(testGIF(t, 0, gif.DisposalNone, red)); err != nil {
+		t.Errorf("decodeImage() unexpected error: %v", err)
+	}
+}
+
+func TestFrameDelay(t *testing.T) {
+	if got := frameDelay(0); got != defaultFrameDelay {
+		t.Errorf("frameDelay(0) = %v, want %v", got, defaultFrameDelay)
+	}
+	if got := frameDelay(25); got != 250*time.Millisecond {
+		t.Errorf("frameDelay(25) = %v, want 250ms", got)
+	}
+}
+
+func TestFitHalfBlock(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           *Environment
+		width, height int
+		maxColumns    int
+		want          imageBox
+	}{
+		{name: "own size", env: &Environment{Rows: 40}, width: 20, height: 10, maxColumns: 80, want: imageBox{columns: 20, rows: 5}},
+		{name: "narrowed", env: &Environment{Rows: 40}, width: 200, height: 100, maxColumns: 40, want: imageBox{columns: 40, rows: 10}},
+		{name: "shortened", env: &Environment{Rows: 11}, width: 100, height: 100, maxColumns: 80, want: imageBox{columns: 20, rows: 10}},
+		{name: "unknown height", env: &Environment{}, width: 100, height: 200, maxColumns: 80, want: imageBox{columns: 24, rows: 24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fitHalfBlock(tt.env, tt.width, tt.height, tt.maxColumns); got != tt.want {
+				t.Errorf("fitHalfBlock() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHalfBlock(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, red) // Red over red
+	img.Set(0, 1, red)
+	img.Set(1, 0, red)  // Red over transparent
+	img.Set(2, 1, blue) // Transparent over blue
+
+	tests := []struct {
+		name string
+		env  *Environment
+		want string
+	}{
+		{
+			name: "truecolor",
+			env:  &Environment{ColorDepth: 24},
+			want: "\033[38;2;255;0;0m\033[48;2;255;0;0m▀\033[49m▀\033[38;2;0;0;255m▄\033[0m",
+		},
+		{
+			name: "256 colors",
+			env:  &Environment{ColorDepth: 8},
+			want: "\033[38;5;196m\033[48;5;196m▀\033[49m▀\033[38;5;21m▄\033[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := halfBlock(tt.env, img, imageBox{columns: 3, rows: 1}); got != tt.want {
+				t.Errorf("halfBlock() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := halfBlock(&Environment{ColorDepth: 24}, image.NewRGBA(image.Rect(0, 0, 2, 4)), imageBox{columns: 2, rows: 2}); got != "  \033[0m\n  \033[0m" {
+		t.Errorf("halfBlock() of a transparent image = %q", got)
+	}
+}
+
+func TestFormatter_Render_HalfBlocks(t *testing.T) {
+	env := &Environment{ColorDepth: 24, Columns: 80, Rows: 24}
+	got := NewFormatter(env).Render(&network.Message{ContentType: network.ContentTypePNG, Body: testPNG(t, 4, 4)})
+
+	want := strings.Repeat("\033[38;2;0;0;0m\033[48;2;0;0;0m▀▀▀▀\033[0m\n", 2)
+	if got != strings.TrimSuffix(want, "\n") {
+		t.Errorf("Render() = %q, want two rows of black half blocks", got)
+	}
+}
@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/png" // Register PNG for image.DecodeConfig
+	"strings"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Cell size in pixels assumed for terminals that do not report theirs,
+// typical of a monospace font with a 1:2 aspect ratio.
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// Layout of an image with text beside it.
+const (
+	layoutGap       = 2  // Columns between the image and the text
+	minCaptionWidth = 20 // Narrowest text column worth placing beside an image
+)
+
+// imageBox is the area of the terminal an image occupies, in cells.
+type imageBox struct {
+	columns, rows int
+}
+
+// fitImage returns the cells an image of the given pixel size occupies,
+// scaled down to at most maxColumns but never enlarged. It fails if the
+// image size cannot be read.
+func fitImage(env *Environment, data []byte, maxColumns int) (imageBox, bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || maxColumns <= 0 {
+		return imageBox{}, false
+	}
+
+	cellWidth, cellHeight := env.CellWidth, env.CellHeight
+	if cellWidth <= 0 || cellHeight <= 0 {
+		cellWidth, cellHeight = defaultCellWidth, defaultCellHeight
+	}
+
+	columns := min(ceilDiv(cfg.Width, cellWidth), maxColumns)
+	// The height the image is scaled to when it is columns cells wide.
+	height := cfg.Height * columns * cellWidth / cfg.Width
+	return imageBox{columns: columns, rows: max(ceilDiv(height, cellHeight), 1)}, true
+}
+
+// imageBeside places an image on the left of text lines, top aligned. image
+// draws the image at the cursor in box. Space for the taller of the two is
+// scrolled into view first, so the cursor can move back up over it, and
+// the cursor is left on the last row.
+func imageBeside(image string, box imageBox, lines []string) string {
+	height := max(box.rows, len(lines))
+
+	var b strings.Builder
+	if height > 1 {
+		fmt.Fprintf(&b, "%s\033[%dA", strings.Repeat("\n", height-1), height-1)
+	}
+	// Draw the image, then return to its top left corner.
+	b.WriteString("\r\0337" + image + "\0338")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "\033[%dG%s", box.columns+layoutGap+1, line)
+	}
+	b.WriteString(strings.Repeat("\n", height-max(len(lines), 1)))
+	return b.String()
+}
+
+// captionLines renders caption as Markdown wrapped to width columns.
+func captionLines(env *Environment, caption string, width int) []string {
+	narrow := *env
+	narrow.Columns = width
+	return strings.Split(renderMarkdown(&narrow, &network.Message{Body: []byte(caption)}), "\n")
+}
+
+// ceilDiv returns a / b rounded up.
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,133 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// testPNG encodes a blank PNG image of the given size.
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFitImage(t *testing.T) {
+	data := testPNG(t, 200, 100)
+
+	tests := []struct {
+		name       string
+		env        *Environment
+		maxColumns int
+		want       imageBox
+	}{
+		{name: "own size", env: &Environment{CellWidth: 10, CellHeight: 20}, maxColumns: 40, want: imageBox{columns: 20, rows: 5}},
+		{name: "scaled down", env: &Environment{CellWidth: 10, CellHeight: 20}, maxColumns: 10, want: imageBox{columns: 10, rows: 3}},
+		{name: "reported cell size", env: &Environment{CellWidth: 8, CellHeight: 16}, maxColumns: 40, want: imageBox{columns: 25, rows: 7}},
+		{name: "unknown cell size", env: &Environment{}, maxColumns: 40, want: imageBox{columns: 20, rows: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := fitImage(tt.env, data, tt.maxColumns)
+			if !ok {
+				t.Fatal("fitImage() failed")
+			}
+			if got != tt.want {
+				t.Errorf("fitImage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := fitImage(&Environment{}, []byte("not a png"), 40); ok {
+		t.Error("fitImage() of invalid data succeeded")
+	}
+}
+
+func TestImageBeside(t *testing.T) {
+	tests := []struct {
+		name  string
+		box   imageBox
+		lines []string
+		want  string
+	}{
+		{
+			name:  "image taller than the text",
+			box:   imageBox{columns: 4, rows: 3},
+			lines: []string{"Hello"},
+			want:  "\n\n\033[2A\r\0337IMG\0338\033[7GHello\n\n",
+		},
+		{
+			name:  "text taller than the image",
+			box:   imageBox{columns: 4, rows: 1},
+			lines: []string{"One", "Two"},
+			want:  "\n\033[1A\r\0337IMG\0338\033[7GOne\n\033[7GTwo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imageBeside("IMG", tt.box, tt.lines); got != tt.want {
+				t.Errorf("imageBeside() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter_Render_Caption(t *testing.T) {
+	data := testPNG(t, 200, 100)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	msg := &network.Message{
+		ContentType: network.ContentTypePNG,
+		Metadata:    network.Metadata{Title: "Logo", Caption: "Welcome to **Acme**, where the builds are always green."},
+		Body:        data,
+	}
+
+	t.Run("beside the image", func(t *testing.T) {
+		env := &Environment{StartSeq: "\033]", EndSeq: "\a", Graphics: []string{GraphicsITerm2}, Columns: 60, CellWidth: 10, CellHeight: 20}
+		image := "\033]1337;File=inline=1;size=" + strconv.Itoa(len(data)) + ";width=20;height=5;preserveAspectRatio=1:" + encoded + "\a"
+		want := "\n\n\n\n\033[4A\r\0337" + image + "\0338" +
+			"\033[23GWelcome to \033[1mAcme\033[0m, where the builds are\n" +
+			"\033[23Galways green.\n\n\n"
+
+		if got := NewFormatter(env).Render(msg); got != want {
+			t.Errorf("Render() =\n%q\nwant\n%q", got, want)
+		}
+	})
+
+	t.Run("kitty", func(t *testing.T) {
+		env := &Environment{Graphics: []string{GraphicsKitty}, Columns: 60, CellWidth: 10, CellHeight: 20}
+		got := NewFormatter(env).Render(msg)
+		if !strings.Contains(got, "\033_Gf=100,a=T,c=20,r=5,C=1,m=") || !strings.Contains(got, "\033[23GWelcome") {
+			t.Errorf("Render() = %q, want a kitty image in 20x5 cells beside the caption", got)
+		}
+	})
+
+	t.Run("below a narrow terminal", func(t *testing.T) {
+		env := &Environment{StartSeq: "\033]", EndSeq: "\a", Graphics: []string{GraphicsITerm2}, Columns: 40, CellWidth: 10, CellHeight: 20}
+		want := "\033]1337;File=inline=1;size=" + strconv.Itoa(len(data)) + ":" + encoded + "\a\n" +
+			"Welcome to \033[1mAcme\033[0m, where the builds are\nalways green."
+
+		if got := NewFormatter(env).Render(msg); got != want {
+			t.Errorf("Render() =\n%q\nwant\n%q", got, want)
+		}
+	})
+
+	t.Run("below alternative text", func(t *testing.T) {
+		got := NewFormatter(&Environment{Columns: 80}).Render(msg)
+		want := "[image: Logo]\nWelcome to \033[1mAcme\033[0m, where the builds are always green."
+		if got != want {
+			t.Errorf("Render() = %q, want %q", got, want)
+		}
+	})
+}
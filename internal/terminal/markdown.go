@@ -0,0 +1,874 @@
+package terminal
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Markdown layout defaults.
+const (
+	defaultColumns = 80 // Used when the terminal width is unknown
+	minWrapWidth   = 10 // Narrowest column nested blocks are wrapped to
+)
+
+// Markdown block syntax recognised by renderMarkdown.
+var (
+	mdHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*))?$`)
+	mdSetext     = regexp.MustCompile(`^ {0,3}(?:=+|-+)$`)
+	mdFence      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})")
+	mdQuote      = regexp.MustCompile(`^ {0,3}> ?`)
+	mdListItem   = regexp.MustCompile(`^( {0,3})([-*+]|(\d{1,9})([.)]))(?:[ \t]+|$)`)
+	mdIndentCode = regexp.MustCompile(`^(?: {4}|\t)`)
+)
+
+// mdSpecial are the bytes that may start inline markup.
+const mdSpecial = "\\`*_[!<"
+
+// mdEscapable are the characters a backslash makes literal.
+const mdEscapable = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// style is a set of text attributes.
+type style uint8
+
+const (
+	styleBold style = 1 << iota
+	styleFaint
+	styleItalic
+	styleUnderline
+	styleCode
+	styleHeading
+)
+
+// styleParams are the SGR parameters for each attribute. Code and headings
+// are only colored.
+var styleParams = []struct {
+	style  style
+	params string
+}{
+	{styleBold, "1"},
+	{styleFaint, "2"},
+	{styleItalic, "3"},
+	{styleUnderline, "4"},
+}
+
+// styleRoles are the theme roles that color text with each attribute, in
+// order of precedence.
+var styleRoles = []struct {
+	style style
+	role  string
+}{
+	{styleCode, RoleCode},
+	{styleHeading, RoleHeading},
+	{styleFaint, RoleMuted},
+}
+
+// span is a run of text with the same attributes and link target, or an
+// escape sequence written as it is.
+type span struct {
+	text  string
+	style style
+	link  string
+	raw   bool // text is an escape sequence, taking no room
+}
+
+// listItem is a list marker and the source lines of the item's content.
+type listItem struct {
+	marker string
+	lines  []string
+}
+
+// markdown renders Markdown for one terminal.
+type markdown struct {
+	env *Environment
+}
+
+// renderMarkdown displays Markdown with headings, emphasis, lists, quotes
+// and code blocks styled with SGR sequences, links as OSC 8 hyperlinks and
+// paragraphs wrapped to the terminal width. Control characters are removed
+// from the source first, so the server cannot drive the terminal.
+func renderMarkdown(env *Environment, msg *network.Message) string {
+	width := env.Columns
+	if width <= 0 {
+		width = defaultColumns
+	}
+
+	m := &markdown{env: env}
+	return strings.Join(m.blocks(strings.Split(sanitize(string(msg.Body)), "\n"), width, false), "\n")
+}
+
+// blocks renders Markdown source lines to terminal lines at most width
+// cells wide, with a blank line between blocks unless tight is set. Code
+// blocks are not wrapped.
+func (m *markdown) blocks(lines []string, width int, tight bool) []string {
+	var out, para []string
+	add := func(block []string) {
+		if len(block) == 0 {
+			return
+		}
+		if len(out) > 0 && !tight {
+			out = append(out, "")
+		}
+		out = append(out, block...)
+	}
+	flush := func() {
+		if len(para) > 0 {
+			add(m.paragraph(strings.Join(para, " "), 0, width))
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		switch {
+		case line == "":
+			flush()
+
+		case len(para) > 0 && mdSetext.MatchString(line):
+			level := 2
+			if strings.Contains(line, "=") {
+				level = 1
+			}
+			text := strings.Join(para, " ")
+			para = nil
+			add(m.heading(level, text, width))
+
+		case mdFence.MatchString(line):
+			flush()
+			match := mdFence.FindStringSubmatch(line)
+			indent, fence := len(match[1]), match[2]
+			var code []string
+			for i++; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+					break
+				}
+				code = append(code, trimIndent(lines[i], indent))
+			}
+			add(m.code(code))
+
+		case len(para) == 0 && mdIndentCode.MatchString(line):
+			var code []string
+			for ; i < len(lines); i++ {
+				if !mdIndentCode.MatchString(lines[i]) && strings.TrimSpace(lines[i]) != "" {
+					break
+				}
+				code = append(code, mdIndentCode.ReplaceAllString(lines[i], ""))
+			}
+			i--
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			add(m.code(code))
+
+		case mdHeading.MatchString(line):
+			flush()
+			match := mdHeading.FindStringSubmatch(line)
+			add(m.heading(len(match[1]), headingText(match[2]), width))
+
+		case isRule(line):
+			flush()
+			add([]string{m.line([]span{{text: strings.Repeat("─", width), style: styleFaint}})})
+
+		case mdQuote.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuote.ReplaceAllString(lines[i], ""))
+			}
+			i--
+			bar := m.line([]span{{text: "│", style: styleFaint}}) + " "
+			add(prefix(m.blocks(quoted, max(width-2, minWrapWidth), false), bar, bar))
+
+		case mdListItem.MatchString(line):
+			flush()
+			list, n := m.list(lines[i:], width)
+			i += n - 1
+			add(list)
+
+		default:
+			para = append(para, strings.TrimSpace(line))
+		}
+	}
+	flush()
+	return out
+}
+
+// list renders the list starting at lines[0] and returns how many lines it
+// spans. Lines indented past an item's marker continue the item and are
+// rendered recursively, so lists nest.
+func (m *markdown) list(lines []string, width int) ([]string, int) {
+	first := mdListItem.FindStringSubmatch(lines[0])
+	number, _ := strconv.Atoi(first[3])
+
+	var items []listItem
+	indent := 0
+	n := 0
+loop:
+	for ; n < len(lines); n++ {
+		line := lines[n]
+		if match := mdListItem.FindStringSubmatch(line); match != nil && (len(items) == 0 || leadingSpaces(line) < indent) {
+			// A different bullet or delimiter starts a new list.
+			if listKind(match) != listKind(first) || isRule(line) {
+				break
+			}
+			marker := "•"
+			if first[3] != "" {
+				marker = strconv.Itoa(number+len(items)) + "."
+			}
+			items = append(items, listItem{marker: marker, lines: []string{line[len(match[0]):]}})
+			indent = len(match[0])
+			continue
+		}
+
+		item := &items[len(items)-1]
+		switch {
+		case strings.TrimSpace(line) == "":
+			// A blank line ends the list unless the next line belongs
+			// to it.
+			if n+1 == len(lines) {
+				break loop
+			}
+			next := lines[n+1]
+			if leadingSpaces(next) >= indent {
+				item.lines = append(item.lines, "")
+			} else if !mdListItem.MatchString(next) {
+				break loop
+			}
+		case leadingSpaces(line) >= indent:
+			item.lines = append(item.lines, trimIndent(line, indent))
+		case strings.TrimSpace(item.lines[len(item.lines)-1]) != "" && !isBlockStart(line):
+			// Lazy continuation of the item's paragraph.
+			item.lines = append(item.lines, strings.TrimSpace(line))
+		default:
+			break loop
+		}
+	}
+
+	markerWidth := 0
+	for _, item := range items {
+		markerWidth = max(markerWidth, textWidth(item.marker))
+	}
+	var out []string
+	for _, item := range items {
+		first := fmt.Sprintf("%*s ", markerWidth, item.marker)
+		rest := strings.Repeat(" ", markerWidth+1)
+		tight := !slices.Contains(item.lines, "")
+		content := m.blocks(item.lines, max(width-markerWidth-1, minWrapWidth), tight)
+		if len(content) == 0 {
+			content = []string{""}
+		}
+		out = append(out, prefix(content, first, rest)...)
+	}
+	return out, n
+}
+
+// heading renders a heading. Top-level headings are also underlined.
+func (m *markdown) heading(level int, text string, width int) []string {
+	st := styleBold | styleHeading
+	if level == 1 {
+		st |= styleUnderline
+	}
+	return m.paragraph(text, st, width)
+}
+
+// paragraph renders inline Markdown wrapped to width.
+func (m *markdown) paragraph(text string, st style, width int) []string {
+	var out []string
+	for _, line := range wrap(m.inline(text, st, ""), width, false) {
+		out = append(out, m.line(line))
+	}
+	return out
+}
+
+// code renders the lines of a code block indented and unwrapped.
+func (m *markdown) code(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if line != "" {
+			out[i] = "  " + m.line([]span{{text: line, style: styleCode}})
+		}
+	}
+	return out
+}
+
+// inline parses emphasis, code spans, links, autolinks and backslash
+// escapes in s. Unmatched markup is kept as text.
+func (m *markdown) inline(s string, st style, link string) []span {
+	var spans []span
+	var text strings.Builder
+	unclosed := make(map[string]bool)
+	add := func(more ...span) {
+		if text.Len() > 0 {
+			spans = append(spans, m.text(text.String(), st, link)...)
+			text.Reset()
+		}
+		for _, sp := range more {
+			spans = appendSpan(spans, sp)
+		}
+	}
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte(mdEscapable, s[i+1]) >= 0 {
+				text.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+
+		case '`':
+			n := runLength(s[i:], c)
+			if end := closingRun(s, i+n, c, n); end >= 0 {
+				code := s[i+n : end]
+				if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				add(span{text: code, style: st | styleCode, link: link})
+				i = end + n
+				continue
+			}
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case '*', '_':
+			n := runLength(s[i:], c)
+			if n <= 3 && canOpen(s, i, n) && !unclosed[s[i:i+n]] {
+				if end := closingDelimiter(s, i+n, c, n); end >= 0 {
+					emphasis := [...]style{styleItalic, styleBold, styleBold | styleItalic}[n-1]
+					add(m.inline(s[i+n:end], st|emphasis, link)...)
+					i = end + n
+					continue
+				}
+				// Later delimiters of this kind cannot be closed either.
+				unclosed[s[i:i+n]] = true
+			}
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case '!', '[':
+			start := i
+			if c == '!' {
+				start++
+			}
+			if start < len(s) && s[start] == '[' {
+				if closeText := matching(s, start, '[', ']'); closeText >= 0 && closeText+1 < len(s) && s[closeText+1] == '(' {
+					if closeDest := matching(s, closeText+1, '(', ')'); closeDest >= 0 {
+						label := s[start+1 : closeText]
+						if c == '!' && label == "" {
+							label = "image"
+						}
+						add(m.link(label, destination(s[closeText+2:closeDest]), st)...)
+						i = closeDest + 1
+						continue
+					}
+				}
+			}
+
+		case '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				target := s[i+1 : i+end]
+				if !strings.ContainsAny(target, " \t<") && allowedLink(target) {
+					add(m.link(target, target, st)...)
+					i += end + 1
+					continue
+				}
+			}
+		}
+
+		// Plain text up to the next byte that may start markup.
+		j := i + 1
+		for j < len(s) && !strings.ContainsRune(mdSpecial, rune(s[j])) {
+			j++
+		}
+		text.WriteString(s[i:j])
+		i = j
+	}
+	add()
+	return spans
+}
+
+// text returns a span of plain text, with bare URLs outside links turned
+// into hyperlinks on terminals that support them.
+func (m *markdown) text(s string, st style, link string) []span {
+	if link != "" || !m.env.Hyperlinks {
+		return []span{{text: s, style: st, link: link}}
+	}
+
+	var spans []span
+	last := 0
+	for _, loc := range bareURL.FindAllStringIndex(s, -1) {
+		target := trimURL(s[loc[0]:loc[1]])
+		spans = appendSpan(spans, span{text: s[last:loc[0]], style: st})
+		spans = appendSpan(spans, span{text: target, style: st | styleUnderline, link: target})
+		last = loc[0] + len(target)
+	}
+	return appendSpan(spans, span{text: s[last:], style: st})
+}
+
+// link renders a link to target labeled with inline Markdown. Links are
+// underlined OSC 8 hyperlinks on terminals that support them and are
+// followed by the target in parentheses elsewhere. Targets with a scheme
+// outside linkSchemes are dropped and only the label is shown.
+func (m *markdown) link(label, target string, st style) []span {
+	if !allowedLink(target) {
+		return m.inline(label, st, "")
+	}
+	if m.env.Hyperlinks {
+		return m.inline(label, st|styleUnderline, target)
+	}
+	spans := m.inline(label, st, "")
+	if label != target {
+		spans = appendSpan(spans, span{text: " (" + target + ")", style: st})
+	}
+	return spans
+}
+
+// line renders spans as one terminal line, opening and closing styles and
+// hyperlinks around each run so none leak onto the next line.
+func (m *markdown) line(spans []span) string {
+	var b strings.Builder
+	var cur span
+	for _, sp := range spans {
+		if sp.text == "" {
+			continue
+		}
+		if sp.style != cur.style || sp.link != cur.link {
+			m.close(&b, cur)
+			m.open(&b, sp)
+			cur = sp
+		}
+		b.WriteString(sp.text)
+	}
+	m.close(&b, cur)
+	return b.String()
+}
+
+// open starts the style and hyperlink of sp.
+func (m *markdown) open(b *strings.Builder, sp span) {
+	if sp.link != "" {
+		b.WriteString(linkStart(m.env, sp.link))
+	}
+	if params := m.sgr(sp); params != "" {
+		fmt.Fprintf(b, "\033[%sm", params)
+	}
+}
+
+// close ends the style and hyperlink of sp.
+func (m *markdown) close(b *strings.Builder, sp span) {
+	if m.sgr(sp) != "" {
+		b.WriteString("\033[0m")
+	}
+	if sp.link != "" {
+		b.WriteString(linkEnd(m.env))
+	}
+}
+
+// sgr returns the SGR parameters for the style of sp, with the theme color
+// of its role, or "" if it is unstyled.
+func (m *markdown) sgr(sp span) string {
+	var params []string
+	for _, p := range styleParams {
+		if sp.style&p.style != 0 {
+			params = append(params, p.params)
+		}
+	}
+
+	color := ""
+	if sp.link != "" {
+		color = m.env.color(RoleLink)
+	}
+	for _, r := range styleRoles {
+		if sp.style&r.style != 0 && color == "" {
+			color = m.env.color(r.role)
+		}
+	}
+	if color != "" {
+		params = append(params, color)
+	}
+	return strings.Join(params, ";")
+}
+
+// wrap breaks spans into lines at most width cells wide at whitespace,
+// which is dropped where a line breaks, and splits words wider than width.
+// Raw spans take no room; those among dropped whitespace move to the start
+// of the next line. Unless keepSpaces is set, runs of whitespace become a
+// single space and leading and trailing whitespace is dropped. With it,
+// whitespace is kept as it is between words and before the first one, and
+// spans that fit are returned as they are.
+func wrap(spans []span, width int, keepSpaces bool) [][]span {
+	if keepSpaces && spansWidth(spans) <= width {
+		return [][]span{spans}
+	}
+
+	// Split into words, remembering the whitespace and raw spans before
+	// each one. The last gap is the one after the last word.
+	var words, gaps [][]span
+	var word, gap []span
+	for _, sp := range spans {
+		if sp.raw {
+			if len(word) > 0 {
+				word = append(word, sp)
+			} else {
+				gap = append(gap, sp)
+			}
+			continue
+		}
+		start := 0
+		for i, r := range sp.text {
+			if !unicode.IsSpace(r) {
+				continue
+			}
+			if i > start {
+				word = appendSpan(word, span{text: sp.text[start:i], style: sp.style, link: sp.link})
+			}
+			if len(word) > 0 {
+				words, gaps = append(words, word), append(gaps, gap)
+				word, gap = nil, nil
+			}
+			gap = appendSpan(gap, span{text: string(r), style: sp.style, link: sp.link})
+			start = i + utf8.RuneLen(r)
+		}
+		if start < len(sp.text) {
+			word = appendSpan(word, span{text: sp.text[start:], style: sp.style, link: sp.link})
+		}
+	}
+	if len(word) > 0 {
+		words, gaps = append(words, word), append(gaps, gap)
+		gap = nil
+	}
+
+	var lines [][]span
+	var line []span
+	lineWidth := 0
+	for k, word := range words {
+		gap := gaps[k]
+		if !keepSpaces {
+			gap = singleSpace(gap, line, word)
+		}
+		w, gw := spansWidth(word), spansWidth(gap)
+		switch {
+		case lineWidth > 0 && lineWidth+gw+w > width:
+			lines = append(lines, line)
+			line, lineWidth = rawSpans(gap), 0
+		case lineWidth > 0 || keepSpaces && len(lines) == 0:
+			line = append(line, gap...)
+			lineWidth += gw
+		default:
+			line = append(line, rawSpans(gap)...)
+		}
+
+		for lineWidth+w > width {
+			var head []span
+			head, word = splitSpans(word, width-lineWidth, lineWidth == 0)
+			line = append(line, head...)
+			w = spansWidth(word)
+			if w == 0 {
+				break
+			}
+			lines = append(lines, line)
+			line, lineWidth = nil, 0
+		}
+		line = append(line, word...)
+		lineWidth += w
+	}
+	line = append(line, rawSpans(gap)...)
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// singleSpace returns the space put between line and word in place of gap,
+// which takes the attributes of the text around it when both sides share
+// them. There is none at the start of a line.
+func singleSpace(gap, line, word []span) []span {
+	if len(line) == 0 {
+		return nil
+	}
+	last := line[len(line)-1]
+	sep := span{text: " "}
+	if g := gap[len(gap)-1]; g.style == last.style && g.link == last.link &&
+		word[0].style == last.style && word[0].link == last.link {
+		sep.style, sep.link = g.style, g.link
+	}
+	return []span{sep}
+}
+
+// rawSpans returns the raw spans among spans.
+func rawSpans(spans []span) []span {
+	var raw []span
+	for _, sp := range spans {
+		if sp.raw {
+			raw = append(raw, sp)
+		}
+	}
+	return raw
+}
+
+// splitSpans splits spans after at most n cells, reusing spans for the
+// tail. The head is narrower than n when a wide character straddles the
+// cut. It holds at least one character if nonEmpty is set, even one wider
+// than n. Raw spans go with the text before them.
+func splitSpans(spans []span, n int, nonEmpty bool) (head, tail []span) {
+	for i, sp := range spans {
+		w := sp.width()
+		if w <= n {
+			head = append(head, sp)
+			n -= w
+			nonEmpty = nonEmpty && w == 0
+			continue
+		}
+		cut := cutWidth(sp.text, n)
+		if cut == 0 && nonEmpty {
+			_, cut = utf8.DecodeRuneInString(sp.text)
+		}
+		if cut > 0 {
+			head = append(head, span{text: sp.text[:cut], style: sp.style, link: sp.link})
+		}
+		spans[i].text = sp.text[cut:]
+		return head, spans[i:]
+	}
+	return head, nil
+}
+
+// appendSpan appends sp to spans, merging it into the last span if both
+// have the same attributes.
+func appendSpan(spans []span, sp span) []span {
+	if sp.text == "" {
+		return spans
+	}
+	if n := len(spans); n > 0 && spans[n-1].style == sp.style && spans[n-1].link == sp.link && spans[n-1].raw == sp.raw {
+		spans[n-1].text += sp.text
+		return spans
+	}
+	return append(spans, sp)
+}
+
+// width returns the width of sp in cells.
+func (sp span) width() int {
+	if sp.raw {
+		return 0
+	}
+	return textWidth(sp.text)
+}
+
+// spansWidth returns the width of spans in cells.
+func spansWidth(spans []span) int {
+	w := 0
+	for _, sp := range spans {
+		w += sp.width()
+	}
+	return w
+}
+
+// prefix prepends first to the first line and rest to the others. Blank
+// lines do not get trailing whitespace.
+func prefix(lines []string, first, rest string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		p := rest
+		if i == 0 {
+			p = first
+		}
+		if line == "" {
+			p = strings.TrimRight(p, " ")
+		}
+		out[i] = p + line
+	}
+	return out
+}
+
+// headingText strips the optional closing sequence of an ATX heading.
+func headingText(s string) string {
+	s = strings.TrimSpace(s)
+	t := strings.TrimRight(s, "#")
+	if t == "" {
+		return ""
+	}
+	if t != s && (strings.HasSuffix(t, " ") || strings.HasSuffix(t, "\t")) {
+		return strings.TrimSpace(t)
+	}
+	return s
+}
+
+// listKind returns what list items must share to belong to the same list:
+// the bullet character or the delimiter after the number.
+func listKind(match []string) string {
+	if match[3] != "" {
+		return match[4]
+	}
+	return match[2]
+}
+
+// isRule reports whether line is a thematic break: three or more of the
+// same -, * or _ characters, optionally separated by spaces.
+func isRule(line string) bool {
+	if leadingSpaces(line) > 3 {
+		return false
+	}
+	t := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, line)
+	return len(t) >= 3 && strings.Contains("-*_", t[:1]) && strings.Count(t, t[:1]) == len(t)
+}
+
+// isBlockStart reports whether line starts a block that interrupts a
+// paragraph.
+func isBlockStart(line string) bool {
+	return mdHeading.MatchString(line) || mdFence.MatchString(line) || mdQuote.MatchString(line) ||
+		mdListItem.MatchString(line) || isRule(line)
+}
+
+// leadingSpaces returns the indentation of line, counting tabs as four
+// spaces.
+func leadingSpaces(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// trimIndent removes up to n columns of indentation from line.
+func trimIndent(line string, n int) string {
+	i := 0
+	for i < len(line) && n > 0 {
+		switch line[i] {
+		case ' ':
+			n--
+		case '\t':
+			n -= 4
+		default:
+			return line[i:]
+		}
+		i++
+	}
+	return line[i:]
+}
+
+// runLength returns how many times c repeats at the start of s.
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// closingRun returns the index of the next run of exactly n c bytes in s
+// at or after start, or -1.
+func closingRun(s string, start int, c byte, n int) int {
+	for i := start; i < len(s); {
+		if s[i] != c {
+			i++
+			continue
+		}
+		run := runLength(s[i:], c)
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// canOpen reports whether the n delimiters at s[i] can open emphasis: they
+// must be followed by text, and underscores must not be inside a word.
+func canOpen(s string, i, n int) bool {
+	if i+n >= len(s) || s[i+n] == ' ' || s[i+n] == '\t' {
+		return false
+	}
+	return s[i] != '_' || i == 0 || !isWordByte(s[i-1])
+}
+
+// closingDelimiter returns the index of the run of exactly n c bytes that
+// closes emphasis opened before start, or -1. Escaped bytes and code spans
+// are skipped.
+func closingDelimiter(s string, start int, c byte, n int) int {
+	for i := start; i < len(s); {
+		switch s[i] {
+		case '\\':
+			i += 2
+			continue
+		case '`':
+			run := runLength(s[i:], '`')
+			if end := closingRun(s, i+run, '`', run); end >= 0 {
+				i = end + run
+			} else {
+				i += run
+			}
+			continue
+		case c:
+			run := runLength(s[i:], c)
+			after := i + run
+			if run == n && i > start && s[i-1] != ' ' && s[i-1] != '\t' &&
+				(c != '_' || after == len(s) || !isWordByte(s[after])) {
+				return i
+			}
+			i = after
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// matching returns the index of the bracket closing the one at s[i], or
+// -1. Escaped brackets are skipped.
+func matching(s string, i int, open, close byte) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// destination returns the URL of a link destination, dropping the
+// optional title and angle brackets.
+func destination(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "<") {
+		if end := strings.IndexByte(s, '>'); end > 0 {
+			return s[1:end]
+		}
+	}
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// isWordByte reports whether b is an ASCII letter or digit, or part of a
+// multi-byte character.
+func isWordByte(b byte) bool {
+	return b >= utf8.RuneSelf || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
@@ -0,0 +1,242 @@
+package terminal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	env := &Environment{StartSeq: "\033]", EndSeq: "\a", Columns: 40, ColorDepth: 4, Hyperlinks: true}
+	noLinks := &Environment{StartSeq: "\033]", EndSeq: "\a", Columns: 40, ColorDepth: 4}
+
+	tests := []struct {
+		name string
+		env  *Environment
+		body string
+		want string
+	}{
+		{
+			name: "paragraph lines are joined",
+			env:  env,
+			body: "Hello\nworld",
+			want: "Hello world",
+		},
+		{
+			name: "headings",
+			env:  env,
+			body: "# Title\n\n## Section ##\n\nSetext\n---",
+			want: "\033[1;4mTitle\033[0m\n\n\033[1mSection\033[0m\n\n\033[1mSetext\033[0m",
+		},
+		{
+			name: "emphasis",
+			env:  env,
+			body: "a **bold** and *italic* or _it_ ***both***",
+			want: "a \033[1mbold\033[0m and \033[3mitalic\033[0m or \033[3mit\033[0m \033[1;3mboth\033[0m",
+		},
+		{
+			name: "nested emphasis",
+			env:  env,
+			body: "**bold _and italic_**",
+			want: "\033[1mbold\033[0m \033[1;3mand italic\033[0m",
+		},
+		{
+			name: "unmatched markup is text",
+			env:  env,
+			body: "2 * 3 and snake_case_name and **open",
+			want: "2 * 3 and snake_case_name and **open",
+		},
+		{
+			name: "code span and escapes",
+			env:  env,
+			body: "run `go *test*` not \\*this\\*",
+			want: "run \033[36mgo *test*\033[0m not *this*",
+		},
+		{
+			name: "link as hyperlink",
+			env:  env,
+			body: "see [the wiki](https://wiki.example.com \"Wiki\")",
+			want: "see \033]8;;https://wiki.example.com\a\033[4mthe wiki\033[0m\033]8;;\a",
+		},
+		{
+			name: "autolink",
+			env:  env,
+			body: "<https://example.com>",
+			want: "\033]8;;https://example.com\a\033[4mhttps://example.com\033[0m\033]8;;\a",
+		},
+		{
+			name: "bare url",
+			env:  env,
+			body: "status: https://status.example.com.",
+			want: "status: \033]8;;https://status.example.com\a\033[4mhttps://status.example.com\033[0m\033]8;;\a.",
+		},
+		{
+			name: "link without hyperlink support",
+			env:  noLinks,
+			body: "see [the wiki](https://wiki.example.com)",
+			want: "see the wiki (https://wiki.example.com)",
+		},
+		{
+			name: "unsafe link scheme shows the label",
+			env:  env,
+			body: "[click](file:///etc/passwd)",
+			want: "click",
+		},
+		{
+			name: "image alt text",
+			env:  noLinks,
+			body: "![logo](https://example.com/logo.png)",
+			want: "logo (https://example.com/logo.png)",
+		},
+		{
+			name: "bullet list with nesting",
+			env:  env,
+			body: "- one\n- two\n  continued\n  - nested\n* other",
+			want: "• one\n• two continued\n  • nested\n\n• other",
+		},
+		{
+			name: "ordered list keeps the start number",
+			env:  env,
+			body: "9. nine\n10. ten\n\nafter",
+			want: " 9. nine\n10. ten\n\nafter",
+		},
+		{
+			name: "fenced code block is not wrapped or parsed",
+			env:  env,
+			body: "```go\nx := \"**\" + strings.Repeat(\"a\", 40) + \"**\"\n\n```",
+			want: "  \033[36mx := \"**\" + strings.Repeat(\"a\", 40) + \"**\"\033[0m\n",
+		},
+		{
+			name: "indented code block",
+			env:  env,
+			body: "intro\n\n    code\n\tmore\n\nend",
+			want: "intro\n\n  \033[36mcode\033[0m\n  \033[36mmore\033[0m\n\nend",
+		},
+		{
+			name: "blockquote",
+			env:  env,
+			body: "> quoted\n> text\n>\n> more",
+			want: "\033[2m│\033[0m quoted text\n\033[2m│\033[0m\n\033[2m│\033[0m more",
+		},
+		{
+			name: "rule",
+			env:  &Environment{Columns: 20},
+			body: "a\n\n***\n\nb",
+			want: "a\n\n\033[2m" + strings.Repeat("─", 20) + "\033[0m\n\nb",
+		},
+		{
+			name: "control characters are removed",
+			env:  env,
+			body: "# Hi\033]0;pwned\a\n\n[x](https://e.com/\033[2J)",
+			want: "\033[1;4mHi]0;pwned\033[0m\n\n\033]8;;https://e.com/[2J\a\033[4mx\033[0m\033]8;;\a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &network.Message{ContentType: network.ContentTypeMarkdown, Body: []byte(tt.body)}
+			if got := NewFormatter(tt.env).Render(msg); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown_Wrap(t *testing.T) {
+	styles := regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	tests := []struct {
+		name    string
+		columns int
+		body    string
+		want    string
+	}{
+		{
+			name:    "wraps at word boundaries",
+			columns: 20,
+			body:    "The quick brown fox jumps over the lazy dog",
+			want:    "The quick brown fox\njumps over the lazy\ndog",
+		},
+		{
+			name:    "styles are closed at line ends",
+			columns: 20,
+			body:    "**The quick brown fox jumps**",
+			want:    "\033[1mThe quick brown fox\033[0m\n\033[1mjumps\033[0m",
+		},
+		{
+			name:    "long words are split",
+			columns: 20,
+			body:    strings.Repeat("x", 45),
+			want:    strings.Repeat("x", 20) + "\n" + strings.Repeat("x", 20) + "\n" + strings.Repeat("x", 5),
+		},
+		{
+			name:    "wide characters take two cells",
+			columns: 11,
+			body:    strings.Repeat("漢", 12),
+			want:    "漢漢漢漢漢\n漢漢漢漢漢\n漢漢",
+		},
+		{
+			name:    "list items hang under their text",
+			columns: 20,
+			body:    "- The quick brown fox jumps",
+			want:    "• The quick brown\n  fox jumps",
+		},
+		{
+			name:    "unknown width uses the default",
+			columns: 0,
+			body:    strings.Repeat("word ", 20),
+			want:    strings.TrimSpace(strings.Repeat("word ", 16)) + "\n" + strings.TrimSpace(strings.Repeat("word ", 4)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &network.Message{ContentType: network.ContentTypeMarkdown, Body: []byte(tt.body)}
+			got := renderMarkdown(&Environment{Columns: tt.columns}, msg)
+			if got != tt.want {
+				t.Errorf("renderMarkdown() = %q, want %q", got, tt.want)
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if w := textWidth(styles.ReplaceAllString(line, "")); tt.columns > 0 && w > tt.columns {
+					t.Errorf("Line %q is %d cells wide, want at most %d", line, w, tt.columns)
+				}
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	bold := span{text: "bold", style: styleBold}
+	tests := []struct {
+		name       string
+		spans      []span
+		width      int
+		keepSpaces bool
+		want       []string
+	}{
+		{name: "spaces collapsed", spans: []span{{text: "  one   two  "}}, width: 20, want: []string{"one two"}},
+		{name: "spaces kept", spans: []span{{text: "  one   two  "}}, width: 20, keepSpaces: true, want: []string{"  one   two  "}},
+		{name: "breaks drop spaces", spans: []span{{text: "  one   two"}}, width: 8, keepSpaces: true, want: []string{"  one", "two"}},
+		{name: "styled words", spans: []span{{text: "a "}, bold, {text: " b"}}, width: 6, want: []string{"a bold", "b"}},
+		{name: "raw spans take no room", spans: []span{{text: "\033[1m", raw: true}, {text: "abc def"}}, width: 3, keepSpaces: true, want: []string{"\033[1mabc", "def"}},
+		{name: "wide characters", spans: []span{{text: "日本語"}}, width: 4, want: []string{"日本", "語"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, line := range wrap(tt.spans, tt.width, tt.keepSpaces) {
+				var b strings.Builder
+				for _, sp := range line {
+					b.WriteString(sp.text)
+				}
+				got = append(got, b.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("wrap() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package terminal
+
+import "strings"
+
+// Positions of the facts panel relative to the message.
+const (
+	PanelOff   = "off"
+	PanelRight = "right"
+	PanelBelow = "below"
+)
+
+// PanelPositions lists the accepted panel positions.
+var PanelPositions = []string{PanelOff, PanelRight, PanelBelow}
+
+// panelGap is the number of columns between the message and a panel on
+// its right.
+const panelGap = 4
+
+// tabWidth is the distance between tab stops.
+const tabWidth = 8
+
+// Fact is a labeled value shown in the facts panel, such as the uptime.
+type Fact struct {
+	Label string
+	Value string
+	Warn  bool // Highlight the fact, e.g. a pending reboot
+}
+
+// Panel places facts to the right of or below rendered, a message
+// returned by Render. A panel on the right falls back to below when the
+// message is not text, such as an inline image, or there is no room for
+// both on the terminal.
+func (f *Formatter) Panel(rendered string, facts []Fact, position string) string {
+	if len(facts) == 0 || position == "" || position == PanelOff {
+		return rendered
+	}
+	panel := panelLines(f.env, facts)
+
+	if position == PanelRight && textOnly(rendered) {
+		lines := strings.Split(rendered, "\n")
+		width := 0
+		for _, line := range lines {
+			width = max(width, displayWidth(line))
+		}
+		columns := f.env.Columns
+		if columns <= 0 {
+			columns = defaultColumns
+		}
+		if width+panelGap+panelWidth(facts) <= columns {
+			return beside(lines, width, panel)
+		}
+	}
+	return rendered + "\n\n" + strings.Join(panel, "\n")
+}
+
+// panelLines formats facts as aligned "Label  value" lines, with faint
+// labels and warnings in bold, colored by the theme.
+func panelLines(env *Environment, facts []Fact) []string {
+	labelWidth := 0
+	for _, fact := range facts {
+		labelWidth = max(labelWidth, textWidth(sanitize(fact.Label)))
+	}
+
+	lines := make([]string, len(facts))
+	for i, fact := range facts {
+		label := sanitize(fact.Label)
+		value := strings.ReplaceAll(sanitize(fact.Value), "\n", " ")
+		padding := strings.Repeat(" ", labelWidth-textWidth(label)+2)
+		if fact.Warn {
+			value = styled(env, value, "1", RoleWarn)
+		}
+		lines[i] = styled(env, label, "2", RoleMuted) + padding + value
+	}
+	return lines
+}
+
+// styled wraps s in the SGR attribute params, which is bold or faint, and
+// the theme color of role, turning both off after it.
+func styled(env *Environment, s, params, role string) string {
+	if color := env.color(role); color != "" {
+		return "\033[" + params + ";" + color + "m" + s + "\033[22;39m"
+	}
+	return "\033[" + params + "m" + s + "\033[22m"
+}
+
+// panelWidth returns the number of columns the panel for facts occupies.
+func panelWidth(facts []Fact) int {
+	labelWidth, valueWidth := 0, 0
+	for _, fact := range facts {
+		labelWidth = max(labelWidth, textWidth(sanitize(fact.Label)))
+		valueWidth = max(valueWidth, textWidth(sanitize(fact.Value)))
+	}
+	return labelWidth + 2 + valueWidth
+}
+
+// beside joins the message lines, padded to width, with the panel lines.
+// Styles set by the message are reset at the end of each line, so they do
+// not color the panel, and restored on the next line.
+func beside(lines []string, width int, panel []string) string {
+	var b strings.Builder
+	carried := ""
+	for i := range max(len(lines), len(panel)) {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		line := ""
+		if i < len(lines) {
+			line = lines[i]
+		}
+		b.WriteString(carried + line)
+		carried = carriedStyle(carried, line)
+		if i >= len(panel) {
+			continue
+		}
+
+		if carried != "" {
+			b.WriteString("\033[0m")
+		}
+		b.WriteString(strings.Repeat(" ", width-displayWidth(line)+panelGap))
+		b.WriteString(panel[i])
+	}
+	return b.String()
+}
+
+// carriedStyle returns the SGR sequences still in effect after line, given
+// those in effect before it.
+func carriedStyle(carried, line string) string {
+	for {
+		i := strings.IndexByte(line, 0x1b)
+		if i < 0 {
+			return carried
+		}
+		seq, n := escapeSequence(line[i:])
+		line = line[i+n:]
+		switch {
+		case seq == "\033[0m" || seq == "\033[m":
+			carried = ""
+		case sgrSequence.MatchString(seq):
+			carried += seq
+		}
+	}
+}
+
+// textOnly reports whether s holds nothing but text, styles and
+// hyperlinks, so its width can be measured.
+func textOnly(s string) bool {
+	for {
+		i := strings.IndexByte(s, 0x1b)
+		if i < 0 {
+			return true
+		}
+		seq, n := escapeSequence(s[i:])
+		if !sgrSequence.MatchString(seq) && !strings.HasPrefix(seq, "\033]8;") {
+			return false
+		}
+		s = s[i+n:]
+	}
+}
+
+// displayWidth returns the number of columns line occupies, ignoring
+// escape sequences and expanding tabs.
+func displayWidth(line string) int {
+	width := 0
+	for len(line) > 0 {
+		i := strings.IndexAny(line, "\033\t")
+		if i < 0 {
+			return width + textWidth(line)
+		}
+		width += textWidth(line[:i])
+		if line[i] == '\t' {
+			width += tabWidth - width%tabWidth
+			line = line[i+1:]
+			continue
+		}
+		_, n := escapeSequence(line[i:])
+		line = line[i+n:]
+	}
+	return width
+}
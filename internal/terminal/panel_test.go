@@ -0,0 +1,136 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatter_Panel(t *testing.T) {
+	facts := []Fact{
+		{Label: "Host", Value: "build-01"},
+		{Label: "Uptime", Value: "3 days"},
+		{Label: "Reboot", Value: "required", Warn: true},
+	}
+	panel := []string{
+		"\033[2mHost\033[22m    build-01",
+		"\033[2mUptime\033[22m  3 days",
+		"\033[2mReboot\033[22m  \033[1mrequired\033[22m",
+	}
+
+	tests := []struct {
+		name     string
+		columns  int
+		rendered string
+		position string
+		want     string
+	}{
+		{
+			name:     "off",
+			rendered: "Hello",
+			position: PanelOff,
+			want:     "Hello",
+		},
+		{
+			name:     "below",
+			rendered: "Hello",
+			position: PanelBelow,
+			want:     "Hello\n\n" + strings.Join(panel, "\n"),
+		},
+		{
+			name:     "right",
+			columns:  80,
+			rendered: "Hello\nWorld!",
+			position: PanelRight,
+			want: "Hello     " + panel[0] + "\n" +
+				"World!    " + panel[1] + "\n" +
+				"          " + panel[2],
+		},
+		{
+			name:     "right of a longer message",
+			columns:  80,
+			rendered: "1\n2\n3\n4",
+			position: PanelRight,
+			want:     "1    " + panel[0] + "\n2    " + panel[1] + "\n3    " + panel[2] + "\n4",
+		},
+		{
+			name:     "styles do not leak into the panel",
+			columns:  80,
+			rendered: "\033[31mRed\nStill red\033[0m\nPlain",
+			position: PanelRight,
+			want: "\033[31mRed\033[0m" + "          " + panel[0] + "\n" +
+				"\033[31mStill red\033[0m" + "    " + panel[1] + "\n" +
+				"Plain        " + panel[2],
+		},
+		{
+			name:     "hyperlinks and tabs are measured",
+			columns:  80,
+			rendered: "\033]8;;https://example.com\033\\site\033]8;;\033\\\n\tx",
+			position: PanelRight,
+			want: "\033]8;;https://example.com\033\\site\033]8;;\033\\" + "         " + panel[0] + "\n" +
+				"\tx    " + panel[1] + "\n" +
+				"             " + panel[2],
+		},
+		{
+			name:     "too narrow for the right",
+			columns:  20,
+			rendered: "Hello",
+			position: PanelRight,
+			want:     "Hello\n\n" + strings.Join(panel, "\n"),
+		},
+		{
+			name:     "images go below",
+			columns:  200,
+			rendered: "\033]1337;File=inline=1:AAAA\a",
+			position: PanelRight,
+			want:     "\033]1337;File=inline=1:AAAA\a\n\n" + strings.Join(panel, "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFormatter(&Environment{Columns: tt.columns})
+			if got := f.Panel(tt.rendered, facts, tt.position); got != tt.want {
+				t.Errorf("Panel() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter_Panel_SanitizesFacts(t *testing.T) {
+	f := NewFormatter(&Environment{})
+	got := f.Panel("Hi", []Fact{{Label: "OS", Value: "Evil\033]0;title\a\nOS"}}, PanelBelow)
+	if want := "Hi\n\n\033[2mOS\033[22m  Evil]0;title OS"; got != want {
+		t.Errorf("Panel() = %q, want %q", got, want)
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{line: "", want: 0},
+		{line: "héllo", want: 5},
+		{line: "\033[1mbold\033[0m", want: 4},
+		{line: "a\tb", want: 9},
+		{line: "日本語", want: 6},
+		{line: "\033[1m한국어\033[0m ok", want: 9},
+		{line: "\033]8;;https://example.com\033\\link\033]8;;\033\\", want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := displayWidth(tt.line); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestPanelLines_Theme(t *testing.T) {
+	env := &Environment{ColorDepth: 8, Theme: Theme{RoleMuted: {kind: colorIndexed, index: 244}, RoleWarn: RGB(255, 0, 0)}}
+	got := panelLines(env, []Fact{{Label: "Reboot", Value: "required", Warn: true}})
+
+	want := "\033[2;38;5;244mReboot\033[22;39m  \033[1;38;5;196mrequired\033[22;39m"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("panelLines() = %q, want %q", got, want)
+	}
+}
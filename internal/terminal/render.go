@@ -0,0 +1,303 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // Register GIF for image.Decode
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Renderer turns a message into the text written to the terminal.
+type Renderer interface {
+	Render(env *Environment, msg *network.Message) string
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(env *Environment, msg *network.Message) string
+
+// Render calls fn(env, msg).
+func (fn RendererFunc) Render(env *Environment, msg *network.Message) string {
+	return fn(env, msg)
+}
+
+// renderers maps content types to the strategy used to display them.
+var renderers = map[string]Renderer{
+	network.ContentTypeText:     RendererFunc(renderText),
+	network.ContentTypeANSI:     RendererFunc(renderANSI),
+	network.ContentTypeMarkdown: RendererFunc(renderMarkdown),
+	network.ContentTypePNG:      RendererFunc(renderImage),
+	network.ContentTypeGIF:      RendererFunc(renderImage),
+	network.ContentTypeOSC:      RendererFunc(renderOSC),
+}
+
+// sgrSequence matches Select Graphic Rendition sequences, which only set
+// colors and styles.
+var sgrSequence = regexp.MustCompile(`^\x1b\[[0-9;:]*m$`)
+
+// kittyChunkSize is the largest base64 payload kitty accepts per escape.
+const kittyChunkSize = 4096
+
+// Render displays msg with the renderer registered for its content type,
+// followed by the message's URL if it has one, and arranges text as the
+// layout asks. Unknown content types are shown as plain text, which is
+// always safe.
+func (f *Formatter) Render(msg *network.Message) string {
+	if len(msg.Body) == 0 {
+		return ""
+	}
+
+	contentType := mediaType(msg)
+	r, ok := renderers[contentType]
+	if !ok {
+		contentType = network.ContentTypeText
+		r = renderers[contentType]
+	}
+	if !slices.Contains(layoutTypes, contentType) {
+		return f.withURL(r.Render(f.env, msg), msg)
+	}
+
+	// Text in a box is rendered for the room inside it.
+	env := f.env
+	if f.layout.boxed() && env.Columns > 0 {
+		inner := *env
+		inner.Columns = max(env.Columns-boxMargin, minWrapWidth)
+		env = &inner
+	}
+	out := f.withURL(r.Render(env, msg), msg)
+	return f.layout.arrange(f.env, contentType, out)
+}
+
+// withURL appends the URL of msg to out, if it has one.
+func (f *Formatter) withURL(out string, msg *network.Message) string {
+	if target := msg.Metadata.URL; allowedLink(target) {
+		out += "\n" + f.Link(target, target)
+	}
+	return out
+}
+
+// mediaType returns the content type of msg without parameters, or "" if
+// it is invalid.
+func mediaType(msg *network.Message) string {
+	contentType, _, err := mime.ParseMediaType(msg.ContentType)
+	if err != nil {
+		return ""
+	}
+	return contentType
+}
+
+// renderText displays text with every control character except newlines
+// and tabs removed, so the server cannot drive the terminal. URLs become
+// hyperlinks.
+func renderText(env *Environment, msg *network.Message) string {
+	return linkify(env, strings.TrimRight(sanitize(string(msg.Body)), "\n"))
+}
+
+// renderANSI displays text keeping only SGR sequences (colors and styles),
+// with colors the terminal cannot show downgraded; every other escape
+// sequence is dropped. URLs become hyperlinks.
+func renderANSI(env *Environment, msg *network.Message) string {
+	body := string(msg.Body)
+	var b strings.Builder
+	for len(body) > 0 {
+		i := strings.IndexByte(body, 0x1b)
+		if i < 0 {
+			b.WriteString(linkify(env, sanitize(body)))
+			break
+		}
+		b.WriteString(linkify(env, sanitize(body[:i])))
+
+		seq, n := escapeSequence(body[i:])
+		if sgrSequence.MatchString(seq) {
+			b.WriteString(adaptSGR(seq, env.ColorDepth))
+		}
+		body = body[i+n:]
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderImage displays a PNG or GIF image with the first inline image
+// protocol the terminal supports, with half blocks on terminals with 256
+// colors or more, or its title as alternative text. A caption is shown
+// beside the image when there is room, and below it otherwise.
+func renderImage(env *Environment, msg *network.Message) string {
+	caption := strings.TrimSpace(msg.Metadata.Caption)
+	box, room := placeImage(env, msg)
+	if room > 0 {
+		return imageBeside(drawImage(env, msg, box), box, captionLines(env, caption, room))
+	}
+
+	out := drawImage(env, msg, box)
+	if caption != "" {
+		out += "\n" + strings.Join(captionLines(env, caption, terminalColumns(env)), "\n")
+	}
+	return out
+}
+
+// placeImage returns the cells an image is scaled to, empty for its own
+// size, and the width of the caption beside it, or 0 if the caption goes
+// below it or there is none.
+func placeImage(env *Environment, msg *network.Message) (imageBox, int) {
+	caption := strings.TrimSpace(msg.Metadata.Caption)
+	columns := terminalColumns(env)
+
+	if len(env.Graphics) > 0 {
+		if caption != "" {
+			box, ok := fitImage(env, msg.Body, columns/2)
+			if room := columns - box.columns - layoutGap; ok && room >= minCaptionWidth {
+				return box, room
+			}
+		}
+		return imageBox{}, 0
+	}
+
+	// Half blocks are always scaled, to fit the terminal.
+	if !halfBlocks(env) {
+		return imageBox{}, 0
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(msg.Body))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return imageBox{}, 0
+	}
+	if caption != "" {
+		box := fitHalfBlock(env, cfg.Width, cfg.Height, columns/2)
+		if room := columns - box.columns - layoutGap; room >= minCaptionWidth {
+			return box, room
+		}
+	}
+	return fitHalfBlock(env, cfg.Width, cfg.Height, columns), 0
+}
+
+// drawImage draws an image scaled to box, or at its own size if box is
+// empty, or returns its alternative text. Animated GIFs are animated by
+// iTerm2 and kitty; elsewhere only their first frame is drawn.
+func drawImage(env *Environment, msg *network.Message, box imageBox) string {
+	switch {
+	case slices.Contains(env.Graphics, GraphicsITerm2):
+		size := ""
+		if box.columns > 0 {
+			size = fmt.Sprintf(";width=%d;height=%d;preserveAspectRatio=1", box.columns, box.rows)
+		}
+		data := base64.StdEncoding.EncodeToString(msg.Body)
+		return fmt.Sprintf("%s1337;File=inline=1;size=%d%s:%s%s", env.StartSeq, len(msg.Body), size, data, env.EndSeq)
+	case slices.Contains(env.Graphics, GraphicsKitty) && mediaType(msg) == network.ContentTypeGIF:
+		if out, ok := kittyAnimation(msg.Body, box); ok {
+			return out
+		}
+	case slices.Contains(env.Graphics, GraphicsKitty):
+		return kittyImage(base64.StdEncoding.EncodeToString(msg.Body), box)
+	case box.columns > 0:
+		// placeImage only sizes images without graphics for half blocks.
+		if img, err := decodeImage(msg.Body); err == nil {
+			return halfBlock(env, img, box)
+		}
+	}
+
+	if msg.Metadata.Title != "" {
+		return fmt.Sprintf("[image: %s]", sanitize(msg.Metadata.Title))
+	}
+	return "[image]"
+}
+
+// terminalColumns returns the width of the terminal, or a typical width if
+// it is unknown.
+func terminalColumns(env *Environment) int {
+	if env.Columns <= 0 {
+		return defaultColumns
+	}
+	return env.Columns
+}
+
+// renderOSC wraps a pre-encoded operating system command in the
+// terminal's OSC sequence.
+func renderOSC(env *Environment, msg *network.Message) string {
+	return fmt.Sprintf("%s%s%s", env.StartSeq, msg.Body, env.EndSeq)
+}
+
+// kittyImage encodes base64 PNG data with the kitty graphics protocol. An
+// image scaled to a box leaves the cursor where it was.
+func kittyImage(data string, box imageBox) string {
+	size := ""
+	if box.columns > 0 {
+		size = fmt.Sprintf(",c=%d,r=%d,C=1", box.columns, box.rows)
+	}
+	return kittyCommand("f=100,a=T"+size, data)
+}
+
+// kittyCommand encodes a kitty graphics command with its control keys and
+// base64 payload, split into the chunks the protocol requires.
+func kittyCommand(control, data string) string {
+	var b strings.Builder
+	first := true
+	for {
+		chunk := data[:min(len(data), kittyChunkSize)]
+		data = data[len(chunk):]
+
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\033_G%s,m=%d;%s\033\\", control, more, chunk)
+			first = false
+		} else {
+			fmt.Fprintf(&b, "\033_Gm=%d;%s\033\\", more, chunk)
+		}
+		if more == 0 {
+			return b.String()
+		}
+	}
+}
+
+// sanitize removes control characters other than newline and tab.
+// Invalid UTF-8 is replaced with U+FFFD.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0):
+			return -1
+		default:
+			return r
+		}
+	}, s)
+}
+
+// escapeSequence returns the escape sequence at the start of s, which must
+// begin with ESC, and its length. Unterminated sequences extend to the end
+// of s.
+func escapeSequence(s string) (string, int) {
+	if len(s) < 2 {
+		return s, len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes, then a final byte.
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return s[:i+1], i + 1
+			}
+		}
+	case ']', 'P', '_', '^', 'X':
+		// String sequences end with BEL or ST.
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return s[:i+1], i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return s[:i+2], i + 2
+			}
+		}
+	default:
+		return s[:2], 2
+	}
+	return s, len(s)
+}
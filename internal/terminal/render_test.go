@@ -0,0 +1,145 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestFormatter_Render(t *testing.T) {
+	plain := &Environment{StartSeq: "\033]", EndSeq: "\a", ColorDepth: 4}
+	iterm := &Environment{StartSeq: "\033]", EndSeq: "\a", Graphics: []string{GraphicsITerm2}}
+	kitty := &Environment{StartSeq: "\033]", EndSeq: "\a", Graphics: []string{GraphicsKitty}}
+
+	tests := []struct {
+		name     string
+		env      *Environment
+		msg      *network.Message
+		expected string
+	}{
+		{
+			name:     "empty body",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypeText},
+			expected: "",
+		},
+		{
+			name:     "plain text strips control characters",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypeText, Body: []byte("Hi\033]0;title\a\tthere\r\n")},
+			expected: "Hi]0;title\tthere",
+		},
+		{
+			name:     "content type parameters are ignored",
+			env:      plain,
+			msg:      &network.Message{ContentType: "text/plain; charset=utf-8", Body: []byte("Hello")},
+			expected: "Hello",
+		},
+		{
+			name:     "unknown content type is plain text",
+			env:      plain,
+			msg:      &network.Message{ContentType: "application/x-unknown", Body: []byte("A\033[2JB")},
+			expected: "A[2JB",
+		},
+		{
+			name:     "ansi keeps colors",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypeANSI, Body: []byte("\033[1;31mRed\033[0m\n")},
+			expected: "\033[1;31mRed\033[0m",
+		},
+		{
+			name:     "ansi downgrades truecolor",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypeANSI, Body: []byte("\033[38;2;205;0;0mRed\033[0m")},
+			expected: "\033[31mRed\033[0m",
+		},
+		{
+			name:     "ansi without colors keeps styles",
+			env:      &Environment{},
+			msg:      &network.Message{ContentType: network.ContentTypeANSI, Body: []byte("\033[1;31mRed\033[0m")},
+			expected: "\033[1mRed\033[0m",
+		},
+		{
+			name:     "ansi drops other sequences",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypeANSI, Body: []byte("\033[2J\033]0;pwned\a\033[>4;1mok\033c")},
+			expected: "ok",
+		},
+		{
+			name:     "osc is wrapped",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypeOSC, Body: []byte("1337;File=inline=1:AAAA")},
+			expected: "\033]1337;File=inline=1:AAAA\a",
+		},
+		{
+			name:     "png with iterm2",
+			env:      iterm,
+			msg:      &network.Message{ContentType: network.ContentTypePNG, Body: []byte("png")},
+			expected: "\033]1337;File=inline=1;size=3:cG5n\a",
+		},
+		{
+			name:     "png with kitty",
+			env:      kitty,
+			msg:      &network.Message{ContentType: network.ContentTypePNG, Body: []byte("png")},
+			expected: "\033_Gf=100,a=T,m=0;cG5n\033\\",
+		},
+		{
+			name: "png without graphics shows title",
+			env:  plain,
+			msg: &network.Message{
+				ContentType: network.ContentTypePNG,
+				Metadata:    network.Metadata{Title: "Logo"},
+				Body:        []byte("png"),
+			},
+			expected: "[image: Logo]",
+		},
+		{
+			name:     "png without graphics or title",
+			env:      plain,
+			msg:      &network.Message{ContentType: network.ContentTypePNG, Body: []byte("png")},
+			expected: "[image]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewFormatter(tt.env).Render(tt.msg)
+			if result != tt.expected {
+				t.Errorf("Render() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKittyImage_Chunks(t *testing.T) {
+	data := strings.Repeat("A", kittyChunkSize+10)
+
+	result := kittyImage(data, imageBox{})
+	want := "\033_Gf=100,a=T,m=1;" + strings.Repeat("A", kittyChunkSize) + "\033\\" +
+		"\033_Gm=0;" + strings.Repeat("A", 10) + "\033\\"
+	if result != want {
+		t.Errorf("kittyImage() produced %d bytes, want %d", len(result), len(want))
+	}
+}
+
+func TestEscapeSequence(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "\033[31mrest", want: "\033[31m"},
+		{input: "\033]0;title\arest", want: "\033]0;title\a"},
+		{input: "\033]0;title\033\\rest", want: "\033]0;title\033\\"},
+		{input: "\033crest", want: "\033c"},
+		{input: "\033[12", want: "\033[12"},
+		{input: "\033", want: "\033"},
+	}
+
+	for _, tt := range tests {
+		seq, n := escapeSequence(tt.input)
+		if seq != tt.want || n != len(tt.want) {
+			t.Errorf("escapeSequence(%q) = %q, %d, want %q, %d", tt.input, seq, n, tt.want, len(tt.want))
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package terminal
+
+import "os"
+
+// querySize is unsupported on this platform; callers fall back to the
+// COLUMNS and LINES environment variables.
+func querySize(f *os.File) (columns, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// queryCellSize is unsupported on this platform; callers assume a typical
+// cell size.
+func queryCellSize(f *os.File) (width, height int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package terminal
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize mirrors struct winsize from <sys/ioctl.h>.
+type winsize struct {
+	Rows    uint16
+	Columns uint16
+	XPixel  uint16
+	YPixel  uint16
+}
+
+// querySize asks the kernel for the window size of the terminal on f.
+func querySize(f *os.File) (columns, rows int, ok bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.Columns == 0 {
+		return 0, 0, false
+	}
+	return int(ws.Columns), int(ws.Rows), true
+}
+
+// queryCellSize asks the kernel for the size in pixels of one cell of the
+// terminal on f. Terminals that do not report their pixel size fail.
+func queryCellSize(f *os.File) (width, height int, ok bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.Columns == 0 || ws.Rows == 0 || ws.XPixel == 0 || ws.YPixel == 0 {
+		return 0, 0, false
+	}
+	return int(ws.XPixel / ws.Columns), int(ws.YPixel / ws.Rows), true
+}
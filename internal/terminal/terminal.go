@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Error definitions
@@ -18,6 +20,15 @@ type DetectorInterface interface {
 	Detect() (*Environment, error)
 }
 
+// Graphics protocols a terminal may support for inline images.
+const (
+	GraphicsITerm2 = "iterm2"
+	GraphicsKitty  = "kitty"
+)
+
+// ClearScreen moves the cursor to the top left and erases the display.
+const ClearScreen = "\033[H\033[2J"
+
 // Environment represents the detected terminal environment.
 type Environment struct {
 	IsITerm2 bool
@@ -25,14 +36,41 @@ type Environment struct {
 	IsTmux   bool
 	StartSeq string
 	EndSeq   string
+
+	// Capabilities advertised to servers that support the handshake.
+	Graphics   []string // Supported inline image protocols
+	Columns    int      // Terminal width in cells, 0 if unknown
+	Rows       int      // Terminal height in cells, 0 if unknown
+	ColorDepth int      // Bits per color: 24, 8, 4 or 0 for none
+
+	CellWidth  int // Width of a cell in pixels, 0 if unknown
+	CellHeight int // Height of a cell in pixels, 0 if unknown
+
+	Hyperlinks bool // Supports OSC 8 hyperlinks
+
+	// Background is the background color of the terminal, the default
+	// color if it is unknown. BackgroundLuminance is its relative
+	// luminance, from 0 for black to 1 for white.
+	Background          Color
+	BackgroundLuminance float64
+
+	// Theme holds the colors of text, chosen by the application rather
+	// than detected. The dark theme is used if it is nil.
+	Theme Theme
 }
 
 // Detector handles terminal environment detection.
-type Detector struct{}
+type Detector struct {
+	backgroundTimeout time.Duration
+}
 
 // NewDetector creates a new terminal detector.
-func NewDetector() *Detector {
-	return &Detector{}
+func NewDetector(opts ...DetectorOption) *Detector {
+	d := &Detector{backgroundTimeout: defaultBackgroundTimeout}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 // Detect determines the terminal environment and returns appropriate formatting.
@@ -70,17 +108,130 @@ func (d *Detector) Detect() (*Environment, error) {
 		env.EndSeq = "\a"
 	}
 
+	env.Graphics = detectGraphics(env, term)
+	env.Columns, env.Rows = detectSize()
+	env.CellWidth, env.CellHeight, _ = queryCellSize(os.Stdout)
+	env.ColorDepth = detectColorDepth(term)
+	env.Hyperlinks = detectHyperlinks(env, term)
+	if background, ok := d.detectBackground(term, env.IsSSH); ok {
+		env.Background = background
+		env.BackgroundLuminance = luminance(background)
+	}
+
 	return env, nil
 }
 
+// detectGraphics lists the inline image protocols the terminal supports.
+func detectGraphics(env *Environment, term string) []string {
+	var graphics []string
+	if env.IsITerm2 || os.Getenv("TERM_PROGRAM") == "WezTerm" {
+		graphics = append(graphics, GraphicsITerm2)
+	}
+	if _, ok := os.LookupEnv("KITTY_WINDOW_ID"); ok || term == "xterm-kitty" {
+		graphics = append(graphics, GraphicsKitty)
+	}
+	return graphics
+}
+
+// detectSize returns the terminal size in cells, preferring the COLUMNS and
+// LINES variables over querying stdout. Unknown dimensions are 0.
+func detectSize() (columns, rows int) {
+	columns, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	rows, _ = strconv.Atoi(os.Getenv("LINES"))
+	if columns > 0 && rows > 0 {
+		return columns, rows
+	}
+
+	if c, r, ok := querySize(os.Stdout); ok {
+		if columns <= 0 {
+			columns = c
+		}
+		if rows <= 0 {
+			rows = r
+		}
+	}
+	return max(columns, 0), max(rows, 0)
+}
+
+// detectColorDepth estimates the number of bits per color. NO_COLOR turns
+// colors off, as does output that is not a terminal unless CLICOLOR_FORCE
+// is set. Otherwise COLORTERM, the terminfo colors capability and the TERM
+// name are consulted in turn.
+func detectColorDepth(term string) int {
+	if os.Getenv("NO_COLOR") != "" {
+		return 0
+	}
+	force := os.Getenv("CLICOLOR_FORCE")
+	forced := force != "" && force != "0"
+	if !forced && !isTerminal(os.Stdout) {
+		return 0
+	}
+
+	depth := 4
+	if colorterm := os.Getenv("COLORTERM"); colorterm == "truecolor" || colorterm == "24bit" {
+		depth = 24
+	} else if colors, ok := terminfoColors(term); ok {
+		depth = colorDepth(colors)
+	} else if term == "dumb" {
+		depth = 0
+	} else if strings.Contains(term, "256color") {
+		depth = 8
+	}
+	if forced {
+		return max(depth, 4)
+	}
+	return depth
+}
+
+// colorDepth returns the number of bits per color of a terminal showing
+// the given number of colors.
+func colorDepth(colors int) int {
+	switch {
+	case colors >= 1<<24:
+		return 24
+	case colors >= 256:
+		return 8
+	case colors >= 8:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// isTerminal reports whether f refers to a character device such as a
+// tty. It is a variable so tests can replace it.
+var isTerminal = func(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // Formatter handles message formatting for different terminal environments.
 type Formatter struct {
-	env *Environment
+	env    *Environment
+	layout Layout
+}
+
+// FormatterOption configures optional Formatter behavior.
+type FormatterOption func(*Formatter)
+
+// WithLayout arranges text messages with layout. By default they are shown
+// as they are.
+func WithLayout(layout Layout) FormatterOption {
+	return func(f *Formatter) {
+		f.layout = layout
+	}
 }
 
 // NewFormatter creates a new message formatter.
-func NewFormatter(env *Environment) *Formatter {
-	return &Formatter{env: env}
+func NewFormatter(env *Environment, opts ...FormatterOption) *Formatter {
+	f := &Formatter{env: env}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
 // Format formats a message for the detected terminal environment.
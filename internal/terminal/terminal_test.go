@@ -2,6 +2,7 @@ package terminal
 
 import (
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -95,6 +96,130 @@ func TestDetector_Detect(t *testing.T) {
 	}
 }
 
+func TestDetector_Capabilities(t *testing.T) {
+	tests := []struct {
+		name      string
+		envVars   map[string]string
+		graphics  []string
+		colorBits int
+	}{
+		{
+			name:      "plain xterm",
+			envVars:   map[string]string{"TERM": "xterm"},
+			graphics:  nil,
+			colorBits: 4,
+		},
+		{
+			name:      "iTerm2 truecolor",
+			envVars:   map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "iTerm.app", "COLORTERM": "truecolor"},
+			graphics:  []string{GraphicsITerm2},
+			colorBits: 24,
+		},
+		{
+			name:      "kitty",
+			envVars:   map[string]string{"TERM": "xterm-kitty", "KITTY_WINDOW_ID": "1"},
+			graphics:  []string{GraphicsKitty},
+			colorBits: 4,
+		},
+		{
+			name:      "256 colors",
+			envVars:   map[string]string{"TERM": "screen-256color"},
+			graphics:  nil,
+			colorBits: 8,
+		},
+		{
+			name:      "dumb terminal",
+			envVars:   map[string]string{"TERM": "dumb"},
+			graphics:  nil,
+			colorBits: 0,
+		},
+	}
+
+	useTerminal(t, true)
+	useTerminfo(t, t.TempDir())
+	useQuery(t, "\033]11;rgb:0000/0000/0000\033\\\033[?62c")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"TERM_PROGRAM", "SSH_CLIENT", "COLORTERM", "KITTY_WINDOW_ID", "NO_COLOR", "CLICOLOR_FORCE"} {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+			t.Setenv("COLUMNS", "120")
+			t.Setenv("LINES", "40")
+
+			env, err := NewDetector().Detect()
+			if err != nil {
+				t.Fatalf("Detect() unexpected error: %v", err)
+			}
+
+			if !slices.Equal(env.Graphics, tt.graphics) {
+				t.Errorf("Graphics = %v, want %v", env.Graphics, tt.graphics)
+			}
+			if env.ColorDepth != tt.colorBits {
+				t.Errorf("ColorDepth = %d, want %d", env.ColorDepth, tt.colorBits)
+			}
+			if env.Columns != 120 || env.Rows != 40 {
+				t.Errorf("Size = %dx%d, want 120x40", env.Columns, env.Rows)
+			}
+			if light, known := env.LightBackground(); tt.envVars["TERM"] != "dumb" && (light || !known) {
+				t.Errorf("LightBackground() = %v, %v, want a known dark background", light, known)
+			}
+		})
+	}
+}
+
+// useTerminal makes detection see stdout as a terminal or not.
+func useTerminal(t *testing.T, terminal bool) {
+	t.Helper()
+	original := isTerminal
+	isTerminal = func(*os.File) bool { return terminal }
+	t.Cleanup(func() { isTerminal = original })
+}
+
+func TestDetectColorDepth(t *testing.T) {
+	dir := t.TempDir()
+	useTerminfo(t, dir)
+	writeTerminfo(t, dir, "x", "xterm-direct", terminfoEntry(terminfoMagic32, "xterm-direct", 1<<24))
+	writeTerminfo(t, dir, "v", "vt100", terminfoEntry(terminfoMagic, "vt100", 0))
+
+	tests := []struct {
+		name     string
+		term     string
+		envVars  map[string]string
+		terminal bool
+		want     int
+	}{
+		{name: "terminfo truecolor", term: "xterm-direct", terminal: true, want: 24},
+		{name: "terminfo without colors", term: "vt100", terminal: true, want: 0},
+		{name: "colorterm before terminfo", term: "vt100", envVars: map[string]string{"COLORTERM": "truecolor"}, terminal: true, want: 24},
+		{name: "term name without terminfo", term: "screen-256color", terminal: true, want: 8},
+		{name: "no color", term: "xterm-direct", envVars: map[string]string{"NO_COLOR": "1"}, terminal: true, want: 0},
+		{name: "empty no color is ignored", term: "xterm-direct", envVars: map[string]string{"NO_COLOR": ""}, terminal: true, want: 24},
+		{name: "not a terminal", term: "xterm-direct", want: 0},
+		{name: "forced", term: "xterm-direct", envVars: map[string]string{"CLICOLOR_FORCE": "1"}, want: 24},
+		{name: "forced dumb terminal", term: "dumb", envVars: map[string]string{"CLICOLOR_FORCE": "1"}, want: 4},
+		{name: "force disabled", term: "xterm-direct", envVars: map[string]string{"CLICOLOR_FORCE": "0"}, want: 0},
+		{name: "no color wins over force", term: "xterm-direct", envVars: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, terminal: true, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"COLORTERM", "NO_COLOR", "CLICOLOR_FORCE"} {
+				t.Setenv(key, tt.envVars[key])
+			}
+			useTerminal(t, tt.terminal)
+
+			if got := detectColorDepth(tt.term); got != tt.want {
+				t.Errorf("detectColorDepth(%q) = %d, want %d", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFormatter_Format(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -142,3 +267,18 @@ func TestFormatter_Format(t *testing.T) {
 		})
 	}
 }
+
+func TestQuerySize_NotATerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "size")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer f.Close()
+
+	if _, _, ok := querySize(f); ok {
+		t.Error("Expected querySize to fail for a regular file")
+	}
+	if _, _, ok := queryCellSize(f); ok {
+		t.Error("Expected queryCellSize to fail for a regular file")
+	}
+}
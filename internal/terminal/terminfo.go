@@ -0,0 +1,98 @@
+package terminal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Magic numbers of compiled terminfo entries, with 16 and 32 bit numbers.
+const (
+	terminfoMagic   = 0o432
+	terminfoMagic32 = 0o1036
+)
+
+// terminfoColorsIndex is the position of the colors capability among the
+// numeric capabilities.
+const terminfoColorsIndex = 13
+
+// terminfoDirs returns the directories searched for terminfo entries, in
+// order. It is a variable so tests can use fixtures.
+var terminfoDirs = func() []string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("TERMINFO_DIRS")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo")
+}
+
+// terminfoColors returns the number of colors of term according to its
+// terminfo entry, 0 if the entry has none, or false if there is no entry.
+func terminfoColors(term string) (int, bool) {
+	if term == "" || strings.ContainsAny(term, `/\`) || strings.HasPrefix(term, ".") {
+		return 0, false
+	}
+
+	for _, dir := range terminfoDirs() {
+		// Entries are grouped by their first letter, or its hex code on
+		// macOS.
+		for _, group := range []string{term[:1], fmt.Sprintf("%02x", term[0])} {
+			data, err := os.ReadFile(filepath.Join(dir, group, term))
+			if err == nil {
+				return parseTerminfoColors(data)
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseTerminfoColors reads the colors capability of a compiled terminfo
+// entry, as described in term(5).
+func parseTerminfoColors(data []byte) (int, bool) {
+	if len(data) < 12 {
+		return 0, false
+	}
+	numberSize := 2
+	switch binary.LittleEndian.Uint16(data) {
+	case terminfoMagic:
+	case terminfoMagic32:
+		numberSize = 4
+	default:
+		return 0, false
+	}
+
+	namesSize := int(int16(binary.LittleEndian.Uint16(data[2:])))
+	boolCount := int(int16(binary.LittleEndian.Uint16(data[4:])))
+	numberCount := int(int16(binary.LittleEndian.Uint16(data[6:])))
+	if namesSize < 0 || boolCount < 0 || numberCount < 0 {
+		return 0, false
+	}
+	if numberCount <= terminfoColorsIndex {
+		return 0, true
+	}
+
+	// Numbers start on an even byte.
+	offset := 12 + namesSize + boolCount
+	offset += offset % 2
+	at := offset + terminfoColorsIndex*numberSize
+	if at+numberSize > len(data) {
+		return 0, false
+	}
+
+	colors := int(int16(binary.LittleEndian.Uint16(data[at:])))
+	if numberSize == 4 {
+		colors = int(int32(binary.LittleEndian.Uint32(data[at:])))
+	}
+	// Absent and cancelled capabilities are negative.
+	return max(colors, 0), true
+}
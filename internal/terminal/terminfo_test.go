@@ -0,0 +1,104 @@
+package terminal
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTerminfo makes terminfo entries be looked up in dir only.
+func useTerminfo(t *testing.T, dir string) {
+	t.Helper()
+	original := terminfoDirs
+	terminfoDirs = func() []string { return []string{dir} }
+	t.Cleanup(func() { terminfoDirs = original })
+}
+
+// terminfoEntry compiles a terminfo entry whose numeric capabilities end
+// with colors, or lack it if colors is 0, in the format given by magic.
+func terminfoEntry(magic uint16, name string, colors int) []byte {
+	numberSize, numbers := 2, 0
+	if magic == terminfoMagic32 {
+		numberSize = 4
+	}
+	if colors != 0 {
+		numbers = terminfoColorsIndex + 1
+	}
+	names := name + "|test terminal\x00"
+	bools := []byte{1, 0, 1} // Odd, so the numbers are padded
+
+	data := binary.LittleEndian.AppendUint16(nil, magic)
+	for _, n := range []int{len(names), len(bools), numbers, 0, 0} {
+		data = binary.LittleEndian.AppendUint16(data, uint16(n))
+	}
+	data = append(append(data, names...), bools...)
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	for i := range numbers {
+		value := -1
+		if i == terminfoColorsIndex {
+			value = colors
+		}
+		if numberSize == 4 {
+			data = binary.LittleEndian.AppendUint32(data, uint32(int32(value)))
+		} else {
+			data = binary.LittleEndian.AppendUint16(data, uint16(int16(value)))
+		}
+	}
+	return data
+}
+
+// writeTerminfo installs a terminfo entry for name under dir.
+func writeTerminfo(t *testing.T, dir, group, name string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, group), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, group, name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTerminfoColors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		want   int
+		wantOK bool
+	}{
+		{name: "16 bit numbers", data: terminfoEntry(terminfoMagic, "xterm-256color", 256), want: 256, wantOK: true},
+		{name: "32 bit numbers", data: terminfoEntry(terminfoMagic32, "xterm-direct", 1<<24), want: 1 << 24, wantOK: true},
+		{name: "no colors", data: terminfoEntry(terminfoMagic, "vt100", 0), want: 0, wantOK: true},
+		{name: "truncated", data: terminfoEntry(terminfoMagic, "xterm", 8)[:30], wantOK: false},
+		{name: "not terminfo", data: []byte("#!/bin/sh\necho hello\n"), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTerminfoColors(tt.data)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseTerminfoColors() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTerminfoColors(t *testing.T) {
+	dir := t.TempDir()
+	useTerminfo(t, dir)
+	writeTerminfo(t, dir, "x", "xterm-256color", terminfoEntry(terminfoMagic, "xterm-256color", 256))
+	writeTerminfo(t, dir, "66", "foot", terminfoEntry(terminfoMagic, "foot", 256))
+
+	for _, term := range []string{"xterm-256color", "foot"} {
+		if colors, ok := terminfoColors(term); !ok || colors != 256 {
+			t.Errorf("terminfoColors(%q) = %d, %v, want 256, true", term, colors, ok)
+		}
+	}
+	for _, term := range []string{"missing", "", "../x/xterm-256color"} {
+		if _, ok := terminfoColors(term); ok {
+			t.Errorf("terminfoColors(%q) found an entry", term)
+		}
+	}
+}
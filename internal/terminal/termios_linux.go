@@ -0,0 +1,9 @@
+package terminal
+
+import "syscall"
+
+// Requests that get and set the attributes of a terminal.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,225 @@
+package terminal
+
+import (
+	"strings"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+// Alignments of text messages.
+const (
+	AlignLeft   = "left"
+	AlignCenter = "center"
+)
+
+// Alignments lists the accepted alignments.
+var Alignments = []string{AlignLeft, AlignCenter}
+
+// Styles of the box drawn around text messages.
+const (
+	BoxNone    = "none"
+	BoxSingle  = "single"
+	BoxRounded = "rounded"
+	BoxDouble  = "double"
+	BoxHeavy   = "heavy"
+)
+
+// BoxStyles lists the accepted box styles.
+var BoxStyles = []string{BoxNone, BoxSingle, BoxRounded, BoxDouble, BoxHeavy}
+
+// boxBorders are the characters of each box style: the top left, top,
+// top right, side, bottom left and bottom right corners.
+var boxBorders = map[string][6]string{
+	BoxSingle:  {"┌", "─", "┐", "│", "└", "┘"},
+	BoxRounded: {"╭", "─", "╮", "│", "╰", "╯"},
+	BoxDouble:  {"╔", "═", "╗", "║", "╚", "╝"},
+	BoxHeavy:   {"┏", "━", "┓", "┃", "┗", "┛"},
+}
+
+// boxMargin is the number of columns a box adds to the width of the text
+// inside it: a border and a space on each side.
+const boxMargin = 4
+
+// layoutTypes are the content types arranged by a Layout. Images and OSC
+// sequences are shown as they are.
+var layoutTypes = []string{network.ContentTypeText, network.ContentTypeANSI, network.ContentTypeMarkdown}
+
+// Layout arranges text messages on the terminal. The zero Layout shows
+// them as they are.
+type Layout struct {
+	Wrap  bool   // Wrap plain text and ANSI lines to the terminal width
+	Align string // AlignLeft or AlignCenter; "" is left
+	Box   string // One of BoxStyles; "" is none
+}
+
+// boxed reports whether the layout draws a box around messages.
+func (l Layout) boxed() bool {
+	return boxBorders[l.Box] != [6]string{}
+}
+
+// active reports whether the layout changes messages at all.
+func (l Layout) active() bool {
+	return l.Wrap || l.Align == AlignCenter || l.boxed()
+}
+
+// arrange wraps, boxes and centers out, a message of the given content
+// type rendered for env, as the layout asks. Lines are only wrapped and
+// centered when the terminal width is known. Messages holding more than
+// text, styles and hyperlinks are returned as they are.
+func (l Layout) arrange(env *Environment, contentType, out string) string {
+	if !l.active() || !textOnly(out) {
+		return out
+	}
+
+	lines := strings.Split(expandTabs(out), "\n")
+	if l.Wrap && contentType != network.ContentTypeMarkdown && env.Columns > 0 {
+		width := env.Columns
+		if l.boxed() {
+			width = max(width-boxMargin, minWrapWidth)
+		}
+		var wrapped []string
+		for _, line := range lines {
+			wrapped = append(wrapped, wrapLine(line, width)...)
+		}
+		lines = wrapped
+	}
+	if l.boxed() || l.Align == AlignCenter {
+		lines = isolateLines(env, lines)
+	}
+	if l.boxed() {
+		lines = boxLines(env, lines, boxBorders[l.Box])
+	}
+	if l.Align == AlignCenter && env.Columns > 0 {
+		for i, line := range lines {
+			if pad := (env.Columns - displayWidth(line)) / 2; pad > 0 && line != "" {
+				lines[i] = strings.Repeat(" ", pad) + line
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine breaks a line of text, which may hold escape sequences but no
+// tabs, into lines at most width cells wide with the wrapper used for
+// Markdown. Lines break at spaces, which are dropped there, and words wider
+// than width are split. Lines that fit are kept as they are, spaces and all.
+func wrapLine(line string, width int) []string {
+	var lines []string
+	for _, spans := range wrap(lineSpans(line), width, true) {
+		var b strings.Builder
+		for _, sp := range spans {
+			b.WriteString(sp.text)
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// lineSpans splits a line into runs of text and raw spans holding its
+// escape sequences.
+func lineSpans(line string) []span {
+	var spans []span
+	for {
+		i := strings.IndexByte(line, 0x1b)
+		if i < 0 {
+			return appendSpan(spans, span{text: line})
+		}
+		seq, n := escapeSequence(line[i:])
+		spans = appendSpan(spans, span{text: line[:i]})
+		spans = append(spans, span{text: seq, raw: true})
+		line = line[i+n:]
+	}
+}
+
+// expandTabs replaces tabs with the spaces up to the next tab stop, so
+// lines keep their look when moved away from the left edge.
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		var b strings.Builder
+		for {
+			j := strings.IndexByte(line, '\t')
+			if j < 0 {
+				b.WriteString(line)
+				break
+			}
+			b.WriteString(line[:j])
+			b.WriteString(strings.Repeat(" ", tabWidth-displayWidth(b.String())%tabWidth))
+			line = line[j+1:]
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isolateLines makes every line stand on its own: styles and hyperlinks
+// still in effect at the end of a line are turned off there and on again
+// at the start of the next, so text put around the lines is not affected.
+func isolateLines(env *Environment, lines []string) []string {
+	out := make([]string, len(lines))
+	carried, link := "", ""
+	for i, line := range lines {
+		var b strings.Builder
+		b.WriteString(carried)
+		if link != "" {
+			b.WriteString(linkStart(env, link))
+		}
+		b.WriteString(line)
+
+		carried = carriedStyle(carried, line)
+		link = openLink(link, line)
+		if link != "" {
+			b.WriteString(linkEnd(env))
+		}
+		if carried != "" {
+			b.WriteString("\033[0m")
+		}
+		out[i] = b.String()
+	}
+	return out
+}
+
+// openLink returns the target of the hyperlink still open after line,
+// given the one open before it, or "" if none is.
+func openLink(link, line string) string {
+	for {
+		i := strings.IndexByte(line, 0x1b)
+		if i < 0 {
+			return link
+		}
+		seq, n := escapeSequence(line[i:])
+		line = line[i+n:]
+		if _, params, ok := strings.Cut(seq, "]8;"); ok {
+			// The parameters are followed by the target and a terminator.
+			_, target, _ := strings.Cut(params, ";")
+			link = strings.TrimSuffix(strings.TrimSuffix(target, "\a"), "\033\\")
+		}
+	}
+}
+
+// boxLines draws a box around lines, which must stand on their own, with
+// the borders in the theme's muted color.
+func boxLines(env *Environment, lines []string, border [6]string) []string {
+	width := 0
+	for _, line := range lines {
+		width = max(width, displayWidth(line))
+	}
+
+	paint := func(s string) string {
+		if color := env.color(RoleMuted); color != "" {
+			return "\033[" + color + "m" + s + "\033[39m"
+		}
+		return s
+	}
+	side := paint(border[3])
+
+	out := make([]string, 0, len(lines)+2)
+	out = append(out, paint(border[0]+strings.Repeat(border[1], width+2)+border[2]))
+	for _, line := range lines {
+		out = append(out, side+" "+line+strings.Repeat(" ", width-displayWidth(line))+" "+side)
+	}
+	return append(out, paint(border[4]+strings.Repeat(border[1], width+2)+border[5]))
+}
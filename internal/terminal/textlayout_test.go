@@ -0,0 +1,186 @@
+package terminal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{name: "fits", line: "  two  spaces  ", width: 20, want: []string{"  two  spaces  "}},
+		{name: "word boundaries", line: "The quick brown fox jumps over the lazy dog", width: 20, want: []string{"The quick brown fox", "jumps over the lazy", "dog"}},
+		{name: "indentation is kept", line: "    indented text wraps", width: 16, want: []string{"    indented", "text wraps"}},
+		{name: "long words are split", line: "go " + strings.Repeat("x", 12), width: 5, want: []string{"go", "xxxxx", "xxxxx", "xx"}},
+		{name: "wide characters", line: "日本語のテキスト", width: 5, want: []string{"日本", "語の", "テキ", "スト"}},
+		{name: "cjk words", line: "こんにちは 世界", width: 10, want: []string{"こんにちは", "世界"}},
+		{name: "emoji", line: "🎉🎉🎉 party", width: 6, want: []string{"🎉🎉🎉", "party"}},
+		{
+			name:  "escape sequences take no room",
+			line:  "\033[1mbold\033[0m and \033[31mred\033[0m text",
+			width: 10,
+			want:  []string{"\033[1mbold\033[0m and", "\033[31mred\033[0m text"},
+		},
+		{
+			name:  "styles among dropped spaces are kept",
+			line:  "one \033[4m two",
+			width: 4,
+			want:  []string{"one", "\033[4mtwo"},
+		},
+		{
+			name:  "split inside a style",
+			line:  "\033[32m" + strings.Repeat("x", 6) + "\033[0m",
+			width: 4,
+			want:  []string{"\033[32mxxxx", "xx\033[0m"},
+		},
+		{name: "narrower than a wide character", line: "日本", width: 1, want: []string{"日", "本"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapLine(tt.line, tt.width)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("wrapLine(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+			}
+			for _, line := range got {
+				if w := displayWidth(line); w > max(tt.width, 2) {
+					t.Errorf("Line %q is %d cells wide, want at most %d", line, w, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "no tabs", want: "no tabs"},
+		{s: "a\tb", want: "a       b"},
+		{s: "\033[1mab\033[0m\tc\nd\te", want: "\033[1mab\033[0m      c\nd       e"},
+		{s: "漢字\tx", want: "漢字    x"},
+	}
+
+	for _, tt := range tests {
+		if got := expandTabs(tt.s); got != tt.want {
+			t.Errorf("expandTabs(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestIsolateLines(t *testing.T) {
+	env := &Environment{StartSeq: "\033]", EndSeq: "\a", Hyperlinks: true}
+	lines := []string{
+		"\033[1;31mred",
+		"still red\033[0m plain",
+		"\033]8;;https://example.com\ahttps://exa",
+		"mple.com\033]8;;\a",
+	}
+	want := []string{
+		"\033[1;31mred\033[0m",
+		"\033[1;31mstill red\033[0m plain",
+		"\033]8;;https://example.com\ahttps://exa\033]8;;\a",
+		"\033]8;;https://example.com\ample.com\033]8;;\a",
+	}
+
+	if got := isolateLines(env, lines); !slices.Equal(got, want) {
+		t.Errorf("isolateLines() = %q, want %q", got, want)
+	}
+}
+
+func TestLayout_arrange(t *testing.T) {
+	env := &Environment{Columns: 20}
+
+	tests := []struct {
+		name        string
+		layout      Layout
+		env         *Environment
+		contentType string
+		out         string
+		want        string
+	}{
+		{name: "zero layout", out: "a\tlong line that is wider than twenty", want: "a\tlong line that is wider than twenty"},
+		{name: "wrap", layout: Layout{Wrap: true}, out: "The quick brown fox jumps", want: "The quick brown fox\njumps"},
+		{name: "wrap needs the width", layout: Layout{Wrap: true}, env: &Environment{}, out: "The quick brown fox jumps", want: "The quick brown fox jumps"},
+		{name: "markdown is wrapped by its renderer", layout: Layout{Wrap: true}, contentType: network.ContentTypeMarkdown, out: "    code that is wider than twenty", want: "    code that is wider than twenty"},
+		{name: "center", layout: Layout{Align: AlignCenter}, out: "hello\n\nworld!", want: "       hello\n\n       world!"},
+		{
+			name:   "box",
+			layout: Layout{Box: BoxRounded},
+			out:    "hi\n日本",
+			want:   "╭──────╮\n│ hi   │\n│ 日本 │\n╰──────╯",
+		},
+		{
+			name:   "wrapped in a box",
+			layout: Layout{Wrap: true, Box: BoxSingle},
+			out:    "The quick brown fox jumps",
+			want:   "┌─────────────────┐\n│ The quick brown │\n│ fox jumps       │\n└─────────────────┘",
+		},
+		{
+			name:   "centered box",
+			layout: Layout{Align: AlignCenter, Box: BoxDouble},
+			out:    "\033[1mhi\nthere\033[0m",
+			want:   "     ╔═══════╗\n     ║ \033[1mhi\033[0m    ║\n     ║ \033[1mthere\033[0m ║\n     ╚═══════╝",
+		},
+		{name: "not text", layout: Layout{Box: BoxSingle}, out: "\033_Gf=100;AAAA\033\\", want: "\033_Gf=100;AAAA\033\\"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.env
+			if e == nil {
+				e = env
+			}
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = network.ContentTypeText
+			}
+			if got := tt.layout.arrange(e, contentType, tt.out); got != tt.want {
+				t.Errorf("arrange() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoxLines_Theme(t *testing.T) {
+	env := &Environment{ColorDepth: 4, Theme: Theme{RoleMuted: {kind: colorANSI, index: 4}}}
+	got := boxLines(env, []string{"hi"}, boxBorders[BoxSingle])
+	want := []string{"\033[34m┌────┐\033[39m", "\033[34m│\033[39m hi \033[34m│\033[39m", "\033[34m└────┘\033[39m"}
+	if !slices.Equal(got, want) {
+		t.Errorf("boxLines() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatter_Render_Layout(t *testing.T) {
+	env := &Environment{Columns: 24}
+	layout := WithLayout(Layout{Wrap: true, Box: BoxSingle})
+
+	text := &network.Message{ContentType: network.ContentTypeText, Body: []byte("The quick brown fox jumps over")}
+	want := "┌─────────────────────┐\n│ The quick brown fox │\n│ jumps over          │\n└─────────────────────┘"
+	if got := NewFormatter(env, layout).Render(text); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	// Markdown is rendered for the room inside the box.
+	markdown := &network.Message{ContentType: network.ContentTypeMarkdown, Body: []byte("The quick brown fox jumps over")}
+	if got := NewFormatter(env, layout).Render(markdown); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	image := &network.Message{ContentType: network.ContentTypePNG, Body: testPNG(t, 2, 2)}
+	if got := NewFormatter(env, layout).Render(image); got != "[image]" {
+		t.Errorf("Render() = %q, want images left alone", got)
+	}
+
+	if got := NewFormatter(env).Render(text); got != "The quick brown fox jumps over" {
+		t.Errorf("Render() = %q, want text as it is without a layout", got)
+	}
+}
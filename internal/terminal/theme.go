@@ -0,0 +1,90 @@
+package terminal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Theme names.
+const (
+	ThemeAuto  = "auto" // Light or dark to match the terminal background
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+)
+
+// ThemeNames lists the accepted theme names.
+var ThemeNames = []string{ThemeAuto, ThemeDark, ThemeLight}
+
+// Roles of the colors in a theme.
+const (
+	RoleHeading = "heading" // Markdown headings
+	RoleCode    = "code"    // Code spans and blocks
+	RoleLink    = "link"    // Hyperlinks
+	RoleMuted   = "muted"   // Rules, quote bars and fact labels, which are also faint
+	RoleWarn    = "warn"    // Warnings such as a pending reboot, which are also bold
+)
+
+// ThemeRoles lists the roles a palette may set colors for.
+var ThemeRoles = []string{RoleHeading, RoleCode, RoleLink, RoleMuted, RoleWarn}
+
+// Theme maps roles to the colors text renderers use for them. Roles
+// without a color use the terminal's default.
+type Theme map[string]Color
+
+// themes are the built-in themes.
+var themes = map[string]Theme{
+	ThemeDark: {
+		RoleCode: {kind: colorANSI, index: 6}, // Cyan
+		RoleWarn: {kind: colorANSI, index: 9}, // Bright red
+	},
+	ThemeLight: {
+		RoleCode: {kind: colorANSI, index: 4}, // Blue
+		RoleWarn: {kind: colorANSI, index: 1}, // Red
+	},
+}
+
+// NewTheme returns the named theme with the colors of palette replacing
+// its own. The auto theme is the light or dark one to match the background
+// of env, dark if it is unknown.
+func NewTheme(name string, env *Environment, palette Theme) Theme {
+	if name == ThemeAuto || themes[name] == nil {
+		name = ThemeDark
+		if light, _ := env.LightBackground(); light {
+			name = ThemeLight
+		}
+	}
+	theme := maps.Clone(themes[name])
+	maps.Copy(theme, palette)
+	return theme
+}
+
+// ParsePalette parses a palette that maps roles to color names, as
+// accepted by ParseColor.
+func ParsePalette(palette map[string]string) (Theme, error) {
+	theme := make(Theme, len(palette))
+	for role, name := range palette {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !slices.Contains(ThemeRoles, role) {
+			return nil, fmt.Errorf("unknown palette role %q, want one of %s", role, strings.Join(ThemeRoles, ", "))
+		}
+		c, err := ParseColor(name)
+		if err != nil {
+			return nil, fmt.Errorf("palette role %s: %w", role, err)
+		}
+		theme[role] = c
+	}
+	return theme, nil
+}
+
+// color returns the SGR parameters of the foreground color for role, or
+// "" if it has none or the terminal has no colors. Environments without a
+// theme use the dark one.
+func (env *Environment) color(role string) string {
+	theme := env.Theme
+	if theme == nil {
+		theme = themes[ThemeDark]
+	}
+	return theme[role].sgr(env.ColorDepth, false)
+}
@@ -0,0 +1,92 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stevielcb/motd-client/internal/network"
+)
+
+func TestNewTheme(t *testing.T) {
+	tests := []struct {
+		name     string
+		theme    string
+		env      *Environment
+		palette  Theme
+		wantCode Color
+		wantWarn Color
+	}{
+		{name: "dark", theme: ThemeDark, env: &Environment{Background: RGB(255, 255, 255), BackgroundLuminance: 1}, wantCode: themes[ThemeDark][RoleCode], wantWarn: themes[ThemeDark][RoleWarn]},
+		{name: "light", theme: ThemeLight, env: &Environment{}, wantCode: themes[ThemeLight][RoleCode], wantWarn: themes[ThemeLight][RoleWarn]},
+		{name: "auto on light background", theme: ThemeAuto, env: &Environment{Background: RGB(253, 246, 227), BackgroundLuminance: 0.91}, wantCode: themes[ThemeLight][RoleCode], wantWarn: themes[ThemeLight][RoleWarn]},
+		{name: "auto on dark background", theme: ThemeAuto, env: &Environment{Background: RGB(30, 30, 46), BackgroundLuminance: 0.013}, wantCode: themes[ThemeDark][RoleCode], wantWarn: themes[ThemeDark][RoleWarn]},
+		{name: "auto on unknown background", theme: ThemeAuto, env: &Environment{}, wantCode: themes[ThemeDark][RoleCode], wantWarn: themes[ThemeDark][RoleWarn]},
+		{name: "palette", theme: ThemeDark, env: &Environment{}, palette: Theme{RoleCode: RGB(255, 135, 0)}, wantCode: RGB(255, 135, 0), wantWarn: themes[ThemeDark][RoleWarn]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			theme := NewTheme(tt.theme, tt.env, tt.palette)
+			if theme[RoleCode] != tt.wantCode || theme[RoleWarn] != tt.wantWarn {
+				t.Errorf("NewTheme() code = %+v, warn = %+v, want %+v, %+v", theme[RoleCode], theme[RoleWarn], tt.wantCode, tt.wantWarn)
+			}
+		})
+	}
+
+	NewTheme(ThemeDark, &Environment{}, Theme{RoleCode: {}})
+	if themes[ThemeDark][RoleCode] == (Color{}) {
+		t.Error("NewTheme() modified the built-in theme")
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	theme, err := ParsePalette(map[string]string{"Heading": "bright-blue", "code": "#ff8700", "warn": "default"})
+	if err != nil {
+		t.Fatalf("ParsePalette() error = %v", err)
+	}
+	want := Theme{RoleHeading: {kind: colorANSI, index: 12}, RoleCode: RGB(255, 135, 0), RoleWarn: {}}
+	if len(theme) != len(want) || theme[RoleHeading] != want[RoleHeading] || theme[RoleCode] != want[RoleCode] {
+		t.Errorf("ParsePalette() = %+v, want %+v", theme, want)
+	}
+
+	for _, palette := range []map[string]string{{"title": "red"}, {"code": "teal"}} {
+		if _, err := ParsePalette(palette); err == nil {
+			t.Errorf("ParsePalette(%v) expected error, got nil", palette)
+		}
+	}
+}
+
+func TestRenderMarkdown_Theme(t *testing.T) {
+	theme := Theme{RoleHeading: {kind: colorANSI, index: 12}, RoleCode: RGB(255, 135, 0), RoleLink: {kind: colorANSI, index: 4}}
+	msg := &network.Message{Body: []byte("# Title\n\nRun `make` or see [docs](https://example.com)")}
+
+	tests := []struct {
+		name string
+		env  *Environment
+		want string
+	}{
+		{
+			name: "truecolor",
+			env:  &Environment{StartSeq: "\033]", EndSeq: "\a", ColorDepth: 24, Hyperlinks: true, Theme: theme},
+			want: "\033[1;4;94mTitle\033[0m\n\nRun \033[38;2;255;135;0mmake\033[0m or see " +
+				"\033]8;;https://example.com\a\033[4;34mdocs\033[0m\033]8;;\a",
+		},
+		{
+			name: "256 colors",
+			env:  &Environment{ColorDepth: 8, Theme: theme},
+			want: "\033[1;4;94mTitle\033[0m\n\nRun \033[38;5;208mmake\033[0m or see docs (https://example.com)",
+		},
+		{
+			name: "no colors",
+			env:  &Environment{Theme: theme},
+			want: "\033[1;4mTitle\033[0m\n\nRun make or see docs (https://example.com)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.env, msg); got != tt.want {
+				t.Errorf("renderMarkdown() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
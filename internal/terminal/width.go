@@ -0,0 +1,181 @@
+package terminal
+
+import "unicode"
+
+// Joiners and selectors that change how the characters around them are
+// shown.
+const (
+	zeroWidthJoiner    = '\u200d' // Joins emoji into one, e.g. a family
+	emojiPresentation  = '\ufe0f' // Shows the character before it as an emoji
+	regionalIndicatorA = '\U0001f1e6'
+	regionalIndicatorZ = '\U0001f1ff'
+)
+
+// wideRunes are the characters terminals show in two cells: East Asian wide
+// and fullwidth characters (UAX #11) and emoji shown as emoji by default.
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1}, // Hangul Jamo initial consonants
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2329, Hi: 0x232a, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x23f0, Hi: 0x23f3, Stride: 3},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2648, Hi: 0x2653, Stride: 1},
+		{Lo: 0x267f, Hi: 0x2693, Stride: 20},
+		{Lo: 0x26a1, Hi: 0x26a1, Stride: 1},
+		{Lo: 0x26aa, Hi: 0x26ab, Stride: 1},
+		{Lo: 0x26bd, Hi: 0x26be, Stride: 1},
+		{Lo: 0x26c4, Hi: 0x26c5, Stride: 1},
+		{Lo: 0x26ce, Hi: 0x26d4, Stride: 6},
+		{Lo: 0x26ea, Hi: 0x26ea, Stride: 1},
+		{Lo: 0x26f2, Hi: 0x26f3, Stride: 1},
+		{Lo: 0x26f5, Hi: 0x26fa, Stride: 5},
+		{Lo: 0x26fd, Hi: 0x2705, Stride: 8},
+		{Lo: 0x270a, Hi: 0x270b, Stride: 1},
+		{Lo: 0x2728, Hi: 0x274c, Stride: 36},
+		{Lo: 0x274e, Hi: 0x274e, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27b0, Hi: 0x27bf, Stride: 15},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b55, Stride: 5},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1}, // CJK radicals, symbols and punctuation
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1}, // Kana, Bopomofo and CJK compatibility
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1}, // CJK unified ideographs extension A
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1}, // CJK unified ideographs
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1}, // Yi
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1}, // Hangul Jamo extended A
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1}, // Hangul syllables
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1}, // CJK compatibility ideographs
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1}, // Vertical forms
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1}, // CJK compatibility forms and small forms
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1}, // Fullwidth forms
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1}, // Fullwidth signs
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x16fe4, Stride: 1},
+		{Lo: 0x17000, Hi: 0x18cff, Stride: 1}, // Tangut and Khitan
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1}, // Kana supplements and Nushu
+		{Lo: 0x1f004, Hi: 0x1f004, Stride: 1},
+		{Lo: 0x1f0cf, Hi: 0x1f0cf, Stride: 1},
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1},
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f202, Stride: 1},
+		{Lo: 0x1f210, Hi: 0x1f23b, Stride: 1},
+		{Lo: 0x1f240, Hi: 0x1f248, Stride: 1},
+		{Lo: 0x1f250, Hi: 0x1f251, Stride: 1},
+		{Lo: 0x1f260, Hi: 0x1f265, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f320, Stride: 1},
+		{Lo: 0x1f32d, Hi: 0x1f335, Stride: 1},
+		{Lo: 0x1f337, Hi: 0x1f37c, Stride: 1},
+		{Lo: 0x1f37e, Hi: 0x1f393, Stride: 1},
+		{Lo: 0x1f3a0, Hi: 0x1f3ca, Stride: 1},
+		{Lo: 0x1f3cf, Hi: 0x1f3d3, Stride: 1},
+		{Lo: 0x1f3e0, Hi: 0x1f3f0, Stride: 1},
+		{Lo: 0x1f3f4, Hi: 0x1f3f4, Stride: 1},
+		{Lo: 0x1f3f8, Hi: 0x1f43e, Stride: 1},
+		{Lo: 0x1f440, Hi: 0x1f440, Stride: 1},
+		{Lo: 0x1f442, Hi: 0x1f4fc, Stride: 1},
+		{Lo: 0x1f4ff, Hi: 0x1f53d, Stride: 1},
+		{Lo: 0x1f54b, Hi: 0x1f54e, Stride: 1},
+		{Lo: 0x1f550, Hi: 0x1f567, Stride: 1},
+		{Lo: 0x1f57a, Hi: 0x1f57a, Stride: 1},
+		{Lo: 0x1f595, Hi: 0x1f596, Stride: 1},
+		{Lo: 0x1f5a4, Hi: 0x1f5a4, Stride: 1},
+		{Lo: 0x1f5fb, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6c5, Stride: 1},
+		{Lo: 0x1f6cc, Hi: 0x1f6cc, Stride: 1},
+		{Lo: 0x1f6d0, Hi: 0x1f6d2, Stride: 1},
+		{Lo: 0x1f6d5, Hi: 0x1f6d7, Stride: 1},
+		{Lo: 0x1f6dc, Hi: 0x1f6df, Stride: 1},
+		{Lo: 0x1f6eb, Hi: 0x1f6ec, Stride: 1},
+		{Lo: 0x1f6f4, Hi: 0x1f6fc, Stride: 1},
+		{Lo: 0x1f7e0, Hi: 0x1f7eb, Stride: 1},
+		{Lo: 0x1f7f0, Hi: 0x1f7f0, Stride: 1},
+		{Lo: 0x1f90c, Hi: 0x1f93a, Stride: 1},
+		{Lo: 0x1f93c, Hi: 0x1f945, Stride: 1},
+		{Lo: 0x1f947, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1}, // Symbols and pictographs extended A
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1}, // CJK unified ideographs extensions
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// runeWidth returns the number of cells r occupies on its own: 0 for
+// control characters, combining marks and other invisible characters, 2
+// for wide characters and 1 otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		return 0
+	case r < 0x300:
+		// Latin, including the soft hyphen most terminals show.
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || r >= 0x1160 && r <= 0x11ff:
+		// Marks, format characters such as joiners, and Hangul Jamo
+		// vowels and final consonants, which combine into one syllable.
+		return 0
+	case unicode.Is(wideRunes, r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// textWidth returns the number of cells s occupies.
+func textWidth(s string) int {
+	var cells cellCounter
+	width := 0
+	for _, r := range s {
+		width += cells.add(r)
+	}
+	return width
+}
+
+// cutWidth returns the length of the longest prefix of s at most n cells
+// wide. Characters that take no cells stay with the one before them.
+func cutWidth(s string, n int) int {
+	var cells cellCounter
+	width := 0
+	for i, r := range s {
+		w := cells.add(r)
+		if w > 0 && width+w > n {
+			return i
+		}
+		width += w
+	}
+	return len(s)
+}
+
+// cellCounter counts the cells taken by the characters of a string, one
+// at a time. Emoji joined with a zero width joiner count as one, as do
+// pairs of regional indicators making up a flag, and the emoji
+// presentation selector widens the character before it.
+type cellCounter struct {
+	prev rune
+	flag bool // prev is the first regional indicator of a flag
+}
+
+// add returns the number of cells r adds to the characters before it.
+func (c *cellCounter) add(r rune) int {
+	prev, flag := c.prev, c.flag
+	indicator := r >= regionalIndicatorA && r <= regionalIndicatorZ
+	c.prev, c.flag = r, indicator && !flag
+
+	switch {
+	case prev == zeroWidthJoiner:
+		// Joined to the emoji before it.
+		return 0
+	case r == emojiPresentation && runeWidth(prev) == 1:
+		return 1
+	case indicator && flag:
+		return 0
+	case indicator:
+		return 2
+	default:
+		return runeWidth(r)
+	}
+}
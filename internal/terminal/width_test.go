@@ -0,0 +1,78 @@
+package terminal
+
+import "testing"
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want int
+	}{
+		{r: 'a', want: 1},
+		{r: 'é', want: 1},
+		{r: '\u0301', want: 0}, // Combining acute accent
+		{r: '\u200b', want: 0}, // Zero width space
+		{r: '\x07', want: 0},
+		{r: '─', want: 1},
+		{r: '漢', want: 2},
+		{r: 'ア', want: 2},
+		{r: '한', want: 2},
+		{r: 'Ａ', want: 2}, // Fullwidth A
+		{r: 'ｱ', want: 1}, // Halfwidth katakana
+		{r: '😀', want: 2},
+		{r: '⚡', want: 2},
+		{r: '❤', want: 1}, // Text presentation by default
+		{r: '\U00020000', want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := runeWidth(tt.r); got != tt.want {
+			t.Errorf("runeWidth(%q) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "hello", want: 5},
+		{s: "héllo", want: 5},
+		{s: "he\u0301llo", want: 5},
+		{s: "日本語", want: 6},
+		{s: "日本語 text", want: 11},
+		{s: "\u2764\ufe0f", want: 2},
+		{s: "\U0001f468\u200d\U0001f469\u200d\U0001f467", want: 2},
+		{s: "🇯🇵🇫🇷", want: 4},
+		{s: "🇯", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := textWidth(tt.s); got != tt.want {
+			t.Errorf("textWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCutWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "hello", n: 3, want: "hel"},
+		{s: "hello", n: 10, want: "hello"},
+		{s: "日本語", n: 3, want: "日"},
+		{s: "日本語", n: 4, want: "日本"},
+		{s: "日本語", n: 1, want: ""},
+		{s: "ae\u0301b", n: 2, want: "ae\u0301"},
+		{s: "a\U0001f468\u200d\U0001f469\u200d\U0001f467b", n: 3, want: "a\U0001f468\u200d\U0001f469\u200d\U0001f467"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.s[:cutWidth(tt.s, tt.n)]; got != tt.want {
+			t.Errorf("cutWidth(%q, %d) cuts %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
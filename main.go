@@ -3,40 +3,168 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/stevielcb/motd-client/internal/app"
 	"github.com/stevielcb/motd-client/internal/config"
+	"github.com/stevielcb/motd-client/internal/failure"
 	"github.com/stevielcb/motd-client/internal/logger"
+	"github.com/stevielcb/motd-client/internal/network"
+	"github.com/stevielcb/motd-client/internal/server"
 )
 
 // main is the entry point of the application.
 func main() {
-	if err := run(); err != nil {
-		slog.Error("Application failed", "error", err)
-		os.Exit(1)
+	// Explicit commands always report failures. Displaying the MOTD honours
+	// MOTD_ON_ERROR, read directly so it also applies when the rest of the
+	// configuration fails to load.
+	mode := failure.ModeFail
+	var err error
+	if args := os.Args[1:]; len(args) > 0 {
+		err = runCommand(args)
+	} else {
+		mode = failure.ParseMode(os.Getenv("MOTD_ON_ERROR"))
+		err = runClient(false)
+	}
+
+	if code := failure.Report(os.Stderr, mode, err); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// runCommand dispatches to the requested command.
+func runCommand(args []string) error {
+	switch {
+	case len(args) == 2 && args[0] == "config" && args[1] == "check":
+		return failure.Wrap(failure.KindConfig, config.Check(os.Stdout))
+	case len(args) == 1 && args[0] == "watch":
+		return runClient(true)
+	case len(args) <= 2 && args[0] == "serve":
+		return runServer(args[1:])
+	default:
+		return failure.Wrap(failure.KindUsage, fmt.Errorf("unknown command %q", strings.Join(args, " ")))
+	}
+}
+
+// runServer serves MOTDs from a directory on the configured host and port.
+// An optional argument overrides the configured message directory.
+func runServer(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return failure.Wrap(failure.KindConfig, err)
+	}
+	if len(args) > 0 {
+		cfg.ServeDir = args[0]
+	}
+
+	// The server's logs are its only output, so never discard them.
+	logOpts := cfg.LogOptions()
+	if logOpts.Output == logger.OutputAuto && logOpts.File == "" {
+		logOpts.Output = logger.OutputStderr
+	}
+	closer, err := logger.Configure(logOpts)
+	if err != nil {
+		return failure.Wrap(failure.KindConfig, err)
+	}
+	defer closer.Close()
+
+	store, err := server.NewStore(cfg.ServeDir, cfg.ServeSelection)
+	if err != nil {
+		return failure.Wrap(failure.KindConfig, err)
 	}
+	opts := []server.Option{server.WithProtocol(cfg.ServeProtocol)}
+	if cfg.ServeSigningKey != "" {
+		key, err := network.LoadPrivateKey(cfg.ServeSigningKey)
+		if err != nil {
+			return failure.Wrap(failure.KindConfig, err)
+		}
+		opts = append(opts, server.WithSigningKey(key))
+	}
+	if len(cfg.ServeAuthKeys) > 0 {
+		secrets, err := cfg.ServeAuthSecrets()
+		if err != nil {
+			return failure.Wrap(failure.KindConfig, err)
+		}
+		opts = append(opts, server.WithAuthKeys(secrets))
+	}
+	opts = append(opts, server.WithWatchInterval(cfg.ServeWatchInterval()))
+	srv := server.New(store, cfg.Timeout(), opts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	err = srv.ListenAndServe(addr)
+	if errors.Is(err, net.ErrClosed) {
+		slog.Info("MOTD server stopped")
+		return nil
+	}
+	return failure.Wrap(failure.KindConnect, err)
 }
 
-// run contains the main application logic with proper error handling.
-func run() error {
+// runClient contains the main application logic with proper error handling.
+// In watch mode the message is redrawn whenever it changes until the process
+// is interrupted.
+func runClient(watch bool) error {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		return err
+		return failure.Wrap(failure.KindConfig, err)
 	}
 
 	// Setup logging
-	logger.Setup(cfg.LogLevel)
+	closer, err := logger.Configure(cfg.LogOptions())
+	if err != nil {
+		return failure.Wrap(failure.KindConfig, err)
+	}
+	defer closer.Close()
+
+	warnings, err := config.UnknownVariables()
+	if err != nil {
+		return failure.Wrap(failure.KindConfig, err)
+	}
+	for _, warning := range warnings {
+		slog.Warn("Configuration warning", "warning", warning)
+	}
 
 	slog.Debug("MOTD client initialized",
 		"host", cfg.Host,
 		"port", cfg.Port,
 		"timeout", cfg.Timeout(),
-		"log_level", cfg.LogLevel)
+		"log_level", cfg.LogLevel,
+		"log_format", cfg.LogFormat,
+		"log_output", cfg.LogOutput,
+		"on_error", cfg.OnError,
+		"watch", watch)
 
 	// Create and run application
-	application := app.New(cfg)
-	return application.Run()
+	application, err := app.New(cfg)
+	if err != nil {
+		return err
+	}
+	run := application.Run
+	if watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		run = func() error { return application.Watch(ctx) }
+	}
+	// Failures are reported once, by failure.Report in main.
+	if err := run(); err != nil {
+		slog.Debug("Application failed", "error", err, "kind", failure.KindOf(err))
+		return err
+	}
+	return nil
 }
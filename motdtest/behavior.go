@@ -0,0 +1,169 @@
+package motdtest
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Payload writes data and closes the connection, like a well-behaved server.
+func Payload(data []byte) Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		conn.Write(data)
+	}
+}
+
+// PayloadString is Payload for string data.
+func PayloadString(data string) Behavior {
+	return Payload([]byte(data))
+}
+
+// Empty closes the connection without writing anything.
+func Empty() Behavior {
+	return func(ctx context.Context, conn net.Conn) {}
+}
+
+// Hang accepts the connection and writes nothing until the server closes.
+func Hang() Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		<-ctx.Done()
+	}
+}
+
+// SlowDrip writes data in chunks of chunkSize bytes, pausing for interval
+// before each chunk, then closes the connection.
+func SlowDrip(data []byte, chunkSize int, interval time.Duration) Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		for len(data) > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+
+			n := min(chunkSize, len(data))
+			if _, err := conn.Write(data[:n]); err != nil {
+				return
+			}
+			data = data[n:]
+		}
+	}
+}
+
+// PartialThenHang writes the first n bytes of data and then keeps the
+// connection open without writing until the server closes.
+func PartialThenHang(data []byte, n int) Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		if _, err := conn.Write(data[:min(n, len(data))]); err != nil {
+			return
+		}
+		<-ctx.Done()
+	}
+}
+
+// ResetAfter writes the first n bytes of data and then aborts the
+// connection with a TCP reset instead of an orderly close.
+func ResetAfter(data []byte, n int) Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		if _, err := conn.Write(data[:min(n, len(data))]); err != nil {
+			return
+		}
+		reset(conn)
+	}
+}
+
+// Oversized writes size bytes of filler text, for exercising size limits.
+func Oversized(size int) Behavior {
+	return Payload(bytes.Repeat([]byte("M"), size))
+}
+
+// Sequence runs the given behaviors for successive connections. Once they
+// are exhausted the last behavior is repeated.
+func Sequence(behaviors ...Behavior) Behavior {
+	var next atomic.Int64
+	return func(ctx context.Context, conn net.Conn) {
+		i := int(next.Add(1) - 1)
+		behaviors[min(i, len(behaviors)-1)](ctx, conn)
+	}
+}
+
+// reset closes conn with SO_LINGER set to zero so the peer sees a reset.
+func reset(conn net.Conn) {
+	// TLS connections expose the underlying TCP connection via NetConn.
+	if wrapped, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = wrapped.NetConn()
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// Versioned speaks the versioned protocol: it greets the client, waits for
+// its hello and replies with data as a checksummed text/plain message.
+func Versioned(data []byte) Behavior {
+	return VersionedFunc(func(Hello) Response {
+		return Response{ContentType: "text/plain", Body: data, Checksum: true}
+	})
+}
+
+// VersionedFunc is like Versioned but lets respond choose the message from
+// the client's hello.
+func VersionedFunc(respond func(hello Hello) Response) Behavior {
+	return VersionedRaw(func(hello Hello) []byte {
+		return EncodeFrame(FrameMessage, respond(hello))
+	})
+}
+
+// VersionedRaw greets the client, waits for its hello and writes the bytes
+// returned by respond verbatim, which allows sending malformed, truncated
+// or corrupted frames.
+func VersionedRaw(respond func(hello Hello) []byte) Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		if err := WriteFrame(conn, FrameGreeting, Response{}); err != nil {
+			return
+		}
+
+		hello, err := ReadHello(conn)
+		if err != nil {
+			return
+		}
+
+		conn.Write(respond(hello))
+	}
+}
+
+// Stream speaks the versioned protocol and sends each of messages as a
+// text/plain message, pausing for interval between them, to clients whose
+// hello asks to watch. Other clients receive only the first message. The
+// connection is closed after the last message.
+func Stream(interval time.Duration, messages ...[]byte) Behavior {
+	return func(ctx context.Context, conn net.Conn) {
+		if err := WriteFrame(conn, FrameGreeting, Response{}); err != nil {
+			return
+		}
+
+		hello, err := ReadHello(conn)
+		if err != nil {
+			return
+		}
+		if !hello.Watch {
+			messages = messages[:1]
+		}
+
+		for i, data := range messages {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(interval):
+				}
+			}
+			if err := WriteFrame(conn, FrameMessage, Response{ContentType: "text/plain", Body: data, Checksum: true}); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package motdtest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPayload(t *testing.T) {
+	srv := NewServer(PayloadString("Hello, World!"))
+	defer srv.Close()
+
+	data, err := readAll(t, srv.Addr(), time.Second)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "Hello, World!" {
+		t.Errorf("Read %q, want %q", data, "Hello, World!")
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	srv := NewServer(Empty())
+	defer srv.Close()
+
+	data, err := readAll(t, srv.Addr(), time.Second)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Expected no data, got %q", data)
+	}
+}
+
+func TestHang(t *testing.T) {
+	srv := NewServer(Hang())
+	defer srv.Close()
+
+	_, err := readAll(t, srv.Addr(), 50*time.Millisecond)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestSlowDrip(t *testing.T) {
+	srv := NewServer(SlowDrip([]byte("abcdef"), 2, 20*time.Millisecond))
+	defer srv.Close()
+
+	start := time.Now()
+	data, err := readAll(t, srv.Addr(), time.Second)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "abcdef" {
+		t.Errorf("Read %q, want %q", data, "abcdef")
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected at least 3 intervals, finished in %v", elapsed)
+	}
+}
+
+func TestPartialThenHang(t *testing.T) {
+	srv := NewServer(PartialThenHang([]byte("abcdef"), 3))
+	defer srv.Close()
+
+	data, err := readAll(t, srv.Addr(), 100*time.Millisecond)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if string(data) != "abc" {
+		t.Errorf("Read %q, want %q", data, "abc")
+	}
+}
+
+func TestResetAfter(t *testing.T) {
+	srv := NewServer(ResetAfter([]byte("abcdef"), 3))
+	defer srv.Close()
+
+	// The reset can race the end of the handshake, so it may surface
+	// from either Dial or Read.
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err == nil {
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = io.ReadAll(conn)
+	}
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected a connection reset error, got %v", err)
+	}
+}
+
+func TestOversized(t *testing.T) {
+	srv := NewServer(Oversized(1 << 20))
+	defer srv.Close()
+
+	data, err := readAll(t, srv.Addr(), time.Second)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(data) != 1<<20 || !bytes.Equal(data[:4], []byte("MMMM")) {
+		t.Errorf("Expected 1 MiB of filler, got %d bytes", len(data))
+	}
+}
+
+func TestSequence(t *testing.T) {
+	srv := NewServer(Sequence(PayloadString("first"), PayloadString("second")))
+	defer srv.Close()
+
+	for _, want := range []string{"first", "second", "second"} {
+		data, err := readAll(t, srv.Addr(), time.Second)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("Read %q, want %q", data, want)
+		}
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	srv := NewServer(VersionedFunc(func(hello Hello) Response {
+		return Response{ContentType: "text/plain", Body: []byte("hello " + hello.ClientVersion), Checksum: true}
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	greeting := make([]byte, 12)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		t.Fatalf("Failed to read greeting: %v", err)
+	}
+	if string(greeting) != "MOTD\x01\x01\x00\x00\x00\x00\x00\x00" {
+		t.Errorf("Greeting = %q", greeting)
+	}
+
+	hello := Response{ContentType: "application/json", Body: []byte(`{"client_version":"tester"}`)}
+	if err := WriteFrame(conn, FrameHello, hello); err != nil {
+		t.Fatalf("Failed to write hello: %v", err)
+	}
+
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	expected := "MOTD\x01\x03\x01\x0atext/plain\x00\x00\x00\x0chello tester"
+	if len(response) != len(expected)+4 || string(response[:len(expected)]) != expected {
+		t.Errorf("Response = %q, want %q followed by a checksum", response, expected)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		hello string
+		want  []byte
+	}{
+		{
+			name:  "watching client",
+			hello: `{"watch":true}`,
+			want:  append(EncodeFrame(FrameMessage, Response{ContentType: "text/plain", Body: []byte("one"), Checksum: true}), EncodeFrame(FrameMessage, Response{ContentType: "text/plain", Body: []byte("two"), Checksum: true})...),
+		},
+		{
+			name:  "other client",
+			hello: `{}`,
+			want:  EncodeFrame(FrameMessage, Response{ContentType: "text/plain", Body: []byte("one"), Checksum: true}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(Stream(10*time.Millisecond, []byte("one"), []byte("two")))
+			defer srv.Close()
+
+			conn, err := net.Dial("tcp", srv.Addr())
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(time.Second))
+
+			greeting := make([]byte, 12)
+			if _, err := io.ReadFull(conn, greeting); err != nil {
+				t.Fatalf("Failed to read greeting: %v", err)
+			}
+			if err := WriteFrame(conn, FrameHello, Response{ContentType: "application/json", Body: []byte(tt.hello)}); err != nil {
+				t.Fatalf("Failed to write hello: %v", err)
+			}
+
+			response, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("Failed to read response: %v", err)
+			}
+			if !bytes.Equal(response, tt.want) {
+				t.Errorf("Response = %q, want %q", response, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeFrame_Checksum(t *testing.T) {
+	frame := EncodeFrame(FrameMessage, Response{Body: []byte("abc"), Checksum: true})
+
+	// CRC-32 (IEEE) of "abc" is 0x352441c2.
+	if !bytes.HasSuffix(frame, []byte{0x35, 0x24, 0x41, 0xc2}) {
+		t.Errorf("EncodeFrame() = %x, want trailing CRC-32 352441c2", frame)
+	}
+	if frame[6] != flagChecksum {
+		t.Errorf("Flags = %#x, want %#x", frame[6], flagChecksum)
+	}
+}
+
+func TestEncodeFrame_Encoding(t *testing.T) {
+	frame := EncodeFrame(FrameMessage, Response{ContentType: "text/plain", Encoding: "gzip", Body: Gzip([]byte("abc"))})
+
+	if frame[6] != flagEncoded {
+		t.Errorf("Flags = %#x, want %#x", frame[6], flagEncoded)
+	}
+	if want := "\x0atext/plain\x04gzip"; string(frame[7:7+len(want)]) != want {
+		t.Errorf("EncodeFrame() header = %q, want content type followed by encoding", frame[7:7+len(want)])
+	}
+}
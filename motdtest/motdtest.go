@@ -0,0 +1,156 @@
+// Package motdtest provides a scriptable fake MOTD server for testing
+// clients of the MOTD protocol, including their failure handling.
+//
+// A Server listens on a random loopback port and runs a Behavior for every
+// accepted connection:
+//
+//	srv := motdtest.NewServer(motdtest.Payload("Hello"))
+//	defer srv.Close()
+//	client := network.NewClient(srv.Host(), srv.Port(), time.Second)
+package motdtest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Behavior scripts how the server responds to one connection. The server
+// closes conn once the behavior returns. ctx is cancelled when the server
+// is closed, so behaviors that hang must wait on ctx.Done().
+type Behavior func(ctx context.Context, conn net.Conn)
+
+// Server is a fake MOTD server listening on the loopback interface.
+type Server struct {
+	// Listener is the underlying listener; it yields TLS connections for
+	// servers created with NewTLSServer.
+	Listener net.Listener
+
+	behavior    Behavior
+	certificate *x509.Certificate
+	connections atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewServer starts a server that runs behavior for every connection.
+// It panics if it cannot listen, as tests cannot proceed without it.
+func NewServer(behavior Behavior) *Server {
+	s := newUnstartedServer(behavior)
+	s.start()
+	return s
+}
+
+// NewTLSServer starts a server like NewServer that speaks TLS using a
+// freshly generated self-signed certificate for localhost, 127.0.0.1 and ::1.
+func NewTLSServer(behavior Behavior) *Server {
+	s := newUnstartedServer(behavior)
+
+	cert, leaf, err := selfSignedCertificate()
+	if err != nil {
+		panic("motdtest: failed to generate certificate: " + err.Error())
+	}
+	s.certificate = leaf
+	s.Listener = tls.NewListener(s.Listener, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	s.start()
+	return s
+}
+
+// newUnstartedServer listens on a random loopback port without accepting.
+func newUnstartedServer(behavior Behavior) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("motdtest: failed to listen: " + err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		Listener: listener,
+		behavior: behavior,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// start accepts connections in the background until Close.
+func (s *Server) start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := s.Listener.Accept()
+			if err != nil {
+				return
+			}
+			s.connections.Add(1)
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer conn.Close()
+				s.behavior(s.ctx, conn)
+			}()
+		}
+	}()
+}
+
+// Addr returns the host:port address the server listens on.
+func (s *Server) Addr() string {
+	return s.Listener.Addr().String()
+}
+
+// Host returns the IP address the server listens on.
+func (s *Server) Host() string {
+	return s.Listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the server listens on.
+func (s *Server) Port() int {
+	return s.Listener.Addr().(*net.TCPAddr).Port
+}
+
+// Connections returns the number of connections accepted so far.
+func (s *Server) Connections() int {
+	return int(s.connections.Load())
+}
+
+// Certificate returns the TLS certificate of a server created with
+// NewTLSServer, or nil for plain TCP servers.
+func (s *Server) Certificate() *x509.Certificate {
+	return s.certificate
+}
+
+// ClientTLSConfig returns a TLS configuration that trusts the server's
+// certificate, or nil for plain TCP servers.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	if s.certificate == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(s.certificate)
+	return &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// Close stops the server, cancels hanging behaviors and waits for all
+// connections to finish. It is safe to call more than once.
+func (s *Server) Close() {
+	s.once.Do(func() {
+		s.cancel()
+		s.Listener.Close()
+		s.wg.Wait()
+	})
+}
@@ -0,0 +1,115 @@
+package motdtest
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// readAll connects to addr and reads until EOF or error.
+func readAll(t *testing.T, addr string, timeout time.Duration) ([]byte, error) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", addr, err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	return io.ReadAll(conn)
+}
+
+func TestServer_Addresses(t *testing.T) {
+	srv := NewServer(Empty())
+	defer srv.Close()
+
+	if srv.Host() != "127.0.0.1" {
+		t.Errorf("Host() = %q, want 127.0.0.1", srv.Host())
+	}
+	if srv.Port() == 0 {
+		t.Error("Expected a non-zero port")
+	}
+	if srv.Addr() != net.JoinHostPort(srv.Host(), strconv.Itoa(srv.Port())) {
+		t.Errorf("Addr() = %q does not match Host() and Port()", srv.Addr())
+	}
+	if srv.Certificate() != nil || srv.ClientTLSConfig() != nil {
+		t.Error("Expected no TLS configuration for a plain server")
+	}
+}
+
+func TestServer_CountsConnections(t *testing.T) {
+	srv := NewServer(PayloadString("x"))
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := readAll(t, srv.Addr(), time.Second); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+	}
+
+	if srv.Connections() != 3 {
+		t.Errorf("Connections() = %d, want 3", srv.Connections())
+	}
+}
+
+func TestServer_CloseUnblocksHangingBehaviors(t *testing.T) {
+	srv := NewServer(Hang())
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		srv.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close() did not return while a behavior was hanging")
+	}
+
+	// Closing twice must be harmless.
+	srv.Close()
+}
+
+func TestNewTLSServer(t *testing.T) {
+	srv := NewTLSServer(PayloadString("Secure MOTD"))
+	defer srv.Close()
+
+	if srv.Certificate() == nil {
+		t.Fatal("Expected a certificate for a TLS server")
+	}
+
+	conn, err := tls.Dial("tcp", srv.Addr(), srv.ClientTLSConfig())
+	if err != nil {
+		t.Fatalf("TLS dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "Secure MOTD" {
+		t.Errorf("Read %q, want %q", data, "Secure MOTD")
+	}
+}
+
+func TestNewTLSServer_RejectsUntrustedClients(t *testing.T) {
+	srv := NewTLSServer(PayloadString("Secure MOTD"))
+	defer srv.Close()
+
+	conn, err := tls.Dial("tcp", srv.Addr(), &tls.Config{ServerName: "localhost"})
+	if err == nil {
+		conn.Close()
+		t.Error("Expected certificate verification to fail without the server's CA")
+	}
+}
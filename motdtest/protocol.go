@@ -0,0 +1,147 @@
+package motdtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Versioned protocol constants. The harness implements the wire format
+// independently of the client so that tests catch encoding mistakes.
+const (
+	ProtocolVersion = 1
+
+	FrameGreeting  byte = 1
+	FrameHello     byte = 2
+	FrameMessage   byte = 3
+	FrameMetadata  byte = 4
+	FrameSignature byte = 5
+	FrameError     byte = 6
+
+	flagChecksum byte = 1 << 0
+	flagEncoded  byte = 1 << 1
+)
+
+// magic starts every frame.
+var magic = []byte("MOTD")
+
+// Hello is the client's hello as decoded by the harness.
+type Hello struct {
+	ClientVersion string `json:"client_version"`
+	Capabilities  struct {
+		Graphics   []string `json:"graphics"`
+		Columns    int      `json:"columns"`
+		Rows       int      `json:"rows"`
+		ColorDepth int      `json:"color_depth"`
+		Background string   `json:"background"`
+	} `json:"capabilities"`
+	// Encodings lists the payload encodings the client can decode.
+	Encodings []string `json:"encodings"`
+	// Auth is the client's authentication token, nil if it sent none.
+	Auth *struct {
+		KeyID     string `json:"key_id"`
+		Timestamp int64  `json:"timestamp"`
+		Nonce     string `json:"nonce"`
+		MAC       string `json:"mac"`
+	} `json:"auth"`
+	// Watch is set when the client wants to keep receiving messages.
+	Watch bool `json:"watch"`
+}
+
+// Response describes a message frame sent by a versioned server.
+type Response struct {
+	ContentType string
+	Encoding    string // Declared encoding; Body must already be encoded, see Gzip
+	Body        []byte
+	Checksum    bool // Append a CRC-32 of Body
+}
+
+// EncodeFrame returns the wire encoding of a frame. Slicing the result is a
+// convenient way to produce truncated frames.
+func EncodeFrame(frameType byte, resp Response) []byte {
+	var flags byte
+	if resp.Checksum {
+		flags |= flagChecksum
+	}
+	if resp.Encoding != "" {
+		flags |= flagEncoded
+	}
+
+	buf := append([]byte{}, magic...)
+	buf = append(buf, ProtocolVersion, frameType, flags, byte(len(resp.ContentType)))
+	buf = append(buf, resp.ContentType...)
+	if resp.Encoding != "" {
+		buf = append(buf, byte(len(resp.Encoding)))
+		buf = append(buf, resp.Encoding...)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(resp.Body)))
+	buf = append(buf, resp.Body...)
+	if resp.Checksum {
+		buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(resp.Body))
+	}
+	return buf
+}
+
+// WriteFrame writes a frame of the given type.
+func WriteFrame(w io.Writer, frameType byte, resp Response) error {
+	_, err := w.Write(EncodeFrame(frameType, resp))
+	return err
+}
+
+// ReadHello reads the client's hello frame from r.
+func ReadHello(r io.Reader) (Hello, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Hello{}, err
+	}
+	if !bytes.Equal(header[:4], magic) || header[5] != FrameHello {
+		return Hello{}, fmt.Errorf("motdtest: unexpected frame header %q", header)
+	}
+
+	// Skip the content type and encoding.
+	if _, err := io.CopyN(io.Discard, r, int64(header[7])); err != nil {
+		return Hello{}, err
+	}
+	if header[6]&flagEncoded != 0 {
+		var n [1]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return Hello{}, err
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(n[0])); err != nil {
+			return Hello{}, err
+		}
+	}
+
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return Hello{}, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return Hello{}, err
+	}
+	if header[6]&flagChecksum != 0 {
+		if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+			return Hello{}, err
+		}
+	}
+
+	var hello Hello
+	if err := json.Unmarshal(payload, &hello); err != nil {
+		return Hello{}, fmt.Errorf("motdtest: invalid hello: %w", err)
+	}
+	return hello, nil
+}
+
+// Gzip compresses data for a Response with Encoding "gzip".
+func Gzip(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}